@@ -0,0 +1,159 @@
+package main
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/dead_letter"
+	"code-bridge/internal/translator_provider"
+	"code-bridge/pkg/database"
+	"code-bridge/pkg/types"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// translationTimeout mirrors the per-job timeout used by the translate handlers
+const translationTimeout = 2 * time.Minute
+
+// configCheck records the outcome of a single validation step
+type configCheck struct {
+	name string
+	err  error
+}
+
+// runConfigCheck loads and validates the full configuration without starting the server,
+// prints a report to out and returns the process exit code
+func runConfigCheck(out io.Writer) int {
+	cfg, err := types.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(out, "[FAIL] load configuration: %v\n", err)
+		return 1
+	}
+
+	defaultProviders, providerErr := configuredProviders(cfg)
+	providerCheck := "TRANSLATOR_PROVIDER valid"
+	if len(cfg.Server.Providers) > 0 {
+		providerCheck = "TRANSLATOR_PROVIDERS valid"
+	}
+
+	checks := []configCheck{
+		{name: "load configuration"},
+		{name: "database reachable", err: checkDatabase(cfg)},
+		{name: providerCheck, err: providerErr},
+		{name: "default provider credentials present", err: checkDefaultCredentials(cfg, defaultProviders)},
+		{name: "timeouts sane", err: checkTimeouts(cfg)},
+		{name: "prompt builds within MAX_PROMPT_TOKENS", err: checkPrompt(cfg)},
+		{name: "DEAD_LETTER_SINK valid", err: checkDeadLetterSink(cfg)},
+		{name: "provider selection valid", err: checkProviderSelection(cfg)},
+		{name: "FORMAT_STRICTNESS valid", err: checkFormatStrictness(cfg)},
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if check.err != nil {
+			failed++
+			fmt.Fprintf(out, "[FAIL] %s: %v\n", check.name, check.err)
+			continue
+		}
+		fmt.Fprintf(out, "[PASS] %s\n", check.name)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(out, "%d of %d checks failed\n", failed, len(checks))
+		return 1
+	}
+	fmt.Fprintf(out, "all %d checks passed\n", len(checks))
+	return 0
+}
+
+func checkDatabase(cfg *types.Config) error {
+	db, err := database.NewDB(databaseConfig(cfg), zap.NewNop())
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+func checkProviderCredentials(cfg *types.Config, providerType translator_provider.GenerativeProviderType) error {
+	switch providerType {
+	case translator_provider.ProviderOpenAI:
+		if cfg.OpenAI.APIKey == "" {
+			return fmt.Errorf("OPENAI_API_KEY is not set")
+		}
+	case translator_provider.ProviderGemini:
+		if cfg.Gemini.APIKey == "" {
+			return fmt.Errorf("GEMINI_API_KEY is not set")
+		}
+	case translator_provider.ProviderAnthropic:
+		if cfg.Anthropic.APIKey == "" {
+			return fmt.Errorf("ANTHROPIC_API_KEY is not set")
+		}
+	case translator_provider.ProviderLocal:
+		// a local server needs no credentials, only a reachable URL
+		if _, err := url.ParseRequestURI(cfg.Local.BaseURL); err != nil {
+			return fmt.Errorf("LOCAL_LLM_BASE_URL is invalid: %w", err)
+		}
+	case translator_provider.ProviderOllama:
+		if _, err := url.ParseRequestURI(cfg.Ollama.Host); err != nil {
+			return fmt.Errorf("OLLAMA_HOST is invalid: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported provider type: %s", providerType)
+	}
+	return nil
+}
+
+// checkDefaultCredentials checks the credentials of every provider requests may fall back to
+func checkDefaultCredentials(cfg *types.Config, providerTypes []translator_provider.GenerativeProviderType) error {
+	if len(providerTypes) == 0 {
+		return fmt.Errorf("no valid default provider is configured")
+	}
+	var errs []error
+	for _, providerType := range providerTypes {
+		if err := checkProviderCredentials(cfg, providerType); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", providerType, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkTimeouts(cfg *types.Config) error {
+	if cfg.Server.ReadTimeout < 0 || cfg.Server.WriteTimeout < 0 {
+		return fmt.Errorf("server timeouts must not be negative")
+	}
+	// a write timeout shorter than a translation would cut SSE streams off mid-job
+	if cfg.Server.WriteTimeout > 0 && cfg.Server.WriteTimeout < translationTimeout {
+		return fmt.Errorf("write timeout %s is shorter than the %s translation timeout", cfg.Server.WriteTimeout, translationTimeout)
+	}
+	// a stream evicted before its job could finish would end the job's stream mid-translation
+	if cfg.SSE.StreamTTL > 0 && cfg.SSE.StreamTTL < translationTimeout {
+		return fmt.Errorf("SSE_STREAM_TTL %s is shorter than the %s translation timeout", cfg.SSE.StreamTTL, translationTimeout)
+	}
+	if cfg.SourceURL.Timeout <= 0 {
+		return fmt.Errorf("SOURCE_URL_TIMEOUT must be positive")
+	}
+	return nil
+}
+
+func checkDeadLetterSink(cfg *types.Config) error {
+	_, err := dead_letter.ParseSink(cfg.DeadLetter.Sink)
+	return err
+}
+
+func checkProviderSelection(cfg *types.Config) error {
+	_, err := newProviderSelector(cfg, translator_provider.NewFactory(cfg, zap.NewNop(), nil))
+	return err
+}
+
+func checkFormatStrictness(cfg *types.Config) error {
+	_, err := code_translator.ParseFormatStrictness(cfg.Translator.FormatStrictness)
+	return err
+}
+
+func checkPrompt(cfg *types.Config) error {
+	service := code_translator.NewCodeTranslatorService(zap.NewNop(), nil, nil, nil, cfg.Translator)
+	return service.CheckPromptSize(types.TranslateRequest{TargetLanguage: "go"})
+}
@@ -2,17 +2,28 @@ package main
 
 import (
 	"code-bridge/internal/api"
+	"code-bridge/internal/chunked_job"
 	"code-bridge/internal/code_translator"
+	"code-bridge/internal/dead_letter"
+	"code-bridge/internal/metrics"
 	"code-bridge/internal/services"
+	"code-bridge/internal/source_fetcher"
+	"code-bridge/internal/translation_history"
+	"code-bridge/internal/translation_memory"
 	"code-bridge/internal/translator_provider"
+	"code-bridge/internal/version"
+	"code-bridge/internal/worker_pool"
 	"code-bridge/pkg/database"
 	"code-bridge/pkg/types"
 	"context"
+	"flag"
 	"fmt"
 	"go.uber.org/zap/zapcore"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -20,14 +31,25 @@ import (
 )
 
 func main() {
+	checkConfig := flag.Bool("check-config", false, "validate configuration and dependencies, then exit")
+	flag.Parse()
+
+	if *checkConfig {
+		os.Exit(runConfigCheck(os.Stdout))
+	}
+
 	// Load application configuration from environment variables
 	globalConfig, err := types.LoadConfig()
 	if err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
+	defaultProviders, err := configuredProviders(globalConfig)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load config: %v", err))
+	}
 
 	// Initialize logger with human-readable timestamps
-	logConfig := zap.NewProductionConfig()
+	logConfig := loggerConfig(globalConfig.Server.LogFormat)
 	logConfig.EncoderConfig.TimeKey = "time"
 	logConfig.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	logLevel := zap.InfoLevel
@@ -43,43 +65,244 @@ func main() {
 	}
 	defer logger.Sync()
 
-	// Initialize database connection
-	dbConfig := database.Config{
-		Host:     globalConfig.Database.Host,
-		Port:     globalConfig.Database.Port,
-		User:     globalConfig.Database.User,
-		Password: globalConfig.Database.Password,
-		DBName:   globalConfig.Database.Name,
-		SSLMode:  globalConfig.Database.SSLMode,
-	}
+	build := version.Info()
+	logger.Info("starting code-bridge",
+		zap.String("version", build.Version),
+		zap.String("commit", build.Commit),
+		zap.String("build_time", build.BuildTime),
+		zap.String("go_version", build.GoVersion),
+	)
 
-	db, err := database.NewDB(dbConfig, logger)
+	// Initialize database connection
+	db, err := database.NewDB(databaseConfig(globalConfig), logger)
 	if err != nil {
 		logger.Fatal("failed to connect to database", zap.Error(err))
 	}
 	defer db.Close()
 
+	// Translation metrics are recorded by the providers and the API and exported at GET /metrics
+	translationMetrics := metrics.New()
+
 	// Initialize provider factory and create translator provider
-	providerFactory := translator_provider.NewFactory(globalConfig)
+	providerFactory := translator_provider.NewFactory(globalConfig, logger, translationMetrics)
+	// deferred after db.Close, so providers are closed first on shutdown
+	defer func() {
+		if err := providerFactory.Close(); err != nil {
+			logger.Error("failed to close translator providers", zap.Error(err))
+		}
+	}()
 
-	// You can change this to translator_provider.ProviderGemini to use Gemini instead
-	provider, err := providerFactory.CreateProvider(translator_provider.ProviderGemini)
+	provider, err := providerFactory.CreateFallback(defaultProviders)
 	if err != nil {
 		logger.Fatal("failed to create translator provider", zap.Error(err))
 	}
 
+	if globalConfig.ProviderHTTP.Warm {
+		// the fallbacks are only needed when the first provider fails
+		go warmProvider(logger, providerFactory, defaultProviders[0])
+	}
+
 	// Initialize services
-	translatorService := code_translator.NewCodeTranslatorService(logger, provider)
+	// Requests may pick another provider by name; the factory caches each SDK client
+	resolveProvider := func(name string) (code_translator.TranslatorProviderInterface, error) {
+		providerType, err := translator_provider.ParseProviderType(name)
+		if err != nil {
+			return nil, err
+		}
+		return providerFactory.CreateProvider(providerType)
+	}
+	memory, err := translation_memory.NewPostgresMemory(context.Background(), db.DB)
+	if err != nil {
+		logger.Fatal("failed to initialize translation memory", zap.Error(err))
+	}
+	if _, err := code_translator.ParseFormatStrictness(globalConfig.Translator.FormatStrictness); err != nil {
+		logger.Fatal("invalid FORMAT_STRICTNESS", zap.Error(err))
+	}
+	translatorService := code_translator.NewCodeTranslatorService(logger, provider, resolveProvider, memory, globalConfig.Translator)
+
+	sourceFetcher := source_fetcher.NewFetcher(globalConfig.SourceURL)
 
-	svc := services.NewServices(translatorService)
+	// All translation jobs share a bounded pool of workers calling the provider
+	workerPool := worker_pool.NewPool(logger, globalConfig.WorkerPool.Workers, globalConfig.WorkerPool.QueueSize, globalConfig.WorkerPool.PriorityAging)
+	workerPool.Start()
+
+	deadLetter, err := newDeadLetterStore(logger, globalConfig, db)
+	if err != nil {
+		logger.Fatal("failed to initialize dead letter store", zap.Error(err))
+	}
+
+	chunkedJobs, err := chunked_job.NewPostgresStore(context.Background(), db.DB)
+	if err != nil {
+		logger.Fatal("failed to initialize chunked job store", zap.Error(err))
+	}
+
+	history, err := translation_history.NewPostgresStore(context.Background(), db.DB)
+	if err != nil {
+		logger.Fatal("failed to initialize translation history", zap.Error(err))
+	}
+
+	selector, err := newProviderSelector(globalConfig, providerFactory)
+	if err != nil {
+		logger.Fatal("failed to initialize provider selection", zap.Error(err))
+	}
+
+	svc := services.NewServices(services.Deps{
+		CodeTranslatorService: translatorService,
+		SourceFetcher:         sourceFetcher,
+		WorkerPool:            workerPool,
+		DeadLetter:            deadLetter,
+		ChunkedJobs:           chunkedJobs,
+		History:               history,
+		ProviderSelector:      selector,
+		Metrics:               translationMetrics,
+	})
 
 	// Start the HTTP server
 	runServer(logger, globalConfig, db, svc)
 }
 
+// loggerConfig returns zap's development config for LOG_FORMAT=console, which prints
+// colored, human-readable lines, and the JSON production config otherwise
+func loggerConfig(format string) zap.Config {
+	if format == "console" {
+		logConfig := zap.NewDevelopmentConfig()
+		logConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		return logConfig
+	}
+	return zap.NewProductionConfig()
+}
+
+// newDeadLetterStore returns the store for failed translations selected by DEAD_LETTER_SINK, or nil when it is off
+func newDeadLetterStore(logger *zap.Logger, cfg *types.Config, db *database.DB) (dead_letter.Store, error) {
+	sink, err := dead_letter.ParseSink(cfg.DeadLetter.Sink)
+	if err != nil {
+		return nil, err
+	}
+	switch sink {
+	case dead_letter.SinkPostgres:
+		return dead_letter.NewPostgresStore(context.Background(), db.DB)
+	case dead_letter.SinkLog:
+		return dead_letter.NewLogStore(logger), nil
+	default:
+		return nil, nil
+	}
+}
+
+// newProviderSelector returns the selector for PROVIDER_STRATEGY, or nil when no strategy is set
+func newProviderSelector(cfg *types.Config, factory *translator_provider.Factory) (*translator_provider.ProviderSelector, error) {
+	if cfg.Selection.Strategy == "" {
+		return nil, nil
+	}
+
+	prices := maps.Clone(translator_provider.DefaultPrices)
+	for name, price := range cfg.Selection.Prices {
+		providerType, err := translator_provider.ParseProviderType(name)
+		if err != nil {
+			return nil, err
+		}
+		prices[providerType] = price
+	}
+	policy, err := translator_provider.NewSelectionPolicy(cfg.Selection.Strategy, prices, factory.Latency())
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []translator_provider.GenerativeProviderType
+	for _, name := range cfg.Selection.Providers {
+		providerType, err := translator_provider.ParseProviderType(name)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, providerType)
+	}
+	// by default choose among the hosted providers that have credentials
+	if len(candidates) == 0 {
+		if cfg.OpenAI.APIKey != "" {
+			candidates = append(candidates, translator_provider.ProviderOpenAI)
+		}
+		if cfg.Gemini.APIKey != "" {
+			candidates = append(candidates, translator_provider.ProviderGemini)
+		}
+		if cfg.Anthropic.APIKey != "" {
+			candidates = append(candidates, translator_provider.ProviderAnthropic)
+		}
+	}
+	return translator_provider.NewProviderSelector(candidates, policy)
+}
+
+// providerWarmTimeout bounds connecting to the default provider at startup
+const providerWarmTimeout = 10 * time.Second
+
+// warmProvider opens a connection to providerType's API so the first translation starts without
+// connection setup. The time it took is logged as an estimate of what the first request saves.
+func warmProvider(logger *zap.Logger, factory *translator_provider.Factory, providerType translator_provider.GenerativeProviderType) {
+	ctx, cancel := context.WithTimeout(context.Background(), providerWarmTimeout)
+	defer cancel()
+	took, err := factory.Warm(ctx, providerType)
+	if err != nil {
+		logger.Warn("failed to warm provider connection", zap.String("provider", string(providerType)), zap.Error(err))
+		return
+	}
+	logger.Info("provider connection warmed",
+		zap.String("provider", string(providerType)),
+		zap.Duration("connect_time", took),
+	)
+}
+
+// providerNames lists the valid provider names for configuration errors
+var providerNames = fmt.Sprintf("%s, %s, %s, %s or %s",
+	translator_provider.ProviderOpenAI, translator_provider.ProviderGemini,
+	translator_provider.ProviderLocal, translator_provider.ProviderAnthropic, translator_provider.ProviderOllama)
+
+// configuredProvider validates TRANSLATOR_PROVIDER, the provider used for requests that don't pick one
+func configuredProvider(cfg *types.Config) (translator_provider.GenerativeProviderType, error) {
+	providerType, err := translator_provider.ParseProviderType(cfg.Server.Provider)
+	if err != nil {
+		return "", fmt.Errorf("invalid TRANSLATOR_PROVIDER %q, expected %s", cfg.Server.Provider, providerNames)
+	}
+	return providerType, nil
+}
+
+// configuredProviders returns the providers used, in fallback order, for requests that don't
+// pick one: the TRANSLATOR_PROVIDERS list, or TRANSLATOR_PROVIDER alone when that is not set
+func configuredProviders(cfg *types.Config) ([]translator_provider.GenerativeProviderType, error) {
+	if len(cfg.Server.Providers) == 0 {
+		providerType, err := configuredProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []translator_provider.GenerativeProviderType{providerType}, nil
+	}
+
+	providerTypes := make([]translator_provider.GenerativeProviderType, 0, len(cfg.Server.Providers))
+	for _, name := range cfg.Server.Providers {
+		providerType, err := translator_provider.ParseProviderType(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRANSLATOR_PROVIDERS entry %q, expected %s", name, providerNames)
+		}
+		if slices.Contains(providerTypes, providerType) {
+			return nil, fmt.Errorf("TRANSLATOR_PROVIDERS lists %s more than once", name)
+		}
+		providerTypes = append(providerTypes, providerType)
+	}
+	return providerTypes, nil
+}
+
+// databaseConfig maps application config to database connection settings
+func databaseConfig(cfg *types.Config) database.Config {
+	return database.Config{
+		Host:     cfg.Database.Host,
+		Port:     cfg.Database.Port,
+		User:     cfg.Database.User,
+		Password: cfg.Database.Password,
+		DBName:   cfg.Database.Name,
+		SSLMode:  cfg.Database.SSLMode,
+	}
+}
+
 func runServer(logger *zap.Logger, cfg *types.Config, db *database.DB, svc *services.Services) {
 
-	apiServer := api.NewGinServer(logger, svc)
+	apiServer := api.NewGinServer(logger, svc, cfg)
 	// Create HTTP server
 	addr := cfg.Server.GetServerAddress()
 	httpServer := &http.Server{
@@ -110,9 +333,16 @@ func runServer(logger *zap.Logger, cfg *types.Config, db *database.DB, svc *serv
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// SSE handlers only return once their stream ends, so end them before waiting on connections
+	apiServer.Shutdown()
 	if err := httpServer.Shutdown(ctx); err != nil {
 		logger.Error("server forced to shutdown", zap.Error(err))
 	}
 
+	if err := svc.WorkerPool.Stop(ctx); err != nil {
+		logger.Error("translation jobs still running at shutdown", zap.Error(err))
+	}
+	apiServer.Close()
+
 	logger.Info("server stopped")
 }
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"code-bridge/pkg/types"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SetDrain toggles connection-draining mode
+// @Summary Enable or disable draining
+// @Description While draining, new translation jobs are rejected with 503 but existing streams keep working
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body types.DrainRequest true "Drain request"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/drain [put]
+func (s *GinServer) SetDrain(c *gin.Context) {
+	var req types.DrainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// a server shutting down must not take new jobs whatever an operator asks
+	if !*req.Enabled && s.shuttingDown.Load() {
+		c.JSON(http.StatusConflict, gin.H{"error": "server is shutting down, draining cannot be disabled", "code": "shutting_down"})
+		return
+	}
+
+	s.draining.Store(*req.Enabled)
+	s.logger.Info("drain mode updated", zap.Bool("enabled", *req.Enabled))
+
+	c.JSON(http.StatusOK, gin.H{"draining": *req.Enabled})
+}
+
+// isDraining reports whether new translation jobs are refused, because an operator
+// enabled draining or the server is shutting down
+func (s *GinServer) isDraining() bool {
+	return s.draining.Load() || s.shuttingDown.Load()
+}
+
+// mayUseHighPriority reports whether client may submit high priority jobs
+func (s *GinServer) mayUseHighPriority(client string) bool {
+	return len(s.highPriorityClients) == 0 || s.highPriorityClients[client]
+}
+
+// QueueStats reports worker pool load
+// @Summary Translation queue metrics
+// @Description Returns worker usage, queue depth and how long jobs waited for a worker
+// @Tags admin
+// @Produce json
+// @Success 200 {object} worker_pool.Stats
+// @Router /admin/queue [get]
+func (s *GinServer) QueueStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.services.WorkerPool.Stats())
+}
+
+// StreamStats reports SSE hub load
+// @Summary Stream metrics
+// @Description Returns the number of streams, connected clients and buffered messages, and the streams with the most clients
+// @Tags admin
+// @Produce json
+// @Success 200 {object} sse.Stats
+// @Router /admin/streams [get]
+func (s *GinServer) StreamStats(c *gin.Context) {
+	c.JSON(http.StatusOK, s.sseHub.Stats())
+}
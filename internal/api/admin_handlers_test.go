@@ -0,0 +1,54 @@
+package api
+
+import (
+	"code-bridge/pkg/types"
+	"net/http"
+	"testing"
+)
+
+func TestAdminRoutesRequireAdminKey(t *testing.T) {
+	server := newTestServer(t, func(cfg *types.Config) {
+		cfg.Server.APIKeys = []string{"client-key"}
+		cfg.Server.AdminAPIKeys = []string{"admin-key"}
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		key    string
+		want   int
+	}{
+		{"drain without key", http.MethodPut, "/admin/drain", `{"enabled":true}`, "", http.StatusUnauthorized},
+		{"drain with client key", http.MethodPut, "/admin/drain", `{"enabled":true}`, "client-key", http.StatusUnauthorized},
+		{"drain with admin key", http.MethodPut, "/admin/drain", `{"enabled":false}`, "admin-key", http.StatusOK},
+		{"queue without key", http.MethodGet, "/admin/queue", "", "", http.StatusUnauthorized},
+		{"streams without key", http.MethodGet, "/admin/streams", "", "", http.StatusUnauthorized},
+		{"streams with admin key", http.MethodGet, "/admin/streams", "", "admin-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.key != "" {
+				header.Set(headerAPIKey, tt.key)
+			}
+			if got := serve(server, tt.method, tt.path, tt.body, header).Code; got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrainCannotBeDisabledDuringShutdown(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Shutdown()
+
+	response := serve(server, http.MethodPut, "/admin/drain", `{"enabled":false}`, nil)
+	if response.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusConflict)
+	}
+	if !server.isDraining() {
+		t.Fatal("server stopped draining during shutdown")
+	}
+}
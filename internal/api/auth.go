@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// headerAPIKey carries an API key for clients that don't send an Authorization header
+const headerAPIKey = "X-API-Key"
+
+// requestAPIKey returns the API key sent with the request: an Authorization bearer token,
+// the X-API-Key header, or, for EventSource which cannot set headers, the api_key query parameter
+func requestAPIKey(c *gin.Context) string {
+	if scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if key := c.GetHeader(headerAPIKey); key != "" {
+		return key
+	}
+	return c.Query("api_key")
+}
+
+// callerKey identifies the client of a request for per-client limits: by API key when keys are
+// enforced, and by IP otherwise, since unchecked keys could be made up to get a fresh entry
+func (s *GinServer) callerKey(c *gin.Context) string {
+	if len(s.apiKeys) > 0 {
+		return "key:" + requestAPIKey(c)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// APIKeyAuth returns a gin middleware that rejects requests without one of keys with 401.
+// With no keys every request is let through, for development.
+func APIKeyAuth(keys []string) gin.HandlerFunc {
+	accepted := make([][]byte, len(keys))
+	for i, key := range keys {
+		accepted[i] = []byte(key)
+	}
+
+	return func(c *gin.Context) {
+		if len(accepted) == 0 {
+			c.Next()
+			return
+		}
+		key := requestAPIKey(c)
+		if key == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "an API key is required", "code": "missing_api_key"})
+			return
+		}
+		// every key is compared so the time taken doesn't tell which one came close
+		match := 0
+		for _, candidate := range accepted {
+			match |= subtle.ConstantTimeCompare([]byte(key), candidate)
+		}
+		if match != 1 {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid API key", "code": "invalid_api_key"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"code-bridge/pkg/types"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// newAuthServer returns a test server that requires the key "secret" or "other"
+func newAuthServer(t *testing.T) *GinServer {
+	t.Helper()
+	return newTestServer(t, func(cfg *types.Config) {
+		cfg.Server.APIKeys = []string{"secret", "other"}
+	})
+}
+
+// errorCode returns the code field of a JSON error body
+func errorCode(t *testing.T, body []byte) string {
+	t.Helper()
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("decode error body %q: %v", body, err)
+	}
+	return resp.Code
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	server := newAuthServer(t)
+
+	tests := []struct {
+		name       string
+		path       string
+		header     http.Header
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing key", "/translate/history", nil, http.StatusUnauthorized, "missing_api_key"},
+		{"invalid key", "/translate/history", http.Header{"Authorization": {"Bearer wrong"}}, http.StatusUnauthorized, "invalid_api_key"},
+		{"non-bearer scheme", "/translate/history", http.Header{"Authorization": {"Basic secret"}}, http.StatusUnauthorized, "missing_api_key"},
+		{"bearer key", "/translate/history", http.Header{"Authorization": {"Bearer secret"}}, http.StatusOK, ""},
+		{"lowercase bearer", "/translate/history", http.Header{"Authorization": {"bearer other"}}, http.StatusOK, ""},
+		{"X-API-Key key", "/translate/history", http.Header{"X-Api-Key": {"secret"}}, http.StatusOK, ""},
+		{"invalid X-API-Key key", "/translate/history", http.Header{"X-Api-Key": {"secrets"}}, http.StatusUnauthorized, "invalid_api_key"},
+		{"query key", "/translate/history?api_key=secret", nil, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, http.MethodGet, tt.path, "", tt.header)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			if code := errorCode(t, w.Body.Bytes()); code != tt.wantCode {
+				t.Errorf("code = %q, want %q", code, tt.wantCode)
+			}
+			if w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without a WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuthCoversTranslateRoutes(t *testing.T) {
+	server := newAuthServer(t)
+
+	routes := []struct{ method, path string }{
+		{http.MethodPost, "/translate"},
+		{http.MethodPost, syncRoute},
+		{http.MethodGet, "/translate/stream/job-1"},
+		{http.MethodPost, "/translate/job-1/resume"},
+		{http.MethodPost, "/translate/cancel/job-1"},
+		{http.MethodGet, "/translate/history"},
+		{http.MethodPost, "/translations/job-1/rerun"},
+	}
+	for _, route := range routes {
+		w := serve(server, route.method, route.path, "", nil)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s without a key: status %d, want 401", route.method, route.path, w.Code)
+		}
+	}
+}
+
+func TestAPIKeyAuthLeavesHealthOpen(t *testing.T) {
+	server := newAuthServer(t)
+
+	for _, path := range []string{"/health", "/ping"} {
+		if w := serve(server, http.MethodGet, path, "", nil); w.Code != http.StatusOK {
+			t.Errorf("GET %s without a key: status %d, want 200", path, w.Code)
+		}
+	}
+}
+
+func TestAPIKeyAuthDisabledWithoutKeys(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	if w := serve(server, http.MethodGet, "/translate/history", "", nil); w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 with no keys configured", w.Code)
+	}
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"code-bridge/internal/chunked_job"
+	"code-bridge/internal/sse"
+	"code-bridge/internal/worker_pool"
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// chunkedJobStoreTimeout bounds each read or write of a chunked job's record
+const chunkedJobStoreTimeout = 5 * time.Second
+
+// createChunkedJob splits the request into parts and persists the job before it is queued.
+// On failure it writes the error response and returns false.
+func (s *GinServer) createChunkedJob(c *gin.Context, id, client string, req types.TranslateRequest) (*types.ChunkedJob, bool) {
+	job := &types.ChunkedJob{
+		ID:        id,
+		Client:    client,
+		Owner:     s.sessionID(c),
+		Request:   req,
+		Parts:     s.services.CodeTranslatorService.SplitParts(req.Code),
+		CreatedAt: time.Now(),
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), chunkedJobStoreTimeout)
+	defer cancel()
+	if err := s.services.ChunkedJobs.Create(ctx, *job); err != nil {
+		s.logger.Error("failed to create chunked job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create chunked job", "code": "job_store_failed"})
+		return nil, false
+	}
+	s.logger.Info("chunked job created", zap.String("id", id), zap.Int("parts", len(job.Parts)))
+	return job, true
+}
+
+// chunkedTranslation translates job's remaining parts, persisting each as it completes
+func (s *GinServer) chunkedTranslation(job *types.ChunkedJob) func(ctx context.Context, onChunk func(string) error) error {
+	// each part is bounded by its own timeout, so the job as a whole has none
+	return func(ctx context.Context, onChunk func(string) error) error {
+		savePart := func(part int, code string) error {
+			saveCtx, cancel := context.WithTimeout(ctx, chunkedJobStoreTimeout)
+			defer cancel()
+			return s.services.ChunkedJobs.SavePart(saveCtx, job.ID, part, code)
+		}
+		return s.services.CodeTranslatorService.TranslateChunked(ctx, job.Request, job.Parts, job.Translated, savePart, onChunk)
+	}
+}
+
+// ResumeTranslation godoc
+// @Summary Resume an interrupted chunked translation
+// @Description Continues a chunked translation from its last completed part on the job's stream
+// @Tags translation
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 202 {object} map[string]interface{}
+// @Router /translate/{id}/resume [post]
+func (s *GinServer) ResumeTranslation(c *gin.Context) {
+	id := c.Param("id")
+	if !jobIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id", "code": "invalid_job_id"})
+		return
+	}
+	if s.isDraining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server is draining, not accepting new jobs", "code": "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), chunkedJobStoreTimeout)
+	defer cancel()
+	job, err := s.services.ChunkedJobs.Load(ctx, id)
+	if errors.Is(err, chunked_job.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "chunked job not found", "code": "job_not_found"})
+		return
+	}
+	if err != nil {
+		s.logger.Error("failed to load chunked job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load chunked job", "code": "job_store_failed"})
+		return
+	}
+	if !s.authorizeJob(c, id, job.Owner) {
+		return
+	}
+	if job.Complete() {
+		c.JSON(http.StatusConflict, gin.H{"error": "job has already completed", "code": "job_complete"})
+		return
+	}
+
+	if err := s.sseHub.Restart(id, job.Owner); err != nil {
+		if errors.Is(err, sse.ErrStreamActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "job is still running", "code": "job_running"})
+			return
+		}
+		s.logger.Warn("rejecting resumed job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": "too_many_streams"})
+		return
+	}
+
+	// already validated when the job was created
+	priority, _ := worker_pool.ParsePriority(job.Request.Priority)
+	if !s.submitJob(c, id, c.ClientIP(), priority, job.Request, s.chunkedTranslation(job)) {
+		return
+	}
+
+	s.logger.Info("chunked job resumed",
+		zap.String("id", id),
+		zap.Int("completed_parts", len(job.Translated)),
+		zap.Int("parts", len(job.Parts)),
+	)
+	s.acceptJob(c, id)
+}
+
+// replayPersisted rebuilds the stream of a chunked job that completed but whose stream
+// has been cleaned up, replaying the stored result without calling the provider. Results
+// older than the replay max age are not replayed. When the stream cannot be rebuilt it
+// writes the error response and returns false.
+func (s *GinServer) replayPersisted(c *gin.Context, id string) bool {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), chunkedJobStoreTimeout)
+	defer cancel()
+	job, err := s.services.ChunkedJobs.Load(ctx, id)
+	if errors.Is(err, chunked_job.ErrJobNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found", "code": "job_not_found"})
+		return false
+	}
+	if err != nil {
+		s.logger.Error("failed to load chunked job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load chunked job", "code": "job_store_failed"})
+		return false
+	}
+	if !s.authorizeJob(c, id, job.Owner) {
+		return false
+	}
+	if !job.Complete() {
+		c.JSON(http.StatusConflict, gin.H{"error": "job was interrupted; resume it with POST /translate/" + id + "/resume", "code": "job_interrupted"})
+		return false
+	}
+	if age := time.Since(job.CompletedAt); age > s.replayMaxAge {
+		s.logger.Info("not replaying expired result", zap.String("id", id), zap.Duration("age", age))
+		c.JSON(http.StatusGone, gin.H{"error": "job result has expired", "code": "result_expired"})
+		return false
+	}
+
+	if err := s.sseHub.Restart(id, job.Owner); err != nil {
+		// another reconnect rebuilt the stream first; attach to that one
+		if errors.Is(err, sse.ErrStreamActive) {
+			return true
+		}
+		s.logger.Warn("rejecting replayed job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": "too_many_streams"})
+		return false
+	}
+
+	// every part is stored, so this only replays them and sends the final chunks
+	err = s.services.CodeTranslatorService.TranslateChunked(c.Request.Context(), job.Request, job.Parts, job.Translated, nil, s.relay(id))
+	if err != nil {
+		s.logger.Error("failed to replay chunked job", zap.String("id", id), zap.Error(err))
+		_ = s.sseHub.Send(id, fmt.Sprintf("ERROR: %v", err))
+	}
+	_ = s.sseHub.Send(id, "[DONE]")
+
+	s.logger.Info("replayed persisted result", zap.String("id", id), zap.Time("completed_at", job.CompletedAt))
+	return true
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsMaxAge is how long, in seconds, browsers may cache a preflight response
+const corsMaxAge = "600"
+
+// corsAllowedHeaders are the request headers cross-origin clients may send: the JSON body's
+// content type, API keys, the header overrides, stream resume tokens and EventSource's Last-Event-ID
+var corsAllowedHeaders = strings.Join([]string{
+	"Content-Type",
+	"Authorization",
+	headerAPIKey,
+	headerModel,
+	headerTemperature,
+	headerProvider,
+	resumeTokenHeader,
+	headerLastEventID,
+	"Cache-Control",
+}, ", ")
+
+// CORS returns a gin middleware that lets browsers on allowedOrigins call the API and
+// answers their preflight requests. "*" allows every origin, without credentials; listed
+// origins are echoed back and may send credentials. With no origins the middleware
+// does nothing, and only same-origin pages can use the API.
+func CORS(allowedOrigins []string) gin.HandlerFunc {
+	anyOrigin := slices.Contains(allowedOrigins, "*")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" || len(allowedOrigins) == 0 {
+			c.Next()
+			return
+		}
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		switch {
+		case slices.Contains(allowedOrigins, origin):
+			// credentials can't be combined with a wildcard, so specific origins are echoed back
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Set("Access-Control-Allow-Credentials", "true")
+		case anyOrigin:
+			header.Set("Access-Control-Allow-Origin", "*")
+		case preflight:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "origin not allowed", "code": "origin_not_allowed"})
+			return
+		default:
+			// without the allow headers the browser keeps the response from the page
+			c.Next()
+			return
+		}
+		header.Set("Access-Control-Expose-Headers", "Retry-After")
+
+		if preflight {
+			header.Set("Access-Control-Allow-Methods", "GET, POST, PUT, OPTIONS")
+			header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			header.Set("Access-Control-Max-Age", corsMaxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
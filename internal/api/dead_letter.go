@@ -0,0 +1,35 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/pkg/types"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deadLetterTimeout bounds recording a failure; the job's own context may already have expired
+const deadLetterTimeout = 5 * time.Second
+
+// recordDeadLetter stores a failed translation with its classified error for later analysis and replay
+func (s *GinServer) recordDeadLetter(id, client string, req types.TranslateRequest, err error) {
+	if s.services.DeadLetter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deadLetterTimeout)
+	defer cancel()
+
+	failure := types.FailedTranslation{
+		JobID:     id,
+		Client:    client,
+		ErrorCode: code_translator.ErrorCode(err),
+		Error:     err.Error(),
+		Request:   req,
+		FailedAt:  time.Now(),
+	}
+	if recordErr := s.services.DeadLetter.Record(ctx, failure); recordErr != nil {
+		s.logger.Error("failed to record failed translation", zap.String("id", id), zap.Error(recordErr))
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"sync"
+	"time"
+)
+
+// failurePruneThreshold is the number of tracked clients above which stale entries are dropped
+const failurePruneThreshold = 1024
+
+// inputFailureCodes are the ErrorCode classifications of failures caused by the request itself.
+// Provider errors, rate limits and timeouts are not the client's doing, and counting them
+// would lock every client out during a provider outage.
+var inputFailureCodes = map[string]bool{
+	code_translator.ErrCodeFormatViolation:  true,
+	code_translator.ErrCodeEmptyTranslation: true,
+	code_translator.ErrCodeContextTooLarge:  true,
+}
+
+// failureTracker counts consecutive failed translation jobs per client and
+// blocks clients for a cooldown once they reach the limit, so a client
+// retrying hopeless input doesn't keep spending provider budget
+type failureTracker struct {
+	mu          sync.Mutex
+	maxFailures int
+	cooldown    time.Duration
+	clients     map[string]*clientFailures
+}
+
+type clientFailures struct {
+	consecutive  int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// newFailureTracker creates a tracker; maxFailures <= 0 disables blocking
+func newFailureTracker(maxFailures int, cooldown time.Duration) *failureTracker {
+	return &failureTracker{
+		maxFailures: maxFailures,
+		cooldown:    cooldown,
+		clients:     make(map[string]*clientFailures),
+	}
+}
+
+// blockedFor returns how much longer client is blocked, or zero if it may submit jobs
+func (t *failureTracker) blockedFor(client string) time.Duration {
+	if t.maxFailures <= 0 {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.clients[client]
+	if !ok {
+		return 0
+	}
+	if remaining := time.Until(entry.blockedUntil); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// recordFailure counts a job that failed with err and starts the cooldown once client
+// reaches the limit; failures not caused by the input are ignored
+func (t *failureTracker) recordFailure(client string, err error) {
+	if t.maxFailures <= 0 || !inputFailureCodes[code_translator.ErrorCode(err)] {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	entry, ok := t.clients[client]
+	if !ok {
+		if len(t.clients) >= failurePruneThreshold {
+			t.prune(now)
+		}
+		entry = &clientFailures{}
+		t.clients[client] = entry
+	}
+
+	entry.consecutive++
+	entry.lastFailure = now
+	if entry.consecutive >= t.maxFailures {
+		// the count starts over after the cooldown
+		entry.blockedUntil = now.Add(t.cooldown)
+		entry.consecutive = 0
+	}
+}
+
+// recordSuccess resets client's consecutive failure count
+func (t *failureTracker) recordSuccess(client string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.clients[client]; ok && time.Now().After(entry.blockedUntil) {
+		delete(t.clients, client)
+	}
+}
+
+// prune drops clients that are not blocked and haven't failed within a cooldown; t.mu must be held
+func (t *failureTracker) prune(now time.Time) {
+	for client, entry := range t.clients {
+		if now.After(entry.blockedUntil) && now.Sub(entry.lastFailure) > t.cooldown {
+			delete(t.clients, client)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestFailureTrackerCountsOnlyInputFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		blocked bool
+	}{
+		{"format violation", &code_translator.FormatError{Missing: []string{"code"}}, true},
+		{"empty translation", code_translator.ErrEmptyTranslation, true},
+		{"context too large", &code_translator.ContextTooLargeError{Limit: 10, Actual: 20}, true},
+		{"wrapped input failure", fmt.Errorf("translate: %w", code_translator.ErrEmptyTranslation), true},
+		{"provider error", errors.New("provider returned 503"), false},
+		{"timeout", context.DeadlineExceeded, false},
+		{"canceled", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newFailureTracker(3, time.Minute)
+			for range 3 {
+				tracker.recordFailure("key:a", tt.err)
+			}
+			if blocked := tracker.blockedFor("key:a") > 0; blocked != tt.blocked {
+				t.Errorf("blocked = %v, want %v", blocked, tt.blocked)
+			}
+			if tracker.blockedFor("key:b") > 0 {
+				t.Error("another client was blocked")
+			}
+		})
+	}
+}
+
+func TestFailureTrackerSuccessResetsCount(t *testing.T) {
+	tracker := newFailureTracker(2, time.Minute)
+	tracker.recordFailure("key:a", code_translator.ErrEmptyTranslation)
+	tracker.recordSuccess("key:a")
+	tracker.recordFailure("key:a", code_translator.ErrEmptyTranslation)
+	if tracker.blockedFor("key:a") > 0 {
+		t.Error("client blocked although a success came between its failures")
+	}
+}
+
+func TestFailureTrackerDisabled(t *testing.T) {
+	tracker := newFailureTracker(0, time.Minute)
+	for range 10 {
+		tracker.recordFailure("key:a", code_translator.ErrEmptyTranslation)
+	}
+	if tracker.blockedFor("key:a") > 0 {
+		t.Error("client blocked although blocking is disabled")
+	}
+}
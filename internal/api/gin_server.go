@@ -1,13 +1,22 @@
 package api
 
 import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/resume_token"
 	"code-bridge/internal/services"
+	"code-bridge/internal/source_fetcher"
 	"code-bridge/internal/sse"
+	"code-bridge/internal/version"
+	"code-bridge/internal/worker_pool"
 	"code-bridge/pkg/types"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,14 +28,55 @@ type GinServer struct {
 	logger   *zap.Logger
 	services *services.Services
 	sseHub   *sse.Hub
+	tokens   *resume_token.Signer
+	features types.FeatureFlags
+	draining atomic.Bool
+	// shuttingDown drains the server for good; PUT /admin/drain cannot undo it
+	shuttingDown atomic.Bool
+	// models resolves request model_alias values and restricts the models requests may name
+	models types.ModelConfig
+	// defaultProvider answers requests that name a model but no provider
+	defaultProvider string
+	failures        *failureTracker
+	// limiter bounds how often each client may request translations
+	limiter *rateLimiter
+	// maxCodeBytes is the largest code a translation request may carry
+	maxCodeBytes int
+	// jobs cancels queued and running translations by job id
+	jobs *jobCancels
+	// sseRetryMs is the reconnect delay sent to stream clients
+	sseRetryMs int
+	// heartbeatInterval is how long a stream may be silent before a keep-alive comment; zero disables them
+	heartbeatInterval time.Duration
+	// highPriorityClients may submit high priority jobs; empty allows every client
+	highPriorityClients map[string]bool
+	// metadataKeys are the request metadata keys clients may set; empty rejects all metadata
+	metadataKeys           map[string]bool
+	metadataMaxValueLength int
+	// sections selects the response sections that are streamed and persisted
+	sections sectionPolicy
+	// historyPreviewChars is how many characters of the code the history lists; zero lists it in full
+	historyPreviewChars int
+	// attachGracePeriod cancels jobs no client attached to within it; zero disables this
+	attachGracePeriod time.Duration
+	// replayMaxAge is how long after completing a persisted result is replayed to reconnecting clients
+	replayMaxAge time.Duration
+	// apiKeys are the keys accepted by the translation endpoints; empty disables authentication
+	apiKeys []string
+	// adminAPIKeys are the keys accepted by the /admin endpoints; empty disables authentication
+	adminAPIKeys []string
 }
 
-func NewGinServer(logger *zap.Logger, services *services.Services) *GinServer {
-	router := gin.Default()
-	router.Use(GinLogger(logger))
+func NewGinServer(logger *zap.Logger, services *services.Services, cfg *types.Config) *GinServer {
+	// gin.New instead of gin.Default: the default logger would duplicate every zap request log
+	router := gin.New()
+	// the SSE stream and synchronous translations last as long as the translation, so they are
+	// exempt from the request timeout; synchronous translations apply translationTimeout themselves
+	router.Use(GinLogger(logger), GinRecovery(logger), CORS(cfg.Server.AllowedOrigins), RequestTimeout(logger, cfg.Server.RequestTimeout, streamRoute, syncRoute))
 
 	// Initialize SSE Hub
-	sseHub := sse.NewHub()
+	bufferLimit := sse.BufferLimit{MaxBytes: cfg.SSE.BufferMaxBytes, Section: chunkSection}
+	sseHub := sse.NewHub(cfg.SSE.MaxStreams, cfg.SSE.OrphanTimeout, cfg.SSE.StreamTTL, bufferLimit, logger)
 	go sseHub.Run()
 
 	server := &GinServer{
@@ -34,6 +84,37 @@ func NewGinServer(logger *zap.Logger, services *services.Services) *GinServer {
 		logger:   logger,
 		services: services,
 		sseHub:   sseHub,
+		tokens:   resume_token.NewSigner(cfg.SSE.ResumeTokenSecret, cfg.SSE.ResumeTokenTTL),
+		features: cfg.Features,
+
+		models:          cfg.Models,
+		defaultProvider: cfg.Server.DefaultProvider(),
+		failures:        newFailureTracker(cfg.Abuse.MaxFailures, cfg.Abuse.Cooldown),
+		limiter:         newRateLimiter(cfg.Abuse.RateLimitRPM, cfg.Abuse.RateLimitBurst),
+		maxCodeBytes:    cfg.Translator.MaxCodeBytes,
+		jobs:            newJobCancels(),
+
+		sseRetryMs:          cfg.SSE.RetryMs,
+		heartbeatInterval:   cfg.SSE.HeartbeatInterval,
+		highPriorityClients: make(map[string]bool, len(cfg.WorkerPool.HighPriorityClients)),
+
+		metadataKeys:           make(map[string]bool, len(cfg.Metadata.AllowedKeys)),
+		metadataMaxValueLength: cfg.Metadata.MaxValueLength,
+
+		sections:            newSectionPolicy(cfg.Sections),
+		historyPreviewChars: cfg.Sections.HistoryPreviewChars,
+		replayMaxAge:        cfg.SSE.ReplayMaxAge,
+
+		attachGracePeriod: cfg.SSE.AttachGracePeriod,
+
+		apiKeys:      cfg.Server.APIKeys,
+		adminAPIKeys: cfg.Server.AdminAPIKeys,
+	}
+	for _, client := range cfg.WorkerPool.HighPriorityClients {
+		server.highPriorityClients[client] = true
+	}
+	for _, key := range cfg.Metadata.AllowedKeys {
+		server.metadataKeys[key] = true
 	}
 	server.SetupRoutes()
 	return server
@@ -44,6 +125,19 @@ func (s *GinServer) GetRouter() *gin.Engine {
 	return s.router
 }
 
+// Shutdown stops accepting translation jobs and ends all open streams, so SSE
+// handlers return and the HTTP server can shut down without waiting for translations
+func (s *GinServer) Shutdown() {
+	s.shuttingDown.Store(true)
+	ended := s.sseHub.Shutdown()
+	s.logger.Info("ended open streams for shutdown", zap.Int("streams", ended))
+}
+
+// Close stops the server's background goroutines; call it once the HTTP server has shut down
+func (s *GinServer) Close() {
+	s.sseHub.Stop()
+}
+
 func (s *GinServer) SetupRoutes() {
 	s.router.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -68,10 +162,41 @@ func (s *GinServer) SetupRoutes() {
 	})
 
 	s.router.GET("/health", s.HealthCheck)
-	s.router.POST("/translate", s.TranslateCode)
-	s.router.GET("/translate/stream/:id", s.StreamHandler)
+
+	// the translation routes run translations, spending provider quota, or expose them, so they
+	// require an API key when API_KEYS is set
+	requireAPIKey := APIKeyAuth(s.apiKeys)
+	s.router.POST("/translate", requireAPIKey, s.rateLimit, s.TranslateCode)
+	s.router.POST(syncRoute, requireAPIKey, s.rateLimit, s.TranslateSync)
+	s.router.GET(streamRoute, requireAPIKey, s.StreamHandler)
+	s.router.POST("/translate/:id/resume", requireAPIKey, s.ResumeTranslation)
+	s.router.POST("/translate/cancel/:id", requireAPIKey, s.CancelTranslation)
+	s.router.GET("/translate/history", requireAPIKey, s.ListHistory)
+	s.router.GET("/translations/:id", requireAPIKey, s.GetTranslation)
+	s.router.POST("/translations/:id/rerun", requireAPIKey, s.rateLimit, s.RerunTranslation)
+	s.router.GET("/models/aliases", s.ListModelAliases)
+
+	// draining stops an instance from taking jobs and the stats list live job ids, so the
+	// admin endpoints require an admin key when ADMIN_API_KEYS or API_KEYS is set
+	requireAdminKey := APIKeyAuth(s.adminAPIKeys)
+	s.router.PUT("/admin/drain", requireAdminKey, s.SetDrain)
+	s.router.GET("/admin/queue", requireAdminKey, s.QueueStats)
+	s.router.GET("/admin/streams", requireAdminKey, s.StreamStats)
+	if s.services.Metrics != nil {
+		s.registerStreamMetrics()
+		s.router.GET("/metrics", s.Metrics)
+	}
 }
 
+// streamRoute is the long-lived SSE route
+const streamRoute = "/translate/stream/:id"
+
+// translationTimeout bounds a translation once a worker picks it up
+const translationTimeout = 2 * time.Minute
+
+// featureProviderMetadata allows requests to set include_metadata (FEATURE_PROVIDER_METADATA=true)
+const featureProviderMetadata = "provider_metadata"
+
 // GinLogger returns a gin middleware for logging using zap
 func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -86,6 +211,19 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 	}
 }
 
+// GinRecovery returns a gin middleware that recovers from panics and logs them using zap
+func GinRecovery(logger *zap.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		logger.Error("panic recovered",
+			zap.Any("error", err),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Stack("stack"),
+		)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	})
+}
+
 // HealthCheck godoc
 // @Summary Health check endpoint
 // @Description Check if the API server is running
@@ -95,9 +233,14 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 // @Success 200 {object} map[string]interface{}
 // @Router /health [get]
 func (s *GinServer) HealthCheck(c *gin.Context) {
+	provider, model := s.services.CodeTranslatorService.ProviderInfo()
 	c.JSON(200, gin.H{
-		"status":  "healthy",
-		"service": "codebridge-api",
+		"status":   "healthy",
+		"service":  "codebridge-api",
+		"provider": provider,
+		"model":    model,
+		"version":  version.Version,
+		"build":    version.Info(),
 	})
 }
 
@@ -153,64 +296,396 @@ func (s *GinServer) HealthCheck(c *gin.Context) {
 // @Success 200 {string} string "SSE stream"
 // @Router /translate [post]
 func (s *GinServer) TranslateCode(c *gin.Context) {
+	req, priority, ok := s.prepareTranslation(c)
+	if !ok {
+		return
+	}
+	s.startTranslation(c, req, priority)
+}
+
+// startTranslation creates the stream and queues the job for a validated request,
+// answering with the job id, or writes the error response if the job cannot be created
+func (s *GinServer) startTranslation(c *gin.Context, req types.TranslateRequest, priority worker_pool.Priority) {
+	client := c.ClientIP()
+
+	// conversions between config formats need no model, so they are answered right away instead of queued
+	if response, ok := s.services.CodeTranslatorService.ConvertConfig(req); ok {
+		s.serveConversion(c, req, response)
+		return
+	}
+
+	// reject prompts that cannot fit the model context before creating a job
+	if !s.checkPromptSize(c, req) {
+		return
+	}
+
+	// create job id
+	id := newJobID()
+
+	// create channel for streaming
+	if err := s.sseHub.Create(id, s.sessionID(c)); err != nil {
+		s.logger.Warn("rejecting translation job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": "too_many_streams"})
+		return
+	}
+
+	translate := func(ctx context.Context, onChunk func(string) error) error {
+		// Use a timeout context; it starts when a worker picks the job up, not while it is queued
+		ctx, cancel := context.WithTimeout(ctx, translationTimeout)
+		defer cancel()
+		return s.services.CodeTranslatorService.TranslateCode(ctx, req, onChunk)
+	}
+	if req.Chunked {
+		job, ok := s.createChunkedJob(c, id, client, req)
+		if !ok {
+			s.sseHub.Remove(id)
+			return
+		}
+		translate = s.chunkedTranslation(job)
+	}
+
+	if !s.submitJob(c, id, client, priority, req, translate) {
+		return
+	}
+
+	s.logger.Info("translation job created", zap.String("id", id))
+	s.acceptJob(c, id)
+}
+
+// prepareTranslation binds and validates a translation request, fetching its source and
+// resolving its provider and model. If the request is rejected it writes the error
+// response and returns false.
+func (s *GinServer) prepareTranslation(c *gin.Context) (types.TranslateRequest, worker_pool.Priority, bool) {
+	if !s.admitTranslation(c) {
+		return types.TranslateRequest{}, 0, false
+	}
+
+	// bound the body before binding, so an oversized payload is rejected without being read whole
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, int64(requestBodyLimit(s.maxCodeBytes)))
 	var req types.TranslateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), "code": "request_too_large"})
+			return types.TranslateRequest{}, 0, false
+		}
 		c.JSON(400, gin.H{"error": err.Error()})
-		return
+		return types.TranslateRequest{}, 0, false
+	}
+
+	switch {
+	case req.Code != "" && req.SourceURL != "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and source_url are mutually exclusive"})
+		return types.TranslateRequest{}, 0, false
+	case req.Code == "" && req.SourceURL == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "one of code or source_url is required"})
+		return types.TranslateRequest{}, 0, false
+	case req.SourceURL != "":
+		code, err := s.services.SourceFetcher.Fetch(c.Request.Context(), req.SourceURL)
+		if err != nil {
+			s.logger.Warn("failed to fetch source url", zap.Error(err))
+			status := http.StatusBadGateway
+			if errors.Is(err, source_fetcher.ErrURLNotAllowed) {
+				status = http.StatusBadRequest
+			} else if errors.Is(err, source_fetcher.ErrSourceTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return types.TranslateRequest{}, 0, false
+		}
+		req.Code = code
+	}
+	priority, ok := s.checkTranslation(c, &req)
+	return req, priority, ok
+}
+
+// checkTranslation validates a request whose code is known and resolves its provider and
+// model. If the request is rejected it writes the error response and returns false.
+func (s *GinServer) checkTranslation(c *gin.Context, req *types.TranslateRequest) (worker_pool.Priority, bool) {
+	if len(req.Code) > s.maxCodeBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":  fmt.Sprintf("code is %d bytes, the limit is %d", len(req.Code), s.maxCodeBytes),
+			"code":   "code_too_large",
+			"limit":  s.maxCodeBytes,
+			"actual": len(req.Code),
+		})
+		return 0, false
+	}
+
+	if req.SourceLanguage == "" && req.Filename != "" {
+		req.SourceLanguage = code_translator.LanguageFromFilename(req.Filename)
+	}
+
+	// resolve the alias before header overrides so it counts as a body value and takes precedence
+	if err := s.resolveModelAlias(req); err != nil {
+		code := "invalid_model_alias"
+		if errors.Is(err, errUnknownModelAlias) {
+			code = "unknown_model_alias"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": code})
+		return 0, false
+	}
+	if err := applyHeaderOverrides(c, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	// a model belongs to a provider, so only requests naming neither are left to the strategy
+	if req.Provider == "" && req.Model == "" && s.services.ProviderSelector != nil {
+		req.Provider = s.services.ProviderSelector.Select()
+	}
+	if err := validateTranslateRequest(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if err := s.checkModelAllowed(*req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "model_not_allowed"})
+		return 0, false
+	}
+	if err := s.validateRequestMetadata(req.Metadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_metadata"})
+		return 0, false
+	}
+	// translation memory holds the code clients sent, so each caller only reads and adds to its own
+	req.MemoryScope = s.callerKey(c)
+	// provider metadata exposes internal details, so it is only available where explicitly enabled
+	if req.IncludeMetadata && !s.features.Enabled(featureProviderMetadata) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "include_metadata is not enabled on this server", "code": "metadata_disabled"})
+		return 0, false
+	}
+	// already validated above
+	priority, _ := worker_pool.ParsePriority(req.Priority)
+	if priority == worker_pool.PriorityHigh && !s.mayUseHighPriority(c.ClientIP()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "high priority is not allowed for this client", "code": "priority_not_allowed"})
+		return 0, false
 	}
 
 	s.logger.Info("translation request",
 		zap.String("source_language", req.SourceLanguage),
 		zap.String("target_language", req.TargetLanguage),
+		zap.String("provider", req.Provider),
+		zap.String("model", req.Model),
 		zap.Int("code_length", len(req.Code)),
+		metadataField(req.Metadata),
 	)
+	return priority, true
+}
 
-	// create job id
-	id := fmt.Sprintf("job-%d", time.Now().UnixNano())
+// admitTranslation turns new jobs away while the server drains and from clients blocked
+// for failing too often, writing the error response and returning false
+func (s *GinServer) admitTranslation(c *gin.Context) bool {
+	if s.isDraining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "server is draining, not accepting new jobs", "code": "draining"})
+		return false
+	}
+	if wait := s.failures.blockedFor(s.callerKey(c)); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many consecutive failed translations, try again later", "code": "too_many_failures"})
+		return false
+	}
+	return true
+}
 
-	// create channel for streaming
-	s.sseHub.Create(id)
+// checkPromptSize rejects requests whose prompt cannot fit the model context, writing
+// the error response and returning false
+func (s *GinServer) checkPromptSize(c *gin.Context, req types.TranslateRequest) bool {
+	err := s.services.CodeTranslatorService.CheckPromptSize(req)
+	if err == nil {
+		return true
+	}
+	var tooLarge *code_translator.ContextTooLargeError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":  err.Error(),
+			"code":   tooLarge.Code(),
+			"limit":  tooLarge.Limit,
+			"actual": tooLarge.Actual,
+		})
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return false
+}
 
-	s.logger.Info("translation job created", zap.String("id", id))
-	c.JSON(http.StatusAccepted, gin.H{"id": id})
+// submitJob queues a translation on the shared worker pool, scheduled fairly across
+// clients, relaying its chunks to id's stream. If the job cannot be queued it drops
+// the stream, writes a 503 and returns false.
+func (s *GinServer) submitJob(c *gin.Context, id, client string, priority worker_pool.Priority, req types.TranslateRequest, translate func(ctx context.Context, onChunk func(string) error) error) bool {
+	// cancelled by POST /translate/cancel/:id, whether the job is still queued or running
+	ctx, cancel := context.WithCancel(context.Background())
+	s.jobs.add(id, cancel)
+	// the stream may be cleaned up soon after it ends, so its owner is looked up now for the history
+	owner, _ := s.sseHub.Owner(id)
+	if s.attachGracePeriod > 0 {
+		go s.awaitAttach(ctx, id)
+	}
+	accepted := time.Now()
+	provider := s.providerLabel(req)
+	caller := s.callerKey(c)
 
-	// call translator in background
-	go func() {
-		// Use a timeout context
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	err := s.services.WorkerPool.Submit(client, priority, func() {
 		defer cancel()
-
-		time.Sleep(100 * time.Millisecond)
+		defer s.jobs.remove(id)
+		if ctx.Err() != nil {
+			s.logger.Info("skipping cancelled translation", zap.String("id", id))
+			return
+		}
 
 		s.logger.Info("starting translation", zap.String("id", id))
 
 		// translator will push messages to hub via callback
-		er := s.services.CodeTranslatorService.TranslateCode(ctx, req.Code, req.SourceLanguage, req.TargetLanguage, func(chunk string) error {
+		relay := s.relay(id)
+		history := newHistoryRecorder(s.sections)
+		er := translate(ctx, func(chunk string) error {
 			s.logger.Debug("sending chunk", zap.String("id", id), zap.Int("chunk_size", len(chunk)))
-			return s.sseHub.Send(id, chunk)
+			history.observe(chunk)
+			return relay(chunk)
 		})
+		if er != nil && ctx.Err() != nil {
+			// the cancel handler has already ended the stream; this is not the client's failure
+			s.logger.Info("translation cancelled", zap.String("id", id), metadataField(req.Metadata))
+			return
+		}
+		s.services.Metrics.TranslationFinished(provider, time.Since(accepted), er)
 		if er != nil {
-			s.logger.Error("translation error", zap.String("id", id), zap.Error(er))
+			s.logger.Error("translation error", zap.String("id", id), zap.Error(er), metadataField(req.Metadata))
 			_ = s.sseHub.Send(id, fmt.Sprintf("ERROR: %v", er))
+			s.failures.recordFailure(caller, er)
+			s.recordDeadLetter(id, client, req, er)
+		} else {
+			s.failures.recordSuccess(caller)
 		}
 		// Always signal end, even on error
 		s.logger.Info("translation finished, sending end signal", zap.String("id", id))
 		_ = s.sseHub.Send(id, "[DONE]")
-		s.logger.Info("translation completed", zap.String("id", id))
-	}()
+		s.logger.Info("translation completed", zap.String("id", id), metadataField(req.Metadata))
+		if er == nil {
+			s.recordTranslation(id, client, owner, req, history)
+		}
+	})
+	if err != nil {
+		s.jobs.remove(id)
+		cancel()
+		s.sseHub.Remove(id)
+		s.logger.Warn("rejecting translation job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": submitErrorCode(err)})
+		return false
+	}
+	s.services.Metrics.TranslationRequested(provider, languageLabel(req.TargetLanguage))
+	return true
+}
+
+// submitErrorCode returns the response code for a job the worker pool did not accept
+func submitErrorCode(err error) string {
+	if errors.Is(err, worker_pool.ErrPoolStopped) {
+		return "shutting_down"
+	}
+	return "queue_full"
+}
+
+// relay returns the callback sending a job's chunks to its stream, leaving out the
+// response sections that are not streamed
+func (s *GinServer) relay(id string) func(chunk string) error {
+	return func(chunk string) error {
+		if !s.sections.streams(chunk) {
+			return nil
+		}
+		return s.sseHub.Send(id, chunk)
+	}
+}
+
+// serveConversion creates a stream holding a finished deterministic config conversion,
+// so clients read it from the stream like any other translation
+func (s *GinServer) serveConversion(c *gin.Context, req types.TranslateRequest, response string) {
+	id := newJobID()
+	if err := s.sseHub.Create(id, s.sessionID(c)); err != nil {
+		s.logger.Warn("rejecting translation job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": "too_many_streams"})
+		return
+	}
+	relay := s.relay(id)
+	history := newHistoryRecorder(s.sections)
+	err := s.services.CodeTranslatorService.SendResponse(req, response, func(chunk string) error {
+		history.observe(chunk)
+		return relay(chunk)
+	})
+	if err != nil {
+		_ = s.sseHub.Send(id, fmt.Sprintf("ERROR: %v", err))
+	}
+	_ = s.sseHub.Send(id, "[DONE]")
+	if err == nil {
+		s.recordTranslation(id, c.ClientIP(), s.sessionID(c), req, history)
+	}
+
+	s.logger.Info("config converted without provider",
+		zap.String("id", id),
+		zap.String("source_language", req.SourceLanguage),
+		zap.String("target_language", req.TargetLanguage),
+		metadataField(req.Metadata),
+	)
+	s.acceptJob(c, id)
+}
+
+// acceptJob responds with the job id and a resume token for attaching to its stream
+func (s *GinServer) acceptJob(c *gin.Context, id string) {
+	token, expiresAt := s.tokens.Issue(id)
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":                      id,
+		"resume_token":            token,
+		"resume_token_expires_at": expiresAt,
+	})
 }
 
 // StreamHandler attaches client to SSE stream
 func (s *GinServer) StreamHandler(c *gin.Context) {
 	id := c.Param("id")
-	if id == "" {
-		c.Status(http.StatusBadRequest)
+	if !jobIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id", "code": "invalid_job_id"})
+		return
+	}
+	// a stream request never carries a body; one usually means the client meant to POST /translate
+	if c.Request.ContentLength != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "stream requests must not have a body", "code": "unexpected_body"})
+		return
+	}
+	filter, err := parseEventFilter(c.Query("events"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_events"})
+		return
+	}
+	encoding, err := parseStreamEncoding(c.Query("encoding"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "invalid_encoding"})
 		return
 	}
 
-	s.logger.Info("client connecting to stream", zap.String("id", id))
+	lastEventID := parseLastEventID(c.GetHeader(headerLastEventID))
 
-	client := s.sseHub.AddClient(id)
+	// only attach to jobs created by POST /translate; an unknown id would otherwise wait forever.
+	// Chunked jobs outlive their stream, so a completed one is replayed from the store.
+	if !s.sseHub.Exists(id) {
+		if !s.replayPersisted(c, id) {
+			return
+		}
+		// the replayed stream is numbered afresh, so ids from the original stream mean nothing
+		lastEventID = 0
+	} else if !s.authorizeStream(c, id) {
+		return
+	}
+
+	s.logger.Info("client connecting to stream", zap.String("id", id), zap.Int64("last_event_id", lastEventID))
+
+	client, err := s.sseHub.AddClient(id, lastEventID)
+	if errors.Is(err, sse.ErrStreamEnded) {
+		// 204 tells EventSource to stop reconnecting
+		s.logger.Info("client already received the whole stream", zap.String("id", id))
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		// the stream was cleaned up between the existence check and attaching
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found", "code": "job_not_found"})
+		return
+	}
 	defer func() {
 		s.logger.Info("client disconnecting from stream", zap.String("id", id))
 		s.sseHub.RemoveClient(id, client)
@@ -228,35 +703,60 @@ func (s *GinServer) StreamHandler(c *gin.Context) {
 		return
 	}
 
-	// Send initial connection message to establish the stream
+	// Send initial connection message to establish the stream, with the reconnect delay browsers should use
 	fmt.Fprintf(c.Writer, ": connected\n\n")
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", s.sseRetryMs)
 	flusher.Flush()
 
 	s.logger.Info("stream established", zap.String("id", id))
 
-	// send existing backlog (if any)
+	// heartbeats are written by this loop like messages, so they never land between parts of one;
+	// the ticker is reset whenever a message goes out, so only silent streams get them
+	var heartbeat *time.Ticker
+	var heartbeats <-chan time.Time
+	if s.heartbeatInterval > 0 {
+		heartbeat = time.NewTicker(s.heartbeatInterval)
+		defer heartbeat.Stop()
+		heartbeats = heartbeat.C
+	}
+
+	// the first batch is the existing backlog (if any)
 	for {
 		select {
-		case msg, ok := <-client.Ch:
+		case _, ok := <-client.Notify:
 			if !ok {
 				s.logger.Info("client channel closed", zap.String("id", id))
 				return
 			}
 
-			// Log what we're sending
-			s.logger.Debug("sending message to client",
-				zap.String("id", id),
-				zap.String("msg_preview", msg[:min(len(msg), 50)]))
-
-			// Send the message as-is (including [DONE])
-			fmt.Fprintf(c.Writer, "data: %s\n\n", msg)
-			flusher.Flush()
-
-			// Check if this is the end signal
-			if msg == "[DONE]" {
-				s.logger.Info("stream end signal sent to client", zap.String("id", id))
-				return
+			for _, msg := range client.Next() {
+				// Log what we're sending
+				s.logger.Debug("sending message to client",
+					zap.String("id", id),
+					zap.Int64("event_id", msg.ID),
+					zap.String("msg_preview", msg.Data[:min(len(msg.Data), 50)]))
+
+				if !filter.allows(msg.Data) {
+					continue
+				}
+				if heartbeat != nil {
+					heartbeat.Reset(s.heartbeatInterval)
+				}
+
+				// Send the message as-is (including [DONE]), with its id for resuming after it
+				fmt.Fprintf(c.Writer, "id: %d\ndata: %s\n\n", msg.ID, encodeChunk(msg.Data, encoding))
+
+				// Check if this is the end signal
+				if msg.Data == "[DONE]" {
+					flusher.Flush()
+					s.logger.Info("stream end signal sent to client", zap.String("id", id))
+					return
+				}
 			}
+			flusher.Flush()
+		case <-heartbeats:
+			fmt.Fprintf(c.Writer, ": keep-alive\n\n")
+			flusher.Flush()
 		case <-c.Request.Context().Done():
 			s.logger.Info("client context cancelled", zap.String("id", id))
 			return
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/translation_history"
+	"code-bridge/pkg/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// historyTimeout bounds each read or write of the translation history
+const historyTimeout = 5 * time.Second
+
+// History page sizes for GET /translate/history
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// historyEntry is a translation as GET /translate/history lists it
+type historyEntry struct {
+	types.Translation
+	// Truncated reports that source_code or translated_code were cut to the preview length
+	Truncated bool `json:"truncated"`
+}
+
+// newHistoryEntry cuts the code of translation to previewChars characters; zero keeps it whole
+func newHistoryEntry(translation types.Translation, previewChars int) historyEntry {
+	source, sourceCut := preview(translation.SourceCode, previewChars)
+	translated, translatedCut := preview(translation.TranslatedCode, previewChars)
+	translation.SourceCode, translation.TranslatedCode = source, translated
+	return historyEntry{Translation: translation, Truncated: sourceCut || translatedCut}
+}
+
+// preview returns the first chars characters of text, never splitting a multi-byte one,
+// and whether anything was cut off
+func preview(text string, chars int) (string, bool) {
+	if chars <= 0 || utf8.RuneCountInString(text) <= chars {
+		return text, false
+	}
+	for i := range text {
+		if chars == 0 {
+			return text[:i], true
+		}
+		chars--
+	}
+	return text, false
+}
+
+// historyRecorder keeps the final content of the persisted response sections as a job's chunks go by
+type historyRecorder struct {
+	policy   sectionPolicy
+	sections map[code_translator.ChunkType]string
+}
+
+func newHistoryRecorder(policy sectionPolicy) *historyRecorder {
+	return &historyRecorder{policy: policy, sections: make(map[code_translator.ChunkType]string)}
+}
+
+// observe records chunk if it is the final version of a persisted section
+func (r *historyRecorder) observe(chunk string) {
+	chunkType, ok := messageChunkType(chunk)
+	if !ok || !responseSections[chunkType] || !r.policy.persists(chunkType) {
+		return
+	}
+	var section code_translator.StreamChunk
+	if err := json.Unmarshal([]byte(chunk), &section); err != nil || section.Delta {
+		return
+	}
+	r.sections[chunkType] = section.Content
+}
+
+// recordTranslation stores a finished job in the history. Failures are only logged:
+// the client already has the result on its stream.
+func (s *GinServer) recordTranslation(id, client, owner string, req types.TranslateRequest, recorder *historyRecorder) {
+	ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+	defer cancel()
+
+	translation := types.Translation{
+		ID:             id,
+		ParentID:       req.ParentID,
+		Client:         client,
+		Owner:          owner,
+		SourceLanguage: req.SourceLanguage,
+		TargetLanguage: req.TargetLanguage,
+		SourceCode:     req.Code,
+		TranslatedCode: recorder.sections[code_translator.ChunkTypeCode],
+		Explanation:    recorder.sections[code_translator.ChunkTypeExplanation],
+		Notes:          recorder.sections[code_translator.ChunkTypeNotes],
+		Dependencies:   recorder.sections[code_translator.ChunkTypeDependencies],
+		Metadata:       req.Metadata,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.services.History.Save(ctx, translation); err != nil {
+		s.logger.Error("failed to save translation history", zap.String("id", id), zap.Error(err))
+	}
+}
+
+// ListHistory godoc
+// @Summary List finished translations
+// @Description Lists the calling session's finished translations, newest first. The source and translated code are cut to HISTORY_PREVIEW_CHARS characters, with truncated set; GET /translations/{id} returns them in full.
+// @Tags translation
+// @Produce json
+// @Param limit query int false "Page size (default 20, at most 100)"
+// @Param offset query int false "Number of translations to skip"
+// @Param If-None-Match header string false "ETag of a page fetched before"
+// @Success 200 {object} map[string]interface{}
+// @Success 304 "The page is unchanged"
+// @Router /translate/history [get]
+func (s *GinServer) ListHistory(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultHistoryLimit)
+	if err != nil || limit < 1 || limit > maxHistoryLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100", "code": "invalid_pagination"})
+		return
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "offset must not be negative", "code": "invalid_pagination"})
+		return
+	}
+
+	// history belongs to the session that created the jobs; without one there is none
+	translations := []types.Translation{}
+	if owner, err := c.Cookie(sessionCookie); err == nil && owner != "" {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), historyTimeout)
+		defer cancel()
+		translations, err = s.services.History.List(ctx, owner, limit, offset)
+		if err != nil {
+			s.logger.Error("failed to list translation history", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list translation history", "code": "history_failed"})
+			return
+		}
+	}
+
+	// the page is private to the session, and revalidated since a new translation changes it
+	etag := historyETag(translations, s.historyPreviewChars)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Vary", "Cookie")
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	entries := make([]historyEntry, len(translations))
+	for i, translation := range translations {
+		entries[i] = newHistoryEntry(translation, s.historyPreviewChars)
+	}
+	c.JSON(http.StatusOK, gin.H{"translations": entries, "limit": limit, "offset": offset})
+}
+
+// GetTranslation godoc
+// @Summary Get a stored translation
+// @Description Returns one of the calling session's finished translations with its code in full
+// @Tags translation
+// @Produce json
+// @Param id path string true "Translation id"
+// @Success 200 {object} types.Translation
+// @Router /translations/{id} [get]
+func (s *GinServer) GetTranslation(c *gin.Context) {
+	id := c.Param("id")
+	if !jobIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid translation id", "code": "invalid_job_id"})
+		return
+	}
+	translation, ok := s.ownTranslation(c, id)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, translation)
+}
+
+// historyETag identifies a history page by the ids and creation times of its translations;
+// stored translations don't change, so the same ones list the same way
+func historyETag(translations []types.Translation, previewChars int) string {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(previewChars)))
+	for _, translation := range translations {
+		h.Write([]byte("\n" + translation.ID + " " + strconv.FormatInt(translation.CreatedAt.UnixNano(), 10)))
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header value lists etag, comparing weakly
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// queryInt parses the integer query parameter name, returning fallback when it is absent
+func queryInt(c *gin.Context, name string, fallback int) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(raw)
+}
+
+// rerunRequest holds the settings a stored translation is re-run with; the fields left
+// empty are chosen as for a new request
+type rerunRequest struct {
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	ModelAlias string `json:"model_alias"`
+}
+
+// RerunTranslation godoc
+// @Summary Re-run a stored translation
+// @Description Translates the stored source of one of the calling session's translations again, e.g. with another provider or model. The result is stored with parent_id set to the original.
+// @Tags translation
+// @Accept json
+// @Produce json
+// @Param id path string true "Translation id"
+// @Param request body rerunRequest false "Provider and model to use"
+// @Success 202 {object} map[string]interface{}
+// @Router /translations/{id}/rerun [post]
+func (s *GinServer) RerunTranslation(c *gin.Context) {
+	id := c.Param("id")
+	if !jobIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid translation id", "code": "invalid_job_id"})
+		return
+	}
+	if !s.admitTranslation(c) {
+		return
+	}
+	var settings rerunRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	original, ok := s.ownTranslation(c, id)
+	if !ok {
+		return
+	}
+	req := types.TranslateRequest{
+		Code:                original.SourceCode,
+		SourceLanguage:      original.SourceLanguage,
+		TargetLanguage:      original.TargetLanguage,
+		Provider:            settings.Provider,
+		Model:               settings.Model,
+		ModelAlias:          settings.ModelAlias,
+		IncludeDependencies: original.Dependencies != "",
+		Metadata:            original.Metadata,
+		ParentID:            original.ID,
+	}
+	priority, ok := s.checkTranslation(c, &req)
+	if !ok {
+		return
+	}
+	s.startTranslation(c, req, priority)
+}
+
+// ownTranslation loads translation id of the calling session. Translations of other
+// sessions are reported missing like unknown ids, so their ids cannot be probed.
+// If it cannot be loaded it writes the error response and returns false.
+func (s *GinServer) ownTranslation(c *gin.Context, id string) (*types.Translation, bool) {
+	owner, err := c.Cookie(sessionCookie)
+	if err != nil || owner == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "translation not found", "code": "translation_not_found"})
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), historyTimeout)
+	defer cancel()
+	translation, err := s.services.History.Get(ctx, id)
+	switch {
+	case errors.Is(err, translation_history.ErrTranslationNotFound) || (err == nil && translation.Owner != owner):
+		c.JSON(http.StatusNotFound, gin.H{"error": "translation not found", "code": "translation_not_found"})
+		return nil, false
+	case err != nil:
+		s.logger.Error("failed to load translation", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load translation", "code": "history_failed"})
+		return nil, false
+	}
+	return translation, true
+}
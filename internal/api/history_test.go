@@ -0,0 +1,295 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/services"
+	"code-bridge/internal/translation_history"
+	"code-bridge/internal/worker_pool"
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// memoryHistory is a translation history kept in memory
+type memoryHistory struct {
+	mu           sync.Mutex
+	translations map[string]types.Translation
+}
+
+func newMemoryHistory(translations ...types.Translation) *memoryHistory {
+	h := &memoryHistory{translations: make(map[string]types.Translation)}
+	for _, translation := range translations {
+		h.translations[translation.ID] = translation
+	}
+	return h
+}
+
+func (h *memoryHistory) Save(_ context.Context, translation types.Translation) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.translations[translation.ID] = translation
+	return nil
+}
+
+func (h *memoryHistory) Get(_ context.Context, id string) (*types.Translation, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	translation, ok := h.translations[id]
+	if !ok {
+		return nil, translation_history.ErrTranslationNotFound
+	}
+	return &translation, nil
+}
+
+func (h *memoryHistory) List(_ context.Context, owner string, limit, offset int) ([]types.Translation, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var owned []types.Translation
+	for _, translation := range h.translations {
+		if translation.Owner == owner {
+			owned = append(owned, translation)
+		}
+	}
+	slices.SortFunc(owned, func(a, b types.Translation) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	owned = owned[min(offset, len(owned)):]
+	return owned[:min(limit, len(owned))], nil
+}
+
+// get returns the translation stored under id, if any
+func (h *memoryHistory) get(id string) (types.Translation, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	translation, ok := h.translations[id]
+	return translation, ok
+}
+
+// recordingProvider answers every prompt with response, recording the prompts
+type recordingProvider struct {
+	mu       sync.Mutex
+	response string
+	prompts  []string
+}
+
+func (p *recordingProvider) StreamCompletion(_ context.Context, prompt string, _ types.CompletionOptions, onChunk func(string) error) error {
+	p.mu.Lock()
+	p.prompts = append(p.prompts, prompt)
+	p.mu.Unlock()
+	return onChunk(p.response)
+}
+
+// storedTranslation is the original translation the rerun tests start from
+var storedTranslation = types.Translation{
+	ID:             "job-1",
+	Owner:          "session-a",
+	SourceLanguage: "python",
+	TargetLanguage: "go",
+	SourceCode:     "print(1)",
+	TranslatedCode: "println(1)",
+	Metadata:       map[string]string{"feature": "editor"},
+	CreatedAt:      time.Now(),
+}
+
+// sessionHeader returns the header of a request from session
+func sessionHeader(session string) http.Header {
+	return http.Header{"Cookie": {sessionCookie + "=" + session}}
+}
+
+func TestRerunTranslation(t *testing.T) {
+	history := newMemoryHistory(storedTranslation)
+	provider := &recordingProvider{response: "=== EXPLANATION ===\nPrints one.\n=== TRANSLATION NOTES ===\n- none\n=== TRANSLATED CODE ===\nfmt.Println(1)"}
+	var resolved []string
+	resolve := func(name string) (code_translator.TranslatorProviderInterface, error) {
+		resolved = append(resolved, name)
+		return provider, nil
+	}
+	pool := worker_pool.NewPool(zap.NewNop(), 1, 10, time.Second)
+	pool.Start()
+	t.Cleanup(func() { _ = pool.Stop(context.Background()) })
+	server := newTestServerWithDeps(t, services.Deps{
+		CodeTranslatorService: code_translator.NewCodeTranslatorService(zap.NewNop(), provider, resolve, nil, types.TranslatorConfig{}),
+		WorkerPool:            pool,
+		History:               history,
+	}, func(cfg *types.Config) {
+		cfg.Metadata = types.RequestMetadataConfig{AllowedKeys: []string{"feature"}, MaxValueLength: 32}
+	})
+
+	w := serve(server, http.MethodPost, "/translations/job-1/rerun", `{"provider":"anthropic"}`, sessionHeader("session-a"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", w.Code, w.Body)
+	}
+	var accepted struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil || accepted.ID == "" {
+		t.Fatalf("response %s has no job id", w.Body)
+	}
+
+	var rerun types.Translation
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var ok bool
+		if rerun, ok = history.get(accepted.ID); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("rerun was not stored in the history")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if rerun.ParentID != "job-1" || rerun.Owner != "session-a" {
+		t.Errorf("rerun parent = %q, owner = %q; want job-1 and session-a", rerun.ParentID, rerun.Owner)
+	}
+	if rerun.SourceCode != "print(1)" || rerun.TranslatedCode != "fmt.Println(1)" {
+		t.Errorf("rerun source = %q, translation = %q", rerun.SourceCode, rerun.TranslatedCode)
+	}
+	if rerun.Metadata["feature"] != "editor" {
+		t.Errorf("rerun metadata = %v, want the original's", rerun.Metadata)
+	}
+	if len(resolved) != 1 || resolved[0] != "anthropic" {
+		t.Errorf("providers resolved = %q, want anthropic", resolved)
+	}
+	if len(provider.prompts) != 1 || !strings.Contains(provider.prompts[0], "print(1)") {
+		t.Errorf("provider was not asked to translate the stored source")
+	}
+}
+
+func TestRerunTranslationNotFound(t *testing.T) {
+	server := newTestServerWithDeps(t, services.Deps{History: newMemoryHistory(storedTranslation)}, nil)
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		status int
+		code   string
+	}{
+		{"unknown id", "/translations/job-2/rerun", sessionHeader("session-a"), http.StatusNotFound, "translation_not_found"},
+		{"other session", "/translations/job-1/rerun", sessionHeader("session-b"), http.StatusNotFound, "translation_not_found"},
+		{"no session", "/translations/job-1/rerun", nil, http.StatusNotFound, "translation_not_found"},
+		{"invalid id", "/translations/1/rerun", sessionHeader("session-a"), http.StatusBadRequest, "invalid_job_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, http.MethodPost, tt.path, "", tt.header)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if code := errorCode(t, w.Body.Bytes()); code != tt.code {
+				t.Errorf("code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
+
+func TestListHistoryPreviewsCode(t *testing.T) {
+	long := storedTranslation
+	long.ID, long.SourceCode, long.TranslatedCode = "job-2", "print('héllo wörld')", "println(\"héllo wörld\")"
+	history := newMemoryHistory(storedTranslation, long)
+	server := newTestServerWithDeps(t, services.Deps{History: history}, func(cfg *types.Config) {
+		cfg.Sections.HistoryPreviewChars = 10
+	})
+
+	w := serve(server, http.MethodGet, "/translate/history", "", sessionHeader("session-a"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	var page struct {
+		Translations []struct {
+			ID             string `json:"id"`
+			SourceCode     string `json:"source_code"`
+			TranslatedCode string `json:"translated_code"`
+			Truncated      bool   `json:"truncated"`
+		} `json:"translations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Translations) != 2 {
+		t.Fatalf("listed %d translations, want 2", len(page.Translations))
+	}
+	for _, entry := range page.Translations {
+		switch entry.ID {
+		case "job-1":
+			if entry.Truncated || entry.SourceCode != "print(1)" {
+				t.Errorf("short translation = %+v, want it whole", entry)
+			}
+		case "job-2":
+			if !entry.Truncated || entry.SourceCode != "print('hél" || entry.TranslatedCode != "println(\"h" {
+				t.Errorf("long translation = %+v, want its code cut to 10 characters", entry)
+			}
+		}
+	}
+
+	w = serve(server, http.MethodGet, "/translations/job-2", "", sessionHeader("session-a"))
+	var full types.Translation
+	if err := json.Unmarshal(w.Body.Bytes(), &full); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /translations/job-2 = %d: %s", w.Code, w.Body)
+	}
+	if full.SourceCode != long.SourceCode || full.TranslatedCode != long.TranslatedCode {
+		t.Errorf("full translation = %q / %q, want the stored code", full.SourceCode, full.TranslatedCode)
+	}
+
+	w = serve(server, http.MethodGet, "/translations/job-2", "", sessionHeader("session-b"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("another session's GET status = %d, want 404", w.Code)
+	}
+}
+
+func TestPreviewKeepsRunesWhole(t *testing.T) {
+	tests := []struct {
+		text  string
+		chars int
+		want  string
+		cut   bool
+	}{
+		{"héllo", 2, "hé", true},
+		{"日本語", 2, "日本", true},
+		{"日本語", 3, "日本語", false},
+		{"héllo", 0, "héllo", false},
+		{"", 5, "", false},
+	}
+	for _, tt := range tests {
+		got, cut := preview(tt.text, tt.chars)
+		if got != tt.want || cut != tt.cut {
+			t.Errorf("preview(%q, %d) = %q, %v; want %q, %v", tt.text, tt.chars, got, cut, tt.want, tt.cut)
+		}
+	}
+}
+
+func TestListHistoryETag(t *testing.T) {
+	history := newMemoryHistory(storedTranslation)
+	server := newTestServerWithDeps(t, services.Deps{History: history}, nil)
+
+	w := serve(server, http.MethodGet, "/translate/history", "", sessionHeader("session-a"))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q; want 200 with an ETag", w.Code, etag)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "private, no-cache" {
+		t.Errorf("Cache-Control = %q, want private, no-cache", cacheControl)
+	}
+
+	header := sessionHeader("session-a")
+	header.Set("If-None-Match", `"other", W/`+etag)
+	w = serve(server, http.MethodGet, "/translate/history", "", header)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("revalidation status = %d with body %q, want an empty 304", w.Code, w.Body)
+	}
+
+	added := storedTranslation
+	added.ID, added.CreatedAt = "job-2", storedTranslation.CreatedAt.Add(time.Second)
+	_ = history.Save(context.Background(), added)
+	w = serve(server, http.MethodGet, "/translate/history", "", header)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after a new translation status = %d, ETag = %q; want 200 with a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
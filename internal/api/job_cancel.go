@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// cancelledSignal is sent on a job's stream, before [DONE], when a client cancels it
+const cancelledSignal = "[CANCELLED]"
+
+// jobCancels holds the cancel func of every queued or running job by job id
+type jobCancels struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+func newJobCancels() *jobCancels {
+	return &jobCancels{cancels: make(map[string]context.CancelFunc)}
+}
+
+// add registers cancel for id until remove is called
+func (j *jobCancels) add(id string, cancel context.CancelFunc) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cancels[id] = cancel
+}
+
+// remove forgets id once its job has finished
+func (j *jobCancels) remove(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.cancels, id)
+}
+
+// cancel cancels id's job, reporting false if it is not queued or running
+func (j *jobCancels) cancel(id string) bool {
+	j.mu.Lock()
+	cancel, ok := j.cancels[id]
+	delete(j.cancels, id)
+	j.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// CancelTranslation godoc
+// @Summary Cancel a translation job
+// @Description Stops a queued or running translation and ends its stream with [CANCELLED]
+// @Tags translation
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /translate/cancel/{id} [post]
+func (s *GinServer) CancelTranslation(c *gin.Context) {
+	id := c.Param("id")
+	if !jobIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id", "code": "invalid_job_id"})
+		return
+	}
+	if !s.sseHub.Exists(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "job not found", "code": "job_not_found"})
+		return
+	}
+	if !s.authorizeStream(c, id) {
+		return
+	}
+	if !s.jobs.cancel(id) {
+		c.JSON(http.StatusConflict, gin.H{"error": "job has already finished", "code": "job_finished"})
+		return
+	}
+
+	// ending the stream here rather than in the job drops anything it sends while it stops
+	_ = s.sseHub.Send(id, cancelledSignal)
+	_ = s.sseHub.Send(id, "[DONE]")
+
+	s.logger.Info("translation job cancelled", zap.String("id", id))
+	c.JSON(http.StatusOK, gin.H{"id": id, "status": "cancelled"})
+}
+
+// awaitAttach cancels id's job and discards its stream if no client attaches within the
+// attach grace period, so a job nobody will read stops spending provider tokens. ctx is
+// the job's context and ends the wait once the job is over.
+func (s *GinServer) awaitAttach(ctx context.Context, id string) {
+	attached, ok := s.sseHub.Attached(id)
+	if !ok {
+		return
+	}
+	timer := time.NewTimer(s.attachGracePeriod)
+	defer timer.Stop()
+
+	select {
+	case <-attached:
+	case <-ctx.Done():
+	case <-timer.C:
+		if s.jobs.cancel(id) {
+			s.sseHub.Remove(id)
+			s.logger.Info("no client attached, job cancelled",
+				zap.String("id", id),
+				zap.Duration("grace_period", s.attachGracePeriod),
+			)
+		}
+	}
+}
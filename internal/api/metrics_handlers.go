@@ -0,0 +1,77 @@
+package api
+
+import (
+	"bytes"
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/metrics"
+	"code-bridge/internal/sse"
+	"code-bridge/pkg/types"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// otherLanguage labels translations into languages code-bridge has no extension for,
+// so arbitrary target_language values can't create new series
+const otherLanguage = "other"
+
+// registerStreamMetrics exports the SSE hub load next to the translation metrics. The hub
+// is counted once per scrape, so the values agree with each other.
+func (s *GinServer) registerStreamMetrics() {
+	var counts sse.Counts
+	group := s.services.Metrics.NewGroup(func() { counts = s.sseHub.Counts() })
+	group.NewGaugeFunc("codebridge_sse_streams", "Streams held by the SSE hub.", func() float64 {
+		return float64(counts.Streams)
+	})
+	group.NewGaugeFunc("codebridge_sse_active_streams", "Streams held by the SSE hub that have not finished.", func() float64 {
+		return float64(counts.Active)
+	})
+	group.NewGaugeFunc("codebridge_sse_clients", "Clients connected to SSE streams.", func() float64 {
+		return float64(counts.Clients)
+	})
+	group.NewGaugeFunc("codebridge_sse_buffered_messages", "Messages buffered by all SSE streams.", func() float64 {
+		return float64(counts.BufferedMessages)
+	})
+	group.NewGaugeFunc("codebridge_sse_buffered_bytes", "Bytes buffered by all SSE streams.", func() float64 {
+		return float64(counts.BufferedBytes)
+	})
+	group.NewCounterFunc("codebridge_sse_streams_completed_total", "SSE streams that finished.", func() float64 {
+		return float64(counts.Completed)
+	})
+}
+
+// Metrics godoc
+// @Summary Prometheus metrics
+// @Description Returns translation, provider and SSE hub metrics in the Prometheus text format
+// @Tags admin
+// @Produce plain
+// @Success 200 {string} string "Prometheus metrics"
+// @Router /metrics [get]
+func (s *GinServer) Metrics(c *gin.Context) {
+	var body bytes.Buffer
+	if err := s.services.Metrics.Write(&body); err != nil {
+		s.logger.Error("failed to write metrics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write metrics", "code": "metrics_failed"})
+		return
+	}
+	c.Data(http.StatusOK, metrics.ContentType, body.Bytes())
+}
+
+// providerLabel is the provider req is sent to first; req.Provider has already been validated
+func (s *GinServer) providerLabel(req types.TranslateRequest) string {
+	if req.Provider != "" {
+		return req.Provider
+	}
+	return s.defaultProvider
+}
+
+// languageLabel maps language onto the known languages, or otherLanguage
+func languageLabel(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code_translator.IsKnownLanguage(language) {
+		return language
+	}
+	return otherLanguage
+}
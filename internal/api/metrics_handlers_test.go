@@ -0,0 +1,57 @@
+package api
+
+import (
+	"code-bridge/internal/metrics"
+	"code-bridge/internal/services"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStreamMetricsShareOneSnapshot(t *testing.T) {
+	server := newTestServerWithDeps(t, services.Deps{Metrics: metrics.New()}, nil)
+	for _, id := range []string{"job-1", "job-2"} {
+		if err := server.sseHub.Create(id, "owner"); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	for _, msg := range []string{"first", "second", "[DONE]"} {
+		if err := server.sseHub.Send("job-1", msg); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if _, err := server.sseHub.AddClient("job-2", 0); err != nil {
+		t.Fatalf("AddClient: %v", err)
+	}
+	counts := server.sseHub.Counts()
+
+	w := serve(server, http.MethodGet, "/metrics", "", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	for _, want := range []string{
+		"codebridge_sse_streams 2\n",
+		"codebridge_sse_active_streams 1\n",
+		"codebridge_sse_clients 1\n",
+		"codebridge_sse_buffered_messages 3\n",
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, w.Body)
+		}
+	}
+	if counts.BufferedMessages != 3 || counts.BufferedBytes == 0 {
+		t.Errorf("Counts = %+v, want 3 buffered messages and their bytes", counts)
+	}
+
+	// concurrent scrapes each take their own snapshot; run with -race
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(server, http.MethodGet, "/metrics", "", nil)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"code-bridge/pkg/types"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errUnknownModelAlias is returned for model_alias values that are not configured
+var errUnknownModelAlias = errors.New("unknown model alias")
+
+// modelAliasResponse is one entry of the alias listing
+type modelAliasResponse struct {
+	Alias string `json:"alias"`
+	types.ModelAlias
+}
+
+// ListModelAliases returns the configured model aliases
+// @Summary List model aliases
+// @Description Lists the names accepted in model_alias and the provider and model each resolves to
+// @Tags models
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /models/aliases [get]
+func (s *GinServer) ListModelAliases(c *gin.Context) {
+	aliases := make([]modelAliasResponse, 0, len(s.models.Aliases))
+	for name, alias := range s.models.Aliases {
+		aliases = append(aliases, modelAliasResponse{Alias: name, ModelAlias: alias})
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Alias < aliases[j].Alias })
+
+	c.JSON(http.StatusOK, gin.H{"aliases": aliases})
+}
+
+// checkModelAllowed returns an error if req names a model the allow-list of its provider, or
+// of the default provider when it names none, does not include
+func (s *GinServer) checkModelAllowed(req types.TranslateRequest) error {
+	if req.Model == "" {
+		return nil
+	}
+	provider := req.Provider
+	if provider == "" {
+		provider = s.defaultProvider
+	}
+	if !s.models.ModelAllowed(provider, req.Model) {
+		return fmt.Errorf("model %q is not allowed for provider %s", req.Model, provider)
+	}
+	return nil
+}
+
+// resolveModelAlias replaces req.ModelAlias with the provider and model it names.
+// An alias cannot be combined with an explicit provider or model in the body.
+func (s *GinServer) resolveModelAlias(req *types.TranslateRequest) error {
+	if req.ModelAlias == "" {
+		return nil
+	}
+	if req.Provider != "" || req.Model != "" {
+		return errors.New("model_alias cannot be combined with provider or model")
+	}
+	alias, ok := s.models.Aliases[strings.ToLower(req.ModelAlias)]
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownModelAlias, req.ModelAlias)
+	}
+	req.Provider, req.Model = alias.Provider, alias.Model
+	return nil
+}
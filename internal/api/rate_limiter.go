@@ -0,0 +1,87 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitPruneInterval is how often buckets that have refilled are dropped
+const rateLimitPruneInterval = time.Minute
+
+// rateLimiter is a token bucket per client: each request takes a token, and tokens
+// are added back at a steady rate up to the burst size
+type rateLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter allowing requestsPerMinute with bursts of up to burst
+// requests; requestsPerMinute <= 0 disables limiting
+func newRateLimiter(requestsPerMinute, burst int) *rateLimiter {
+	return &rateLimiter{
+		perSecond: float64(requestsPerMinute) / 60,
+		burst:     float64(max(burst, 1)),
+		buckets:   make(map[string]*tokenBucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// allow takes a token from client's bucket, or returns how long until one is available
+func (l *rateLimiter) allow(client string) (time.Duration, bool) {
+	if l.perSecond <= 0 {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.lastPrune) >= rateLimitPruneInterval {
+		l.prune(now)
+	}
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = bucket
+	}
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.perSecond)
+	bucket.last = now
+	if bucket.tokens < 1 {
+		return time.Duration((1 - bucket.tokens) / l.perSecond * float64(time.Second)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
+
+// prune drops buckets idle long enough to have refilled, which are the same as new ones; l.mu must be held
+func (l *rateLimiter) prune(now time.Time) {
+	refill := time.Duration(l.burst / l.perSecond * float64(time.Second))
+	for client, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= refill {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastPrune = now
+}
+
+// rateLimit is a gin middleware answering 429 to clients over the rate limit
+func (s *GinServer) rateLimit(c *gin.Context) {
+	if wait, ok := s.limiter.allow(s.callerKey(c)); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded, try again later", "code": "rate_limited"})
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"code-bridge/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitedRouter returns a router answering 200 behind server's rate limit middleware
+func rateLimitedRouter(server *GinServer) *gin.Engine {
+	router := gin.New()
+	router.GET("/limited", server.rateLimit, func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// get sends a request to router with apiKey as a Bearer token, if set
+func get(router *gin.Engine, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimitRejectsRequestsPastBurst(t *testing.T) {
+	server := newTestServer(t, func(cfg *types.Config) {
+		cfg.Abuse.RateLimitRPM = 1
+		cfg.Abuse.RateLimitBurst = 3
+	})
+	router := rateLimitedRouter(server)
+
+	for i := range 3 {
+		if w := get(router, ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: status %d, want 200", i+1, w.Code)
+		}
+	}
+	w := get(router, "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request past the burst: status %d, want 429", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("Retry-After %q is not a number of seconds", w.Header().Get("Retry-After"))
+	}
+	// one token refills per minute
+	if retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("Retry-After = %d, want between 1 and 60", retryAfter)
+	}
+	if !strings.Contains(w.Body.String(), `"code":"rate_limited"`) {
+		t.Errorf("body = %s, want code rate_limited", w.Body.String())
+	}
+}
+
+func TestRateLimitKeysOnAPIKey(t *testing.T) {
+	server := newTestServer(t, func(cfg *types.Config) {
+		cfg.Server.APIKeys = []string{"key-a", "key-b"}
+		cfg.Abuse.RateLimitRPM = 1
+		cfg.Abuse.RateLimitBurst = 1
+	})
+	router := rateLimitedRouter(server)
+
+	if w := get(router, "key-a"); w.Code != http.StatusOK {
+		t.Fatalf("first request with key-a: status %d, want 200", w.Code)
+	}
+	if w := get(router, "key-a"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("second request with key-a: status %d, want 429", w.Code)
+	}
+	// same IP, but another key has its own bucket
+	if w := get(router, "key-b"); w.Code != http.StatusOK {
+		t.Errorf("first request with key-b: status %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	server := newTestServer(t, func(cfg *types.Config) {
+		cfg.Abuse.RateLimitRPM = 0
+		cfg.Abuse.RateLimitBurst = 1
+	})
+	router := rateLimitedRouter(server)
+
+	for i := range 20 {
+		if w := get(router, ""); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i+1, w.Code)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+// validateRequestMetadata checks client metadata against the allowed keys and value length
+func (s *GinServer) validateRequestMetadata(metadata map[string]string) error {
+	if len(metadata) == 0 {
+		return nil
+	}
+	if len(s.metadataKeys) == 0 {
+		return errors.New("metadata is not accepted by this server")
+	}
+	// sorted so the error names the same key every time
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		if !s.metadataKeys[key] {
+			return fmt.Errorf("metadata key %q is not allowed, allowed keys are %s", key, strings.Join(slices.Sorted(maps.Keys(s.metadataKeys)), ", "))
+		}
+		value := metadata[key]
+		if value == "" {
+			return fmt.Errorf("metadata %q must not be empty", key)
+		}
+		if utf8.RuneCountInString(value) > s.metadataMaxValueLength {
+			return fmt.Errorf("metadata %q exceeds %d characters", key, s.metadataMaxValueLength)
+		}
+	}
+	return nil
+}
+
+// metadataField logs validated request metadata as a single object field
+func metadataField(metadata map[string]string) zap.Field {
+	if len(metadata) == 0 {
+		return zap.Skip()
+	}
+	return zap.Any("metadata", metadata)
+}
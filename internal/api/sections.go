@@ -0,0 +1,53 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/pkg/types"
+)
+
+// responseSections are the chunk types carrying a response section
+var responseSections = map[code_translator.ChunkType]bool{
+	code_translator.ChunkTypeExplanation:  true,
+	code_translator.ChunkTypeNotes:        true,
+	code_translator.ChunkTypeDependencies: true,
+	code_translator.ChunkTypeCode:         true,
+}
+
+// sectionPolicy decides which response sections are streamed to clients and which are
+// persisted, independently, so e.g. everything can be streamed while only code is stored
+type sectionPolicy struct {
+	streamed  map[code_translator.ChunkType]bool
+	persisted map[code_translator.ChunkType]bool
+}
+
+func newSectionPolicy(cfg types.SectionsConfig) sectionPolicy {
+	policy := sectionPolicy{
+		streamed:  make(map[code_translator.ChunkType]bool, len(cfg.Streamed)),
+		persisted: make(map[code_translator.ChunkType]bool, len(cfg.Persisted)),
+	}
+	for _, section := range cfg.Streamed {
+		policy.streamed[code_translator.ChunkType(section)] = true
+	}
+	for _, section := range cfg.Persisted {
+		policy.persisted[code_translator.ChunkType(section)] = true
+	}
+	return policy
+}
+
+// streams reports whether msg is sent to the job's stream; anything but a response section always is
+func (p sectionPolicy) streams(msg string) bool {
+	// the common case needs no parsing of every chunk
+	if len(p.streamed) == len(responseSections) {
+		return true
+	}
+	chunkType, ok := messageChunkType(msg)
+	if !ok || !responseSections[chunkType] {
+		return true
+	}
+	return p.streamed[chunkType]
+}
+
+// persists reports whether section is kept when a finished translation is stored
+func (p sectionPolicy) persists(section code_translator.ChunkType) bool {
+	return p.persisted[section]
+}
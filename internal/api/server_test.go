@@ -0,0 +1,86 @@
+package api
+
+import (
+	"code-bridge/internal/chunked_job"
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/services"
+	"code-bridge/pkg/types"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestServer creates a server without a database or providers; the config is
+// adjusted by configure before the routes are set up
+func newTestServer(t *testing.T, configure func(cfg *types.Config)) *GinServer {
+	t.Helper()
+	return newTestServerWithDeps(t, services.Deps{}, configure)
+}
+
+// newTestServerWithDeps is newTestServer using the services in deps; a missing translator
+// has no provider, and missing chunked jobs hold none
+func newTestServerWithDeps(t *testing.T, deps services.Deps, configure func(cfg *types.Config)) *GinServer {
+	t.Helper()
+	cfg := &types.Config{
+		Server:     types.ServerConfig{Provider: "gemini", RequestTimeout: 30 * time.Second},
+		SSE:        types.SSEConfig{MaxStreams: 10, ResumeTokenTTL: time.Minute, OrphanTimeout: time.Minute},
+		Abuse:      types.AbuseConfig{MaxFailures: 5, Cooldown: time.Minute},
+		Translator: types.TranslatorConfig{MaxCodeBytes: 1 << 20},
+		Sections: types.SectionsConfig{
+			Streamed:  []string{"explanation", "notes", "dependencies", "code"},
+			Persisted: []string{"explanation", "notes", "dependencies", "code"},
+		},
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	if deps.CodeTranslatorService == nil {
+		deps.CodeTranslatorService = code_translator.NewCodeTranslatorService(zap.NewNop(), nil, nil, nil, types.TranslatorConfig{})
+	}
+	if deps.ChunkedJobs == nil {
+		deps.ChunkedJobs = noChunkedJobs{}
+	}
+	server := NewGinServer(zap.NewNop(), services.NewServices(deps), cfg)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// noChunkedJobs is a chunked job store that holds no jobs
+type noChunkedJobs struct{}
+
+func (noChunkedJobs) Create(context.Context, types.ChunkedJob) error { return nil }
+
+func (noChunkedJobs) SavePart(context.Context, string, int, string) error { return nil }
+
+func (noChunkedJobs) Load(context.Context, string) (*types.ChunkedJob, error) {
+	return nil, chunked_job.ErrJobNotFound
+}
+
+// serve sends a request to server and returns the recorded response
+func serve(server *GinServer, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	recorder := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(recorder, req)
+	return recorder
+}
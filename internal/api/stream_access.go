@@ -0,0 +1,74 @@
+package api
+
+import (
+	"code-bridge/internal/resume_token"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// resumeTokenHeader carries the token returned by POST /translate when attaching to a stream
+	resumeTokenHeader = "X-Resume-Token"
+	// sessionCookie identifies the client that created a job so it can read the stream without a token
+	sessionCookie = "codebridge_session"
+)
+
+// sessionID returns the requester's session id, issuing a new session cookie if there is none
+func (s *GinServer) sessionID(c *gin.Context) string {
+	if id, err := c.Cookie(sessionCookie); err == nil && id != "" {
+		return id
+	}
+
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	id := hex.EncodeToString(buf)
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookie, id, 0, "/", "", c.Request.TLS != nil, true)
+	return id
+}
+
+// authorizeStream allows attaching to id's stream with a valid resume token, or
+// from the session that created the job. Otherwise it writes a 403 and returns false.
+func (s *GinServer) authorizeStream(c *gin.Context, id string) bool {
+	owner, _ := s.sseHub.Owner(id)
+	return s.authorizeJob(c, id, owner)
+}
+
+// authorizeJob allows access to job id with a valid resume token, or from the
+// owner session. Otherwise it writes a 403 and returns false.
+func (s *GinServer) authorizeJob(c *gin.Context, id, owner string) bool {
+	// EventSource cannot set headers, so the token is also accepted as a query parameter
+	token := c.GetHeader(resumeTokenHeader)
+	if token == "" {
+		token = c.Query("resume_token")
+	}
+	if token != "" {
+		err := s.tokens.Verify(token, id)
+		if err == nil {
+			return true
+		}
+		code := "invalid_resume_token"
+		if errors.Is(err, resume_token.ErrTokenExpired) {
+			code = "resume_token_expired"
+		}
+		s.logger.Warn("rejected stream token", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": code})
+		return false
+	}
+
+	session, err := c.Cookie(sessionCookie)
+	if owner != "" && err == nil && subtle.ConstantTimeCompare([]byte(owner), []byte(session)) == 1 {
+		return true
+	}
+
+	s.logger.Warn("rejected stream access", zap.String("id", id))
+	c.JSON(http.StatusForbidden, gin.H{"error": "not allowed to access this job", "code": "forbidden"})
+	return false
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// encodingBase64 is the only ?encoding= value; it base64-encodes each chunk's content
+const encodingBase64 = "base64"
+
+// parseStreamEncoding validates the ?encoding= query parameter; empty means plain text
+func parseStreamEncoding(raw string) (string, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(raw)); encoding {
+	case "", encodingBase64:
+		return encoding, nil
+	default:
+		return "", fmt.Errorf("unsupported encoding %q", raw)
+	}
+}
+
+// encodeChunk returns msg with its content encoded as requested. Messages that
+// are not chunks ([DONE], "ERROR: ..." lines) are passed through unchanged.
+func encodeChunk(msg, encoding string) string {
+	if encoding != encodingBase64 || !strings.HasPrefix(msg, "{") {
+		return msg
+	}
+	var chunk code_translator.StreamChunk
+	if err := json.Unmarshal([]byte(msg), &chunk); err != nil {
+		return msg
+	}
+	chunk.Content = base64.StdEncoding.EncodeToString([]byte(chunk.Content))
+	encoded, err := json.Marshal(chunk)
+	if err != nil {
+		return msg
+	}
+	return string(encoded)
+}
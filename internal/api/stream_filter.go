@@ -0,0 +1,77 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// eventFilter is the set of chunk types a stream client asked for via ?events=; nil delivers everything
+type eventFilter map[code_translator.ChunkType]bool
+
+// parseEventFilter parses a comma-separated list of chunk types such as "code,notes"
+func parseEventFilter(raw string) (eventFilter, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	filter := eventFilter{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !code_translator.IsChunkType(name) {
+			return nil, fmt.Errorf("unknown event type %q", name)
+		}
+		filter[code_translator.ChunkType(name)] = true
+	}
+	if len(filter) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// allows reports whether msg should be delivered. Messages that are not chunks
+// ([DONE], "ERROR: ..." lines) and error chunks are always delivered so clients
+// still see the stream end and why it failed.
+func (f eventFilter) allows(msg string) bool {
+	if f == nil {
+		return true
+	}
+	chunkType, ok := messageChunkType(msg)
+	if !ok {
+		return true
+	}
+	return chunkType == code_translator.ChunkTypeError || f[chunkType]
+}
+
+// chunkSection returns the chunk type of msg and whether it is a delta, which carries its
+// section's content so far and is therefore replaced by any later chunk of that type
+func chunkSection(msg string) (string, bool) {
+	if !strings.HasPrefix(msg, "{") {
+		return "", false
+	}
+	var chunk struct {
+		Type  code_translator.ChunkType `json:"type"`
+		Delta bool                      `json:"delta"`
+	}
+	if err := json.Unmarshal([]byte(msg), &chunk); err != nil {
+		return "", false
+	}
+	return string(chunk.Type), chunk.Delta
+}
+
+// messageChunkType returns the type of a chunk message, or false for messages that are not chunks
+func messageChunkType(msg string) (code_translator.ChunkType, bool) {
+	if !strings.HasPrefix(msg, "{") {
+		return "", false
+	}
+	var chunk struct {
+		Type code_translator.ChunkType `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(msg), &chunk); err != nil {
+		return "", false
+	}
+	return chunk.Type, true
+}
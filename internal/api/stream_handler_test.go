@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestStreamHandlerUnknownJob(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	response := serve(server, http.MethodGet, "/translate/stream/job-123", "", nil)
+	if response.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not JSON: %v", err)
+	}
+	if body.Code != "job_not_found" {
+		t.Errorf("code = %q, want job_not_found", body.Code)
+	}
+}
+
+// streamJob creates job id's stream with msgs and returns a header authorizing access to it
+func streamJob(t *testing.T, server *GinServer, id string, msgs ...string) http.Header {
+	t.Helper()
+	if err := server.sseHub.Create(id, "owner"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, msg := range msgs {
+		if err := server.sseHub.Send(id, msg); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	token, _ := server.tokens.Issue(id)
+	header := http.Header{}
+	header.Set(resumeTokenHeader, token)
+	return header
+}
+
+func TestStreamHandlerResumesAfterLastEventID(t *testing.T) {
+	server := newTestServer(t, nil)
+	header := streamJob(t, server, "job-1", "first", "second", "[DONE]")
+	header.Set(headerLastEventID, "1")
+
+	response := serve(server, http.MethodGet, "/translate/stream/job-1", "", header)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusOK)
+	}
+	body := response.Body.String()
+	if strings.Contains(body, "data: first") {
+		t.Errorf("resumed stream repeated the message before Last-Event-ID:\n%s", body)
+	}
+	for _, want := range []string{"id: 2\ndata: second\n\n", "id: 3\ndata: [DONE]\n\n"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("resumed stream is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestStreamHandlerAfterDoneReturnsNoContent(t *testing.T) {
+	server := newTestServer(t, nil)
+	header := streamJob(t, server, "job-1", "first", "[DONE]")
+	header.Set(headerLastEventID, "2")
+
+	response := serve(server, http.MethodGet, "/translate/stream/job-1", "", header)
+	if response.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", response.Code, http.StatusNoContent)
+	}
+	if response.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", response.Body.String())
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// headerLastEventID is sent by reconnecting EventSource clients with the id of the last event they received
+const headerLastEventID = "Last-Event-ID"
+
+// parseLastEventID returns the event id a reconnecting client last received. Anything that
+// is not an id the stream handed out is 0, so the client gets the whole stream.
+func parseLastEventID(raw string) int64 {
+	id, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// syncRoute translates without a stream, answering with the whole result once it is done
+const syncRoute = "/translate/sync"
+
+// mimeMarkdown answers synchronous translations as a Markdown document
+const mimeMarkdown = "text/markdown"
+
+// syncFormats are the response types of POST /translate/sync, by the Accept header; the first is the default
+var syncFormats = []string{gin.MIMEJSON, gin.MIMEPlain, mimeMarkdown}
+
+// syncResult collects what a stream client would have received of a translation: the final
+// version of each section, and the warnings, patch, notebook and metadata chunks
+type syncResult struct {
+	policy   sectionPolicy
+	chunks   map[code_translator.ChunkType]code_translator.StreamChunk
+	warnings []string
+}
+
+func newSyncResult(policy sectionPolicy) *syncResult {
+	return &syncResult{policy: policy, chunks: make(map[code_translator.ChunkType]code_translator.StreamChunk)}
+}
+
+// observe records chunk if it is one the response is built from
+func (r *syncResult) observe(chunk string) {
+	if !r.policy.streams(chunk) {
+		return
+	}
+	var parsed code_translator.StreamChunk
+	if err := json.Unmarshal([]byte(chunk), &parsed); err != nil || parsed.Delta {
+		return
+	}
+	switch parsed.Type {
+	case code_translator.ChunkTypeWarning:
+		r.warnings = append(r.warnings, parsed.Content)
+	case code_translator.ChunkTypePatch, code_translator.ChunkTypeNotebook, code_translator.ChunkTypeMetadata:
+		r.chunks[parsed.Type] = parsed
+	default:
+		if responseSections[parsed.Type] {
+			r.chunks[parsed.Type] = parsed
+		}
+	}
+}
+
+// response builds the JSON body for the finished translation id. Sections left out of
+// the stream by SECTIONS_STREAMED are left out here too.
+func (r *syncResult) response(id string, req types.TranslateRequest) gin.H {
+	body := gin.H{"id": id}
+	for _, section := range []code_translator.ChunkType{code_translator.ChunkTypeExplanation, code_translator.ChunkTypeNotes, code_translator.ChunkTypeCode} {
+		if r.policy.streamed[section] {
+			body[string(section)] = r.chunks[section].Content
+		}
+	}
+	if req.IncludeDependencies && r.policy.streamed[code_translator.ChunkTypeDependencies] {
+		dependencies := r.chunks[code_translator.ChunkTypeDependencies].Items
+		if dependencies == nil {
+			dependencies = []string{}
+		}
+		body["dependencies"] = dependencies
+	}
+	if chunk, ok := r.chunks[code_translator.ChunkTypePatch]; ok {
+		body["patch"] = chunk.Content
+	}
+	if chunk, ok := r.chunks[code_translator.ChunkTypeNotebook]; ok {
+		body["notebook"] = chunk.Content
+	}
+	if chunk, ok := r.chunks[code_translator.ChunkTypeMetadata]; ok {
+		body["metadata"] = chunk.Metadata
+	}
+	if len(r.warnings) > 0 {
+		body["warnings"] = r.warnings
+	}
+	return body
+}
+
+// markdown formats the finished translation as a document with a heading per section,
+// leaving out the same sections as response
+func (r *syncResult) markdown(req types.TranslateRequest) string {
+	var b strings.Builder
+	section := func(heading, content string) {
+		if content = strings.TrimSpace(content); content != "" {
+			b.WriteString("## " + heading + "\n\n" + content + "\n\n")
+		}
+	}
+	list := func(items []string) string {
+		var lines []string
+		for _, item := range items {
+			lines = append(lines, "- "+item)
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	if r.policy.streamed[code_translator.ChunkTypeExplanation] {
+		section("Explanation", r.chunks[code_translator.ChunkTypeExplanation].Content)
+	}
+	if r.policy.streamed[code_translator.ChunkTypeNotes] {
+		section("Notes", r.chunks[code_translator.ChunkTypeNotes].Content)
+	}
+	if req.IncludeDependencies && r.policy.streamed[code_translator.ChunkTypeDependencies] {
+		section("Dependencies", list(r.chunks[code_translator.ChunkTypeDependencies].Items))
+	}
+	if code := r.chunks[code_translator.ChunkTypeCode].Content; code != "" {
+		section("Code", "```"+strings.ToLower(req.TargetLanguage)+"\n"+strings.TrimSpace(code)+"\n```")
+	}
+	if chunk, ok := r.chunks[code_translator.ChunkTypePatch]; ok {
+		section("Patch", "```diff\n"+strings.TrimSpace(chunk.Content)+"\n```")
+	}
+	section("Warnings", list(r.warnings))
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// write answers with the finished translation id in format, one of syncFormats
+func (r *syncResult) write(c *gin.Context, format, id string, req types.TranslateRequest) {
+	switch format {
+	case gin.MIMEPlain:
+		c.String(http.StatusOK, "%s", r.chunks[code_translator.ChunkTypeCode].Content)
+	case mimeMarkdown:
+		c.Data(http.StatusOK, mimeMarkdown+"; charset=utf-8", []byte(r.markdown(req)))
+	default:
+		c.JSON(http.StatusOK, r.response(id, req))
+	}
+}
+
+// TranslateSync godoc
+// @Summary Translate code without streaming
+// @Description Runs a translation to completion and returns it in a single response: JSON with all sections (default), only the code for Accept: text/plain, or a Markdown document for Accept: text/markdown. Other Accept values get 406.
+// @Tags translation
+// @Accept json
+// @Produce json,plain,markdown
+// @Param request body types.TranslateRequest true "Translation request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 406 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Failure 504 {object} map[string]interface{}
+// @Router /translate/sync [post]
+func (s *GinServer) TranslateSync(c *gin.Context) {
+	// the format is settled first, so no translation runs for a response the client cannot take
+	format := c.NegotiateFormat(syncFormats...)
+	if format == "" {
+		c.JSON(http.StatusNotAcceptable, gin.H{"error": "Accept must allow application/json, text/plain or text/markdown", "code": "not_acceptable"})
+		return
+	}
+	req, priority, ok := s.prepareTranslation(c)
+	if !ok {
+		return
+	}
+	// chunked translations exist to survive interruptions, which a single response cannot
+	if req.Chunked {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chunked is not supported for synchronous translations", "code": "chunked_not_supported"})
+		return
+	}
+	client := c.ClientIP()
+	id := newJobID()
+
+	result := newSyncResult(s.sections)
+	history := newHistoryRecorder(s.sections)
+	observe := func(chunk string) error {
+		result.observe(chunk)
+		history.observe(chunk)
+		return nil
+	}
+
+	// conversions between config formats need no model, so they are answered without a worker
+	if response, ok := s.services.CodeTranslatorService.ConvertConfig(req); ok {
+		if err := s.services.CodeTranslatorService.SendResponse(req, response, observe); err != nil {
+			s.respondSyncFailure(c, id, client, req, err)
+			return
+		}
+		s.recordTranslation(id, client, s.sessionID(c), req, history)
+		result.write(c, format, id, req)
+		return
+	}
+
+	if !s.checkPromptSize(c, req) {
+		return
+	}
+
+	// unlike streamed jobs the client is waiting, so the timeout includes time spent queued
+	ctx, cancel := context.WithTimeout(c.Request.Context(), translationTimeout)
+	defer cancel()
+	accepted := time.Now()
+	provider := s.providerLabel(req)
+	done := make(chan error, 1)
+	err := s.services.WorkerPool.Submit(client, priority, func() {
+		if ctx.Err() != nil {
+			done <- ctx.Err()
+			return
+		}
+		done <- s.services.CodeTranslatorService.TranslateCode(ctx, req, observe)
+	})
+	if err != nil {
+		s.logger.Warn("rejecting translation job", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": submitErrorCode(err)})
+		return
+	}
+	s.services.Metrics.TranslationRequested(provider, languageLabel(req.TargetLanguage))
+	s.logger.Info("synchronous translation started", zap.String("id", id))
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// still queued, or a provider ignoring cancellation; the job gives up on its own
+		err = ctx.Err()
+	}
+	if !errors.Is(err, context.Canceled) || c.Request.Context().Err() == nil {
+		s.services.Metrics.TranslationFinished(provider, time.Since(accepted), err)
+	}
+	if err != nil {
+		s.respondSyncFailure(c, id, client, req, err)
+		return
+	}
+
+	s.failures.recordSuccess(s.callerKey(c))
+	s.recordTranslation(id, client, s.sessionID(c), req, history)
+	s.logger.Info("translation completed", zap.String("id", id), metadataField(req.Metadata))
+	result.write(c, format, id, req)
+}
+
+// respondSyncFailure records a failed synchronous translation and answers 504 if it timed out
+// and 502 otherwise. Nothing is sent to clients that went away.
+func (s *GinServer) respondSyncFailure(c *gin.Context, id, client string, req types.TranslateRequest, err error) {
+	if errors.Is(err, context.Canceled) && c.Request.Context().Err() != nil {
+		s.logger.Info("translation cancelled, client disconnected", zap.String("id", id), metadataField(req.Metadata))
+		return
+	}
+	s.logger.Error("translation error", zap.String("id", id), zap.Error(err), metadataField(req.Metadata))
+	s.failures.recordFailure(s.callerKey(c), err)
+	s.recordDeadLetter(id, client, req, err)
+
+	code := code_translator.ErrorCode(err)
+	status := http.StatusBadGateway
+	if code == code_translator.ErrCodeTimeout {
+		status = http.StatusGatewayTimeout
+	}
+	c.JSON(status, gin.H{"error": err.Error(), "id": id, "code": code})
+}
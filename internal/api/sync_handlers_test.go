@@ -0,0 +1,74 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/services"
+	"code-bridge/internal/worker_pool"
+	"code-bridge/pkg/types"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTranslateSyncNegotiatesFormat(t *testing.T) {
+	provider := &recordingProvider{response: "=== EXPLANATION ===\nPrints one.\n=== TRANSLATION NOTES ===\n- none\n=== TRANSLATED CODE ===\nfmt.Println(1)"}
+	pool := worker_pool.NewPool(zap.NewNop(), 1, 10, time.Second)
+	pool.Start()
+	t.Cleanup(func() { _ = pool.Stop(context.Background()) })
+	server := newTestServerWithDeps(t, services.Deps{
+		CodeTranslatorService: code_translator.NewCodeTranslatorService(zap.NewNop(), provider, nil, nil, types.TranslatorConfig{}),
+		WorkerPool:            pool,
+		History:               newMemoryHistory(),
+	}, func(cfg *types.Config) {
+		cfg.Abuse.RateLimitRPM = 0
+	})
+	body := `{"code":"print(1)","source_language":"python","target_language":"Go"}`
+
+	tests := []struct {
+		name        string
+		accept      string
+		status      int
+		contentType string
+		want        []string
+	}{
+		{"default", "", http.StatusOK, "application/json", []string{`"code":"fmt.Println(1)"`, `"explanation":"Prints one."`}},
+		{"any", "*/*", http.StatusOK, "application/json", []string{`"code":"fmt.Println(1)"`}},
+		{"json", "application/json", http.StatusOK, "application/json", []string{`"notes":"- none"`}},
+		{"plain", "text/plain", http.StatusOK, "text/plain", []string{"fmt.Println(1)"}},
+		{"markdown", "text/markdown;q=0.9, text/html", http.StatusOK, "text/markdown", []string{
+			"## Explanation\n\nPrints one.\n\n## Notes\n\n- none\n\n## Code\n\n```go\nfmt.Println(1)\n```\n",
+		}},
+		{"unsupported", "image/png", http.StatusNotAcceptable, "application/json", []string{`"code":"not_acceptable"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Content-Type": {"application/json"}}
+			if tt.accept != "" {
+				header.Set("Accept", tt.accept)
+			}
+			w := serve(server, http.MethodPost, syncRoute, body, header)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %s", contentType, tt.contentType)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body %q does not contain %q", w.Body, want)
+				}
+			}
+			if tt.accept == "text/plain" && w.Body.String() != "fmt.Println(1)" {
+				t.Errorf("plain body = %q, want only the code", w.Body)
+			}
+		})
+	}
+
+	if len(provider.prompts) != 5 {
+		t.Errorf("provider ran %d translations, want 5: none for the unsupported Accept", len(provider.prompts))
+	}
+}
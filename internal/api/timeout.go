@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequestTimeout returns a gin middleware that cancels the request context after
+// timeout and answers 503 if the handler hasn't started responding by then.
+// Routes in exempt (gin route patterns, e.g. long-lived SSE streams) are not limited.
+func RequestTimeout(logger *zap.Logger, timeout time.Duration, exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, route := range exempt {
+		skip[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if timeout <= 0 || skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &timeoutWriter{ResponseWriter: c.Writer, header: make(http.Header)}
+		c.Writer = writer
+
+		// The handler runs in its own goroutine only so the 503 can go out on time.
+		// Its panics are handed back to this goroutine for the recovery middleware.
+		done := make(chan struct{})
+		var panicked any
+		go func() {
+			defer close(done)
+			defer func() { panicked = recover() }()
+			c.Next()
+		}()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			// the 503 is flushed before waiting, so the client has its answer while a handler
+			// that ignores the cancellation is still running
+			if writer.timeout() {
+				logger.Warn("request timed out",
+					zap.String("method", c.Request.Method),
+					zap.String("path", c.Request.URL.Path),
+					zap.Duration("timeout", timeout),
+				)
+			}
+			// wait for the handler so the gin context isn't reused while it still runs
+			<-done
+		}
+
+		c.Writer = writer.ResponseWriter
+		if panicked != nil {
+			panic(panicked)
+		}
+	}
+}
+
+// timeoutWriter buffers response headers and drops all writes once the request
+// has timed out, so a slow handler cannot interleave with the 503 response
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+// flushHeader copies the handler's headers to the underlying writer; w.mu must be held
+func (w *timeoutWriter) flushHeader() {
+	dst := w.ResponseWriter.Header()
+	for key, values := range w.header {
+		dst[key] = values
+	}
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.flushHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return len(data), nil
+	}
+	w.flushHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// timeout stops the handler's output and sends a 503 unless the handler has
+// already started its response. The 503 is complete and flushed to the client
+// when timeout returns. It reports whether the 503 was sent.
+func (w *timeoutWriter) timeout() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	if w.ResponseWriter.Written() {
+		return false
+	}
+
+	body, _ := json.Marshal(gin.H{"error": "request timed out", "code": "request_timeout"})
+	w.ResponseWriter.Header().Set("Content-Type", "application/json; charset=utf-8")
+	// with a length the client knows the response is complete without waiting for the handler to return
+	w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = w.ResponseWriter.Write(body)
+	w.ResponseWriter.Flush()
+	return true
+}
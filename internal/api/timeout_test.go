@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestRequestTimeoutRespondsBeforeHandlerReturns(t *testing.T) {
+	release := make(chan struct{})
+	returned := make(chan struct{})
+	router := gin.New()
+	router.Use(RequestTimeout(zap.NewNop(), 50*time.Millisecond))
+	// the handler ignores the cancelled context and only returns once the test has its answer
+	router.GET("/slow", func(c *gin.Context) {
+		defer close(returned)
+		<-release
+		c.JSON(http.StatusOK, gin.H{"status": "late"})
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	var releaseOnce sync.Once
+	releaseHandler := func() { releaseOnce.Do(func() { close(release) }) }
+	t.Cleanup(releaseHandler)
+
+	client := server.Client()
+	client.Timeout = 5 * time.Second
+	resp, err := client.Get(server.URL + "/slow")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	select {
+	case <-returned:
+		t.Fatal("handler returned before the client had the 503")
+	default:
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+	var decoded struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil || decoded.Code != "request_timeout" {
+		t.Errorf("body = %s, want code request_timeout", body)
+	}
+
+	// the late response of the handler is dropped
+	releaseHandler()
+	<-returned
+}
+
+func TestRequestTimeoutLeavesFastHandlersAlone(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestTimeout(zap.NewNop(), time.Second))
+	router.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Handler", "fast")
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusOK || w.Header().Get("X-Handler") != "fast" {
+		t.Errorf("status = %d, X-Handler = %q, want 200 and the handler's header", w.Code, w.Header().Get("X-Handler"))
+	}
+}
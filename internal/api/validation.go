@@ -0,0 +1,135 @@
+package api
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/translator_provider"
+	"code-bridge/internal/worker_pool"
+	"code-bridge/pkg/types"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers that let clients which cannot change the JSON body override provider settings
+const (
+	headerModel       = "X-Translate-Model"
+	headerTemperature = "X-Translate-Temperature"
+	headerProvider    = "X-Translate-Provider"
+)
+
+// maxOutputTokens bounds max_output_tokens; providers with a lower limit reject the request themselves
+const maxOutputTokens = 65536
+
+// requestBodyOverhead is the room a translation request body gets beyond its code, for the other fields
+const requestBodyOverhead = 64 * 1024
+
+// requestBodyLimit is the largest translation request body read for code of up to maxCodeBytes.
+// JSON escaping can make code longer than it is, so the code is allowed twice its size.
+func requestBodyLimit(maxCodeBytes int) int {
+	return 2*maxCodeBytes + requestBodyOverhead
+}
+
+var modelNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]{0,127}$`)
+
+// jobIDPattern matches the ids handed out by POST /translate ("job-" followed by a unix-nano timestamp)
+var jobIDPattern = regexp.MustCompile(`^job-[0-9]{1,20}$`)
+
+// newJobID returns a fresh job id in the format accepted by jobIDPattern
+func newJobID() string {
+	return fmt.Sprintf("job-%d", time.Now().UnixNano())
+}
+
+// applyHeaderOverrides copies provider settings from request headers into req.
+// Values already present in the body take precedence over headers.
+func applyHeaderOverrides(c *gin.Context, req *types.TranslateRequest) error {
+	if req.Provider == "" {
+		req.Provider = c.GetHeader(headerProvider)
+	}
+	if req.Model == "" {
+		req.Model = c.GetHeader(headerModel)
+	}
+	if req.Temperature == nil {
+		if raw := c.GetHeader(headerTemperature); raw != "" {
+			temperature, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s header: %q is not a number", headerTemperature, raw)
+			}
+			req.Temperature = &temperature
+		}
+	}
+	return nil
+}
+
+// validateTranslateRequest checks the optional fields of a translation request,
+// whether they came from the body or from override headers
+func validateTranslateRequest(req *types.TranslateRequest) error {
+	if req.Provider != "" {
+		if _, err := translator_provider.ParseProviderType(req.Provider); err != nil {
+			return err
+		}
+	}
+	if req.Model != "" && !modelNamePattern.MatchString(req.Model) {
+		return fmt.Errorf("invalid model %q", req.Model)
+	}
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return errors.New("temperature must be between 0.0 and 2.0")
+	}
+	// gemini only accepts 32-bit seeds, so keep the range portable across providers
+	if req.Seed != nil && (*req.Seed < math.MinInt32 || *req.Seed > math.MaxInt32) {
+		return errors.New("seed must fit in a 32-bit integer")
+	}
+	if req.MaxOutputTokens != nil && (*req.MaxOutputTokens < 1 || *req.MaxOutputTokens > maxOutputTokens) {
+		return fmt.Errorf("max_output_tokens must be between 1 and %d", maxOutputTokens)
+	}
+	if err := code_translator.ValidateStopSequences(req.StopSequences); err != nil {
+		return err
+	}
+	if err := code_translator.ValidateGuidance(req.Framework, req.Instructions); err != nil {
+		return err
+	}
+	if err := code_translator.ValidateTermMap(req.TermMap); err != nil {
+		return err
+	}
+	if strings.EqualFold(req.SourceLanguage, code_translator.LanguagePseudocode) && !code_translator.IsKnownLanguage(req.TargetLanguage) {
+		return fmt.Errorf("target_language %q is not a supported programming language for pseudocode generation", req.TargetLanguage)
+	}
+	if err := code_translator.ValidateLineRange(req.Code, req.StartLine, req.EndLine); err != nil {
+		return err
+	}
+	if (req.StartLine != 0 || req.EndLine != 0) && code_translator.IsNotebook(req.Code) {
+		return errors.New("start_line and end_line are not supported for notebooks")
+	}
+	if req.IncludeDependencies && code_translator.IsNotebook(req.Code) {
+		return errors.New("include_dependencies is not supported for notebooks")
+	}
+	if req.Chunked {
+		switch {
+		case req.StartLine != 0 || req.EndLine != 0:
+			return errors.New("start_line and end_line are not supported for chunked translations")
+		case req.UseMemory:
+			return errors.New("use_memory is not supported for chunked translations")
+		case req.IncludeDependencies:
+			return errors.New("include_dependencies is not supported for chunked translations")
+		case code_translator.IsNotebook(req.Code):
+			return errors.New("chunked is not supported for notebooks, which are translated cell by cell")
+		}
+	}
+	if _, err := worker_pool.ParsePriority(req.Priority); err != nil {
+		return err
+	}
+	if req.Output != "" && req.Output != code_translator.OutputPatch {
+		return fmt.Errorf("unsupported output %q", req.Output)
+	}
+	if req.FormatStrictness != "" {
+		if _, err := code_translator.ParseFormatStrictness(req.FormatStrictness); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package chunked_job
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// chunkedJob is one row of the chunked_jobs table
+type chunkedJob struct {
+	bun.BaseModel `bun:"table:chunked_jobs"`
+
+	ID        string                 `bun:"id,pk"`
+	Client    string                 `bun:"client,notnull"`
+	Owner     string                 `bun:"owner,notnull"`
+	Request   types.TranslateRequest `bun:"request,type:jsonb,notnull"`
+	Parts     []types.PartRange      `bun:"parts,type:jsonb,notnull"`
+	CreatedAt time.Time              `bun:"created_at,notnull,default:current_timestamp"`
+}
+
+// chunkedJobPart is one row of the chunked_job_parts table, a completed part of a job
+type chunkedJobPart struct {
+	bun.BaseModel `bun:"table:chunked_job_parts"`
+
+	JobID       string    `bun:"job_id,pk"`
+	Part        int       `bun:"part,pk"`
+	Translated  string    `bun:"translated,notnull"`
+	CompletedAt time.Time `bun:"completed_at,notnull,default:current_timestamp"`
+}
+
+// PostgresStore keeps chunked jobs in the chunked_jobs and chunked_job_parts tables
+type PostgresStore struct {
+	db *bun.DB
+}
+
+// NewPostgresStore creates the chunked job tables if needed
+func NewPostgresStore(ctx context.Context, db *bun.DB) (*PostgresStore, error) {
+	if _, err := db.NewCreateTable().Model((*chunkedJob)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create chunked_jobs table: %w", err)
+	}
+	if _, err := db.NewCreateTable().Model((*chunkedJobPart)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create chunked_job_parts table: %w", err)
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Create inserts a new job
+func (s *PostgresStore) Create(ctx context.Context, job types.ChunkedJob) error {
+	row := chunkedJob{
+		ID:        job.ID,
+		Client:    job.Client,
+		Owner:     job.Owner,
+		Request:   job.Request,
+		Parts:     job.Parts,
+		CreatedAt: job.CreatedAt,
+	}
+	if _, err := s.db.NewInsert().Model(&row).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to create chunked job: %w", err)
+	}
+	return nil
+}
+
+// SavePart stores a completed part, replacing an earlier translation of the same part
+func (s *PostgresStore) SavePart(ctx context.Context, jobID string, part int, translated string) error {
+	row := chunkedJobPart{JobID: jobID, Part: part, Translated: translated, CompletedAt: time.Now()}
+	_, err := s.db.NewInsert().
+		Model(&row).
+		On("CONFLICT (job_id, part) DO UPDATE").
+		Set("translated = EXCLUDED.translated").
+		Set("completed_at = EXCLUDED.completed_at").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to save chunked job part: %w", err)
+	}
+	return nil
+}
+
+// Load returns the job with the translations of its leading run of completed parts
+func (s *PostgresStore) Load(ctx context.Context, jobID string) (*types.ChunkedJob, error) {
+	var row chunkedJob
+	if err := s.db.NewSelect().Model(&row).Where("id = ?", jobID).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to load chunked job: %w", err)
+	}
+
+	var parts []chunkedJobPart
+	if err := s.db.NewSelect().Model(&parts).Where("job_id = ?", jobID).Order("part ASC").Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load chunked job parts: %w", err)
+	}
+
+	job := &types.ChunkedJob{
+		ID:        row.ID,
+		Client:    row.Client,
+		Owner:     row.Owner,
+		Request:   row.Request,
+		Parts:     row.Parts,
+		CreatedAt: row.CreatedAt,
+	}
+	// parts are translated in order, so a gap can only come from a lost write; resume from it
+	for i, part := range parts {
+		if part.Part != i {
+			break
+		}
+		job.Translated = append(job.Translated, part.Translated)
+		if part.CompletedAt.After(job.CompletedAt) {
+			job.CompletedAt = part.CompletedAt
+		}
+	}
+	return job, nil
+}
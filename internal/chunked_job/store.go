@@ -0,0 +1,20 @@
+package chunked_job
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+)
+
+// ErrJobNotFound is returned by Load for ids without a chunked job
+var ErrJobNotFound = errors.New("chunked job not found")
+
+// Store persists chunked jobs and the translations of their completed parts
+type Store interface {
+	// Create records a new job before any of its parts are translated
+	Create(ctx context.Context, job types.ChunkedJob) error
+	// SavePart records the translation of a job's part (0-based)
+	SavePart(ctx context.Context, jobID string, part int, translated string) error
+	// Load returns a job with the translations of its completed parts
+	Load(ctx context.Context, jobID string) (*types.ChunkedJob, error)
+}
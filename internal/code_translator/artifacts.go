@@ -0,0 +1,50 @@
+package code_translator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// promptArtifactPatterns match lines of the prompt that models sometimes echo into their
+// output. Update them together with the wording in buildPrompt and its helpers. Each
+// pattern matches a whole line (or its start), so code that merely mentions these words
+// is left alone.
+var promptArtifactPatterns = []*regexp.Regexp{
+	// section markers leaking into another section, e.g. "=== TRANSLATED CODE ===" inside the code
+	regexp.MustCompile(`^\s*(?:\d\.\s*)?=== [A-Z][A-Z ]* ===\s*$`),
+	regexp.MustCompile(`^\s*SOURCE CODE TO TRANSLATE:\s*$`),
+	regexp.MustCompile(`^\s*Your response MUST follow this EXACT structure:\s*$`),
+	regexp.MustCompile(`^\s*You are a (?:code translator|software engineer turning pseudocode into working code)\. You MUST respond in the EXACT format shown below\.\s*$`),
+	regexp.MustCompile(`^\s*(?:CRITICAL|IMPORTANT): (?:You must include ALL (?:THREE|FOUR) sections|Your response must contain ONLY (?:this section|these sections)|You MUST include all (?:three|four) sections|Respond with only the (?:DEPENDENCIES and )?TRANSLATED CODE section)`),
+	// placeholders from the response structure
+	regexp.MustCompile(`^\s*(?:- )?\[(?:The complete translated code goes here|Write 2-3 sentences explaining what the original code does|pseudocode describes|Key difference \d between source and target language|Implementation decision \d[^\]]*|Every third-party package [^\]]*)\]\s*$`),
+	regexp.MustCompile(`^\s*REQUIRED TERM MAPPINGS \(hard constraints\):`),
+	regexp.MustCompile(`^\s*Follow these additional instructions as long as they do not conflict with the required response format:\s*$`),
+	regexp.MustCompile(`^\s*The code to translate is lines \d+-\d+ of a larger file\.`),
+	regexp.MustCompile(`^\s*Lines (?:before|after):\s*$`),
+}
+
+// isPromptArtifact reports whether line is an echoed piece of the prompt
+func isPromptArtifact(line string) bool {
+	for _, pattern := range promptArtifactPatterns {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripPromptArtifacts removes the lines of content that echo the prompt
+func stripPromptArtifacts(content string) string {
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !isPromptArtifact(line) {
+			kept = append(kept, line)
+		}
+	}
+	if len(kept) == len(lines) {
+		return content
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
@@ -0,0 +1,50 @@
+package code_translator
+
+import "testing"
+
+func TestPromptArtifactPatterns(t *testing.T) {
+	// one case per entry of promptArtifactPatterns, in order: an echoed line it must
+	// strip and a line of real output mentioning the same words that it must keep
+	tests := []struct {
+		name     string
+		artifact string
+		kept     string
+	}{
+		{"section marker", "2. === TRANSLATED CODE ===", `fmt.Println("=== TRANSLATED CODE ===")`},
+		{"source code label", "SOURCE CODE TO TRANSLATE:", "// the SOURCE CODE TO TRANSLATE: see below"},
+		{"structure instruction", "Your response MUST follow this EXACT structure:", "// Your response MUST follow this EXACT structure: JSON"},
+		{"role line", "You are a code translator. You MUST respond in the EXACT format shown below.", "// You are a code translator."},
+		{"critical instruction", "CRITICAL: You must include ALL FOUR sections in order.", "// CRITICAL: release the lock first"},
+		{"placeholder", "- [Key difference 1 between source and target language]", "xs := []int{1, 2}"},
+		{"term mappings", "REQUIRED TERM MAPPINGS (hard constraints):", "// term mappings are applied before the lookup"},
+		{"additional instructions", "Follow these additional instructions as long as they do not conflict with the required response format:", "// Follow these additional instructions"},
+		{"line range", "The code to translate is lines 10-20 of a larger file.", "// lines 10-20 of a larger file"},
+		{"context label", "Lines before:", `label := "Lines before:"`},
+	}
+	if len(tests) != len(promptArtifactPatterns) {
+		t.Fatalf("%d cases for %d patterns; add a case for each new pattern", len(tests), len(promptArtifactPatterns))
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !promptArtifactPatterns[i].MatchString(tt.artifact) {
+				t.Errorf("pattern %d does not match %q", i, tt.artifact)
+			}
+			if isPromptArtifact(tt.kept) {
+				t.Errorf("%q was taken for an artifact", tt.kept)
+			}
+
+			content := "line one\n" + tt.artifact + "\n" + tt.kept
+			if got, want := stripPromptArtifacts(content), "line one\n"+tt.kept; got != want {
+				t.Errorf("stripPromptArtifacts = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestStripPromptArtifactsKeepsCleanContent(t *testing.T) {
+	content := "  func add(a, b int) int {\n\treturn a + b\n}\n"
+	if got := stripPromptArtifacts(content); got != content {
+		t.Errorf("stripPromptArtifacts changed clean content to %q", got)
+	}
+}
@@ -0,0 +1,124 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheKey returns a stable hash of everything that affects a translation's output.
+// model identifies the provider and model that will answer, e.g. "openai:gpt-4o".
+// Changing any input, or PromptTemplateVersion, yields a different key. The format strictness
+// is part of it since a lenient response may lack sections a strict request requires;
+// req.FormatStrictness must already be resolved against the configured default.
+func CacheKey(req types.TranslateRequest, model string) string {
+	// maps marshal with sorted keys, so TermMap doesn't make the key depend on map order
+	normalized, _ := json.Marshal(struct {
+		TemplateVersion  string            `json:"template_version"`
+		Model            string            `json:"model"`
+		Code             string            `json:"code"`
+		SourceLanguage   string            `json:"source_language"`
+		TargetLanguage   string            `json:"target_language"`
+		StartLine        int               `json:"start_line"`
+		EndLine          int               `json:"end_line"`
+		Framework        string            `json:"framework"`
+		Instructions     string            `json:"instructions"`
+		TermMap          map[string]string `json:"term_map"`
+		CodeOnly         bool              `json:"code_only"`
+		Dependencies     bool              `json:"include_dependencies"`
+		Temperature      *float64          `json:"temperature"`
+		Seed             *int64            `json:"seed"`
+		StopSequences    []string          `json:"stop_sequences"`
+		MaxOutputTokens  *int64            `json:"max_output_tokens"`
+		FormatStrictness string            `json:"format_strictness"`
+	}{
+		TemplateVersion:  PromptTemplateVersion,
+		Model:            strings.ToLower(strings.TrimSpace(model)),
+		Code:             strings.TrimSpace(strings.ReplaceAll(req.Code, "\r\n", "\n")),
+		SourceLanguage:   normalizeLanguage(req.SourceLanguage),
+		TargetLanguage:   normalizeLanguage(req.TargetLanguage),
+		StartLine:        req.StartLine,
+		EndLine:          req.EndLine,
+		Framework:        strings.ToLower(strings.TrimSpace(req.Framework)),
+		Instructions:     strings.TrimSpace(req.Instructions),
+		TermMap:          req.TermMap,
+		CodeOnly:         req.CodeOnly,
+		Dependencies:     req.IncludeDependencies,
+		Temperature:      req.Temperature,
+		Seed:             req.Seed,
+		StopSequences:    req.StopSequences,
+		MaxOutputTokens:  req.MaxOutputTokens,
+		FormatStrictness: req.FormatStrictness,
+	})
+	sum := sha256.Sum256(normalized)
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedTranslation is a complete provider response kept for identical requests
+type cachedTranslation struct {
+	key      string
+	response string
+	metadata *types.CompletionMetadata
+	expires  time.Time
+}
+
+// translationCache is a size-bounded LRU of provider responses with a fixed TTL
+type translationCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+}
+
+func newTranslationCache(size int, ttl time.Duration) *translationCache {
+	return &translationCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached response for key unless it is missing or expired
+func (c *translationCache) get(key string) (cachedTranslation, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return cachedTranslation{}, false
+	}
+	entry := elem.Value.(*cachedTranslation)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return cachedTranslation{}, false
+	}
+	c.order.MoveToFront(elem)
+	return *entry, true
+}
+
+// put stores a response, evicting the least recently used entry when full
+func (c *translationCache) put(key, response string, metadata *types.CompletionMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cachedTranslation{key: key, response: response, metadata: metadata, expires: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedTranslation).key)
+	}
+}
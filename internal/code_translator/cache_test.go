@@ -0,0 +1,67 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// scriptedProvider answers every completion with response and counts the calls
+type scriptedProvider struct {
+	response string
+	calls    int
+}
+
+func (p *scriptedProvider) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	p.calls++
+	return onChunk(p.response)
+}
+
+func TestCacheKeyCoversFormatStrictness(t *testing.T) {
+	req := types.TranslateRequest{Code: "print(1)", SourceLanguage: "python", TargetLanguage: "go"}
+	lenient, strict := req, req
+	lenient.FormatStrictness = FormatLenient
+	strict.FormatStrictness = FormatStrict
+
+	if CacheKey(lenient, "openai:gpt") == CacheKey(strict, "openai:gpt") {
+		t.Error("lenient and strict requests share a cache key")
+	}
+}
+
+func TestCachedLenientResponseDoesNotAnswerStrictRequest(t *testing.T) {
+	// the notes section is missing, which only a strict request rejects
+	provider := &scriptedProvider{response: "=== EXPLANATION ===\nPrints one.\n=== TRANSLATED CODE ===\nfmt.Println(1)"}
+	service := NewCodeTranslatorService(zap.NewNop(), provider, nil, nil, types.TranslatorConfig{
+		CacheEnabled:     true,
+		CacheSize:        10,
+		CacheTTL:         time.Minute,
+		FormatStrictness: FormatLenient,
+	})
+	req := types.TranslateRequest{Code: "print(1)", SourceLanguage: "python", TargetLanguage: "go"}
+	discard := func(string) error { return nil }
+
+	if err := service.TranslateCode(context.Background(), req, discard); err != nil {
+		t.Fatalf("lenient TranslateCode: %v", err)
+	}
+	if err := service.TranslateCode(context.Background(), req, discard); err != nil {
+		t.Fatalf("repeated lenient TranslateCode: %v", err)
+	}
+	if provider.calls != 1 {
+		t.Fatalf("provider called %d times for a repeated lenient request, want 1", provider.calls)
+	}
+
+	req.FormatStrictness = FormatStrict
+	err := service.TranslateCode(context.Background(), req, discard)
+	var formatErr *FormatError
+	if !errors.As(err, &formatErr) {
+		t.Fatalf("strict TranslateCode error = %v, want a FormatError", err)
+	}
+	// the strict request asked the provider, and asked again, instead of using the cache
+	if provider.calls != 3 {
+		t.Errorf("provider called %d times, want 3", provider.calls)
+	}
+}
@@ -0,0 +1,156 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ChunkPartTimeout bounds the provider call for one part of a chunked translation;
+// the job as a whole may take as long as its parts need
+const ChunkPartTimeout = 2 * time.Minute
+
+// PartProgress reports which part of a chunked translation a ChunkTypePart chunk belongs to
+type PartProgress struct {
+	// Part is the 0-based index of the part
+	Part int `json:"part"`
+	// Done and Total count translated parts
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// SplitParts splits code into line ranges of about the configured part size for a
+// chunked translation. Parts are cut before top-level definitions where possible,
+// and only cut elsewhere once a part has grown to twice the size.
+func (s *CodeTranslatorService) SplitParts(code string) []types.PartRange {
+	return splitParts(code, s.chunkPartBytes)
+}
+
+func splitParts(code string, maxBytes int) []types.PartRange {
+	lines := strings.Split(code, "\n")
+	var parts []types.PartRange
+	start, size := 1, 0
+	for i, line := range lines {
+		n := i + 1
+		if size > 0 {
+			boundary := segmentStartPattern.MatchString(line) && size+len(line) > maxBytes
+			if boundary || size >= 2*maxBytes {
+				parts = append(parts, types.PartRange{StartLine: start, EndLine: n - 1})
+				start, size = n, 0
+			}
+		}
+		size += len(line) + 1
+	}
+	return append(parts, types.PartRange{StartLine: start, EndLine: len(lines)})
+}
+
+// partPrompt builds the prompt for one part; the lines around it are shown as context
+func (s *CodeTranslatorService) partPrompt(req types.TranslateRequest, part types.PartRange) string {
+	code, excerpt := selectLines(req.Code, part.StartLine, part.EndLine)
+	return s.preparePrompt(promptInput{
+		code:         code,
+		source:       req.SourceLanguage,
+		target:       req.TargetLanguage,
+		framework:    req.Framework,
+		instructions: req.Instructions,
+		termMap:      req.TermMap,
+		// explanations of individual parts would be noise, so only the code is asked for
+		codeOnly: true,
+		excerpt:  excerpt,
+	})
+}
+
+// checkPartPrompts returns a ContextTooLargeError if any part's prompt exceeds the token limit
+func (s *CodeTranslatorService) checkPartPrompts(req types.TranslateRequest) error {
+	for i, part := range s.SplitParts(req.Code) {
+		if err := s.checkPrompt(s.partPrompt(req, part)); err != nil {
+			return fmt.Errorf("part %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// TranslateChunked translates req.Code one part at a time. The first len(translated)
+// parts were completed by an earlier run and are only replayed as part chunks, so a
+// finished job's result can be replayed without calling the provider. onPart
+// is called with each newly translated part before its chunk is sent, so it can be
+// persisted; an error from it stops the translation. Once all parts are done the
+// joined translation is sent as the final code chunk, followed by stats.
+func (s *CodeTranslatorService) TranslateChunked(ctx context.Context, req types.TranslateRequest, parts []types.PartRange, translated []string, onPart func(part int, code string) error, onChunk func(string) error) error {
+	sendPart := func(i int, code string) error {
+		jsonData, _ := json.Marshal(StreamChunk{
+			Type:    ChunkTypePart,
+			Content: code,
+			Range:   &LineRange{StartLine: parts[i].StartLine, EndLine: parts[i].EndLine},
+			Part:    &PartProgress{Part: i, Done: i + 1, Total: len(parts)},
+		})
+		return onChunk(string(jsonData))
+	}
+
+	translated = append([]string(nil), translated...)
+	for i, code := range translated {
+		if err := sendPart(i, code); err != nil {
+			return err
+		}
+	}
+
+	if len(translated) < len(parts) {
+		if err := s.translateParts(ctx, req, parts, &translated, onPart, sendPart, onChunk); err != nil {
+			return err
+		}
+	}
+
+	// the parts went through finalCode already, so this only carries them through the usual final chunks
+	return s.sendFinalSections(req, sectionHeading(sectionCode)+"\n"+strings.Join(translated, "\n\n"), onChunk)
+}
+
+// translateParts translates the parts after those in translated, appending each as it completes
+func (s *CodeTranslatorService) translateParts(ctx context.Context, req types.TranslateRequest, parts []types.PartRange, translated *[]string, onPart func(part int, code string) error, sendPart func(part int, code string) error, onChunk func(string) error) error {
+	provider, opts, err := s.completionSetup(req, onChunk)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("translating in parts",
+		zap.String("source_language", req.SourceLanguage),
+		zap.String("target_language", req.TargetLanguage),
+		zap.Int("parts", len(parts)),
+		zap.Int("completed", len(*translated)),
+	)
+
+	for i := len(*translated); i < len(parts); i++ {
+		prompt := s.partPrompt(req, parts[i])
+		if err := s.checkPrompt(prompt); err != nil {
+			return fmt.Errorf("part %d: %w", i+1, err)
+		}
+
+		var response strings.Builder
+		partCtx, cancel := context.WithTimeout(ctx, ChunkPartTimeout)
+		err := provider.StreamCompletion(partCtx, prompt, opts, func(chunk string) error {
+			response.WriteString(chunk)
+			return nil
+		})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("part %d: %w", i+1, err)
+		}
+
+		code := s.finalCode(req, response.String())
+		if code == "" {
+			return fmt.Errorf("part %d: %w", i+1, ErrEmptyTranslation)
+		}
+		if err := onPart(i, code); err != nil {
+			return err
+		}
+		*translated = append(*translated, code)
+		if err := sendPart(i, code); err != nil {
+			return err
+		}
+	}
+	return nil
+}
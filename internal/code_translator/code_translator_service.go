@@ -1,74 +1,375 @@
 package code_translator
 
 import (
+	"code-bridge/pkg/types"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"strings"
+	"time"
 )
 
 // ChunkType represents the type of chunk being sent
 type ChunkType string
 
 const (
-	ChunkTypeExplanation ChunkType = "explanation"
-	ChunkTypeNotes       ChunkType = "notes"
-	ChunkTypeCode        ChunkType = "code"
-	ChunkTypeError       ChunkType = "error"
-	ChunkTypeRaw         ChunkType = "raw"
+	ChunkTypeExplanation  ChunkType = "explanation"
+	ChunkTypeNotes        ChunkType = "notes"
+	ChunkTypeDependencies ChunkType = "dependencies"
+	ChunkTypeCode         ChunkType = "code"
+	ChunkTypeError        ChunkType = "error"
+	ChunkTypeRaw          ChunkType = "raw"
+	ChunkTypeWarning      ChunkType = "warning"
+	ChunkTypeStats        ChunkType = "stats"
+	ChunkTypePatch        ChunkType = "patch"
+	ChunkTypeCell         ChunkType = "cell"
+	ChunkTypeNotebook     ChunkType = "notebook"
+	ChunkTypeMetadata     ChunkType = "metadata"
+	ChunkTypeStatus       ChunkType = "status"
+	ChunkTypePart         ChunkType = "part"
 )
 
+// chunkTypes lists every ChunkType the translator emits
+var chunkTypes = []ChunkType{
+	ChunkTypeExplanation, ChunkTypeNotes, ChunkTypeDependencies, ChunkTypeCode, ChunkTypeError, ChunkTypeRaw,
+	ChunkTypeWarning, ChunkTypeStats, ChunkTypePatch, ChunkTypeCell, ChunkTypeNotebook, ChunkTypeMetadata, ChunkTypeStatus, ChunkTypePart,
+}
+
+// statusMessages is the content of the status chunk sent for each provider lifecycle event
+var statusMessages = map[types.CompletionEvent]string{
+	types.EventResponseStarted:   "model started",
+	types.EventOutputStarted:     "generating",
+	types.EventResponseCompleted: "response complete",
+}
+
+// IsChunkType reports whether name is one of the chunk types the translator emits
+func IsChunkType(name string) bool {
+	for _, chunkType := range chunkTypes {
+		if string(chunkType) == name {
+			return true
+		}
+	}
+	return false
+}
+
 // StreamChunk represents a chunk of the translation stream
 type StreamChunk struct {
 	Type    ChunkType `json:"type"`
 	Content string    `json:"content"`
 	Delta   bool      `json:"delta,omitempty"` // true if this is a partial update
+	// Items holds the parsed bullet list of the final notes chunk, and the packages of
+	// the final dependencies chunk
+	Items []string `json:"items,omitempty"`
+	// Stats is only set on ChunkTypeStats chunks
+	Stats *TranslationStats `json:"stats,omitempty"`
+	// Progress is only set on ChunkTypeCell chunks
+	Progress *CellProgress `json:"progress,omitempty"`
+	// Range is set on the final code chunk when only a line range was translated,
+	// and on ChunkTypePart chunks to the part's lines
+	Range *LineRange `json:"range,omitempty"`
+	// Part is only set on ChunkTypePart chunks
+	Part *PartProgress `json:"part,omitempty"`
+	// Metadata is only set on ChunkTypeMetadata chunks
+	Metadata *types.CompletionMetadata `json:"metadata,omitempty"`
 }
 
 // TranslatorProviderInterface defines the methods required for translation providers
 type TranslatorProviderInterface interface {
-	StreamCompletion(ctx context.Context, prompt string, onChunk func(string) error) error
+	StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error
+}
+
+// ProviderResolver looks up a provider by name for requests that override the default provider
+type ProviderResolver func(name string) (TranslatorProviderInterface, error)
+
+// providerDescriber is implemented by providers that can report their name and default model
+type providerDescriber interface {
+	Name() string
+	Model() string
+}
+
+// seedSupporter is implemented by providers that honour CompletionOptions.Seed
+type seedSupporter interface {
+	SupportsSeed() bool
 }
 
 // CodeTranslatorService provides code translation functionalities
 type CodeTranslatorService struct {
-	logger   *zap.Logger
-	provider TranslatorProviderInterface
+	logger          *zap.Logger
+	provider        TranslatorProviderInterface
+	resolveProvider ProviderResolver
+	maxPromptTokens int
+	examples        []types.TranslationExample
+	maxExampleChars int
+	memory          TranslationMemory
+	// stopOnComplete cancels the provider once the code fence closes
+	stopOnComplete bool
+	// warnLanguageMismatch emits a warning when the code fence names another language than requested
+	warnLanguageMismatch bool
+	// delta chunks are held back until this many bytes changed or this much time passed
+	deltaFlushBytes    int
+	deltaFlushInterval time.Duration
+	// headings recognizes section headings, including configured synonyms
+	headings *sectionHeadings
+	// cache holds recent provider responses by CacheKey; nil when caching is disabled
+	cache *translationCache
+	// convertConfig converts between configuration formats without the provider
+	convertConfig bool
+	// chunkPartBytes is the approximate size of each part of a chunked translation
+	chunkPartBytes int
+	// formatStrictness is the default for requests that don't set format_strictness
+	formatStrictness string
 }
 
 // NewCodeTranslatorService creates a new instance of CodeTranslatorService
-func NewCodeTranslatorService(logger *zap.Logger, provider TranslatorProviderInterface) *CodeTranslatorService {
-	return &CodeTranslatorService{
-		logger:   logger,
-		provider: provider,
+// memory may be nil, in which case requests asking for translation memory translate without it
+func NewCodeTranslatorService(logger *zap.Logger, provider TranslatorProviderInterface, resolveProvider ProviderResolver, memory TranslationMemory, cfg types.TranslatorConfig) *CodeTranslatorService {
+	s := &CodeTranslatorService{
+		logger:          logger,
+		provider:        provider,
+		resolveProvider: resolveProvider,
+		maxPromptTokens: cfg.MaxPromptTokens,
+		examples:        cfg.Examples,
+		maxExampleChars: cfg.MaxExampleChars,
+		memory:          memory,
+
+		warnLanguageMismatch: cfg.WarnLanguageMismatch,
+		stopOnComplete:       cfg.StopOnComplete,
+
+		deltaFlushBytes:    cfg.DeltaFlushBytes,
+		deltaFlushInterval: cfg.DeltaFlushInterval,
+
+		headings:       newSectionHeadings(cfg.SectionHeadings, cfg.StripPromptArtifacts),
+		convertConfig:  cfg.ConfigConversion,
+		chunkPartBytes: cfg.ChunkPartBytes,
+
+		formatStrictness: cfg.FormatStrictness,
+	}
+	if cfg.CacheEnabled {
+		s.cache = newTranslationCache(cfg.CacheSize, cfg.CacheTTL)
+	}
+	return s
+}
+
+// ProviderInfo returns the name and default model of the default provider,
+// or empty strings if the provider does not describe itself
+func (s *CodeTranslatorService) ProviderInfo() (name, model string) {
+	if describer, ok := s.provider.(providerDescriber); ok {
+		return describer.Name(), describer.Model()
+	}
+	return "", ""
+}
+
+// CheckPromptSize returns a ContextTooLargeError if the prompt for the request
+// would exceed the configured token limit
+func (s *CodeTranslatorService) CheckPromptSize(req types.TranslateRequest) error {
+	if IsNotebook(req.Code) {
+		return NewNotebookTranslator(s).CheckPromptSize(req.Code, req.SourceLanguage, req.TargetLanguage)
+	}
+	if req.Chunked {
+		return s.checkPartPrompts(req)
+	}
+	code, excerpt := selectLines(req.Code, req.StartLine, req.EndLine)
+	return s.checkPrompt(s.preparePrompt(promptInput{
+		code:         code,
+		source:       req.SourceLanguage,
+		target:       req.TargetLanguage,
+		framework:    req.Framework,
+		instructions: req.Instructions,
+		termMap:      req.TermMap,
+		codeOnly:     req.CodeOnly,
+		dependencies: req.IncludeDependencies,
+		excerpt:      excerpt,
+	}))
+}
+
+// preparePrompt builds the prompt, injecting as many of in.memory's translation memory
+// hints and then matching few-shot examples as fit in the remaining context budget
+func (s *CodeTranslatorService) preparePrompt(in promptInput) string {
+	memory := in.memory
+	in.memory, in.examples = nil, nil
+	prompt := buildPrompt(in)
+	if len(s.examples) == 0 && len(memory) == 0 {
+		return prompt
+	}
+
+	budget := s.maxPromptTokens - estimateTokens(prompt)
+	in.memory = selectExamples(memory, in.source, in.target, 0, budget)
+	if len(in.memory) > 0 {
+		budget = s.maxPromptTokens - estimateTokens(buildPrompt(in))
+	}
+	in.examples = selectExamples(s.examples, in.source, in.target, s.maxExampleChars, budget)
+	if len(in.examples) == 0 && len(in.memory) == 0 {
+		return prompt
 	}
+	return buildPrompt(in)
+}
+
+func (s *CodeTranslatorService) checkPrompt(prompt string) error {
+	if s.maxPromptTokens <= 0 {
+		return nil
+	}
+	estimated := estimateTokens(prompt)
+	if estimated > s.maxPromptTokens {
+		return &ContextTooLargeError{Limit: s.maxPromptTokens, Actual: estimated}
+	}
+	return nil
+}
+
+// estimateTokens approximates the token count of a prompt (~4 bytes per token)
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
 }
 
 // TranslateCode sends prompt to OpenAI and streams chunks to the callback
-func (s *CodeTranslatorService) TranslateCode(ctx context.Context, code, sourceLang, targetLang string, onChunk func(string) error) error {
-	prompt := buildPrompt(code, sourceLang, targetLang)
+func (s *CodeTranslatorService) TranslateCode(ctx context.Context, req types.TranslateRequest, onChunk func(string) error) error {
+	if IsNotebook(req.Code) {
+		return NewNotebookTranslator(s).Translate(ctx, req, onChunk)
+	}
+
+	sourceLang, targetLang := req.SourceLanguage, req.TargetLanguage
+	// the cache key covers the whole file since the lines around a selection are part of the prompt
+	cacheReq := req
+	cacheReq.FormatStrictness = FormatLenient
+	if s.strictFormat(req.FormatStrictness) {
+		cacheReq.FormatStrictness = FormatStrict
+	}
+
+	// From here on only the selected lines are translated, diffed and remembered
+	var excerpt *excerpt
+	req.Code, excerpt = selectLines(req.Code, req.StartLine, req.EndLine)
+	if excerpt != nil {
+		req.StartLine, req.EndLine = excerpt.StartLine, excerpt.EndLine
+	}
+
+	var memory []types.TranslationExample
+	if req.UseMemory {
+		memory = s.recallMemory(ctx, req.MemoryScope, req.Code, sourceLang, targetLang)
+	}
+	prompt := s.preparePrompt(promptInput{
+		code:         req.Code,
+		source:       sourceLang,
+		target:       targetLang,
+		framework:    req.Framework,
+		instructions: req.Instructions,
+		termMap:      req.TermMap,
+		codeOnly:     req.CodeOnly,
+		dependencies: req.IncludeDependencies,
+		memory:       memory,
+		excerpt:      excerpt,
+	})
+
+	// Fail fast rather than paying for a provider round-trip that cannot succeed
+	if err := s.checkPrompt(prompt); err != nil {
+		return err
+	}
 
 	s.logger.Info("translating code",
 		zap.String("source_language", sourceLang),
 		zap.String("target_language", targetLang),
 	)
 
+	provider, opts, err := s.completionSetup(req, onChunk)
+	if err != nil {
+		return err
+	}
+
+	// translation memory makes the prompt depend on earlier translations, so those are not cached
+	var cacheKey string
+	if s.cache != nil && !req.UseMemory {
+		cacheKey = CacheKey(cacheReq, cacheModel(provider, opts.Model))
+		if cached, ok := s.cache.get(cacheKey); ok {
+			s.logger.Info("serving cached translation", zap.String("cache_key", cacheKey))
+			if err := s.sendFinalSections(req, cached.response, onChunk); err != nil {
+				return err
+			}
+			if req.IncludeMetadata && cached.metadata != nil {
+				return sendMetadata(onChunk, cached.metadata)
+			}
+			return nil
+		}
+	}
+
+	// metadata is kept for cached responses even when this request did not ask for it
+	var metadata *types.CompletionMetadata
+	if req.IncludeMetadata || cacheKey != "" {
+		opts.OnMetadata = func(m types.CompletionMetadata) { metadata = &m }
+	}
+
+	opts.OnEvent = func(event types.CompletionEvent) error {
+		message, ok := statusMessages[event]
+		if !ok {
+			return nil
+		}
+		return sendChunk(onChunk, ChunkTypeStatus, message, false)
+	}
+
+	response, err := s.streamResponse(ctx, req, provider, prompt, opts, onChunk)
+	if err != nil {
+		return err
+	}
+
+	if s.strictFormat(req.FormatStrictness) {
+		response, err = s.enforceFormat(ctx, req, provider, prompt, opts, response, onChunk)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Send final complete sections
+	if err := s.sendFinalSections(req, response, onChunk); err != nil {
+		return err
+	}
+
+	// sent before the empty-translation check since the finish reason often explains it
+	if req.IncludeMetadata && metadata != nil {
+		if err := sendMetadata(onChunk, metadata); err != nil {
+			return err
+		}
+	}
+
+	translated := s.finalCode(req, response)
+	if translated == "" {
+		return ErrEmptyTranslation
+	}
+
+	if req.UseMemory {
+		s.rememberTranslation(ctx, req.MemoryScope, req.Code, translated, sourceLang, targetLang)
+	}
+	if cacheKey != "" {
+		s.cache.put(cacheKey, response, metadata)
+	}
+	return nil
+}
+
+// streamResponse streams the provider's response to prompt, sending each section as it
+// completes and delta updates while it is written, and returns the whole response
+func (s *CodeTranslatorService) streamResponse(ctx context.Context, req types.TranslateRequest, provider TranslatorProviderInterface, prompt string, opts types.CompletionOptions, onChunk func(string) error) (string, error) {
+	// Cancelling this context stops the provider once the response is complete
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+
 	// Stream handler that processes chunks in real-time
 	var fullResponse strings.Builder
 	currentSection := ""
-	sectionBuffer := strings.Builder{}
+	flush := newDeltaFlusher(s.deltaFlushBytes, s.deltaFlushInterval)
 
-	err := s.provider.StreamCompletion(ctx, prompt, func(chunk string) error {
+	// time to first chunk shows what connection reuse and warming save
+	start := time.Now()
+	err := provider.StreamCompletion(streamCtx, prompt, opts, func(chunk string) error {
+		if fullResponse.Len() == 0 {
+			s.logger.Info("first chunk received", zap.Duration("time_to_first_chunk", time.Since(start)))
+		}
 		fullResponse.WriteString(chunk)
 		text := fullResponse.String()
 
 		// Detect section changes
-		newSection := detectCurrentSection(text)
+		newSection := s.headings.detectCurrentSection(text)
 
 		// If section changed, send the complete previous section
 		if newSection != currentSection && currentSection != "" {
-			content := extractSectionContent(fullResponse.String(), currentSection)
+			content := s.headings.extractSectionContent(fullResponse.String(), currentSection)
 			if content != "" {
 				streamChunk := StreamChunk{
 					Type:    ChunkType(currentSection),
@@ -80,15 +381,15 @@ func (s *CodeTranslatorService) TranslateCode(ctx context.Context, code, sourceL
 					return err
 				}
 			}
-			sectionBuffer.Reset()
+			flush.reset()
 		}
 
 		currentSection = newSection
 
-		// Send delta updates for current section
+		// Send delta updates for current section once enough has changed
 		if currentSection != "" {
-			content := extractSectionContent(text, currentSection)
-			if content != "" && content != sectionBuffer.String() {
+			content := s.headings.extractSectionContent(text, currentSection)
+			if content != "" && flush.due(content) {
 				streamChunk := StreamChunk{
 					Type:    ChunkType(currentSection),
 					Content: content,
@@ -98,114 +399,118 @@ func (s *CodeTranslatorService) TranslateCode(ctx context.Context, code, sourceL
 				if err := onChunk(string(jsonData)); err != nil {
 					return err
 				}
-				sectionBuffer.WriteString(content)
+				flush.sent(content)
 			}
 		}
 
+		if s.stopOnComplete && s.headings.responseComplete(text, req.CodeOnly) {
+			cancelStream()
+			return errResponseComplete
+		}
 		return nil
 	})
+	if errors.Is(err, errResponseComplete) {
+		s.logger.Debug("response complete, stopped provider stream early")
+		err = nil
+	}
+	return fullResponse.String(), err
+}
 
+// completionSetup resolves the request's provider and generation options, warning
+// the client about options the provider cannot honour
+func (s *CodeTranslatorService) completionSetup(req types.TranslateRequest, onChunk func(string) error) (TranslatorProviderInterface, types.CompletionOptions, error) {
+	provider, err := s.providerFor(req.Provider)
 	if err != nil {
-		return err
+		return nil, types.CompletionOptions{}, err
 	}
 
-	// Send final complete sections
-	return s.sendFinalSections(fullResponse.String(), onChunk)
+	opts := req.CompletionOptions()
+	if opts.Seed != nil && !supportsSeed(provider) {
+		s.logger.Warn("provider does not support seed, ignoring it")
+		opts.Seed = nil
+		if err := sendChunk(onChunk, ChunkTypeWarning, "seed is not supported by the configured provider and was ignored", false); err != nil {
+			return nil, types.CompletionOptions{}, err
+		}
+	}
+	return provider, opts, nil
 }
 
-func detectCurrentSection(text string) string {
-	// Check which section we're currently in based on the last header seen
-	lastExplanation := strings.LastIndex(strings.ToLower(text), "=== explanation ===")
-	lastNotes := strings.LastIndex(strings.ToLower(text), "=== translation notes ===")
-	lastCode := strings.LastIndex(strings.ToLower(text), "=== translated code ===")
-
-	// Find the most recent section header
-	if lastCode > lastNotes && lastCode > lastExplanation {
-		return "code"
-	} else if lastNotes > lastExplanation && lastNotes > lastCode {
-		return "notes"
-	} else if lastExplanation >= 0 {
-		return "explanation"
+// providerFor returns the provider requested by name, or the default provider when name is empty
+func (s *CodeTranslatorService) providerFor(name string) (TranslatorProviderInterface, error) {
+	if name == "" || s.resolveProvider == nil {
+		return s.provider, nil
 	}
-
-	return ""
+	return s.resolveProvider(name)
 }
 
-func extractSectionContent(text, section string) string {
-	lowerText := strings.ToLower(text)
-
-	switch section {
-	case "explanation":
-		start := strings.Index(lowerText, "=== explanation ===")
-		if start == -1 {
-			return ""
-		}
-		start += len("=== explanation ===")
-
-		// Find end (next section or end of text)
-		end := strings.Index(lowerText[start:], "=== translation notes ===")
-		if end == -1 {
-			end = len(text) - start
-		}
-
-		return strings.TrimSpace(text[start : start+end])
-
-	case "notes":
-		start := strings.Index(lowerText, "=== translation notes ===")
-		if start == -1 {
-			return ""
-		}
-		start += len("=== translation notes ===")
-
-		end := strings.Index(lowerText[start:], "=== translated code ===")
-		if end == -1 {
-			end = len(text) - start
+// cacheModel names the provider and model answering a request for CacheKey
+func cacheModel(provider TranslatorProviderInterface, model string) string {
+	name := ""
+	if describer, ok := provider.(providerDescriber); ok {
+		name = describer.Name()
+		if model == "" {
+			model = describer.Model()
 		}
+	}
+	return name + ":" + model
+}
 
-		return strings.TrimSpace(text[start : start+end])
-
-	case "code":
-		start := strings.Index(lowerText, "=== translated code ===")
-		if start == -1 {
-			return ""
-		}
-		start += len("=== translated code ===")
+func supportsSeed(provider TranslatorProviderInterface) bool {
+	supporter, ok := provider.(seedSupporter)
+	return ok && supporter.SupportsSeed()
+}
 
-		content := strings.TrimSpace(text[start:])
-		// Remove markdown code fences
-		content = strings.TrimPrefix(content, "```javascript")
-		content = strings.TrimPrefix(content, "```typescript")
-		content = strings.TrimPrefix(content, "```python")
-		content = strings.TrimPrefix(content, "```go")
-		content = strings.TrimPrefix(content, "```rust")
-		content = strings.TrimPrefix(content, "```java")
-		content = strings.TrimPrefix(content, "```csharp")
-		content = strings.TrimPrefix(content, "```cpp")
-		content = strings.TrimPrefix(content, "```php")
-		content = strings.TrimPrefix(content, "```ruby")
-		content = strings.TrimPrefix(content, "```swift")
-		content = strings.TrimPrefix(content, "```kotlin")
-		content = strings.TrimPrefix(content, "```")
-		content = strings.TrimSuffix(content, "```")
+// sendChunk marshals a StreamChunk and passes it to the callback
+func sendChunk(onChunk func(string) error, chunkType ChunkType, content string, delta bool) error {
+	jsonData, _ := json.Marshal(StreamChunk{
+		Type:    chunkType,
+		Content: content,
+		Delta:   delta,
+	})
+	return onChunk(string(jsonData))
+}
 
-		return strings.TrimSpace(content)
+// finalCode extracts the translated code from a complete response, enforcing the
+// request's term map on it when asked to
+func (s *CodeTranslatorService) finalCode(req types.TranslateRequest, text string) string {
+	code := s.headings.extractSectionContent(text, sectionCode)
+	if req.EnforceTermMap {
+		code = enforceTermMap(code, req.TermMap)
 	}
+	return code
+}
 
-	return ""
+// sendMetadata sends the provider's response metadata as a metadata chunk
+func sendMetadata(onChunk func(string) error, metadata *types.CompletionMetadata) error {
+	jsonData, _ := json.Marshal(StreamChunk{Type: ChunkTypeMetadata, Metadata: metadata})
+	return onChunk(string(jsonData))
 }
 
-func (s *CodeTranslatorService) sendFinalSections(text string, onChunk func(string) error) error {
-	// Send final complete versions of all sections
-	sections := []string{"explanation", "notes", "code"}
+func (s *CodeTranslatorService) sendFinalSections(req types.TranslateRequest, text string, onChunk func(string) error) error {
+	translated := s.finalCode(req, text)
 
-	for _, section := range sections {
-		content := extractSectionContent(text, section)
+	// Send final complete versions of all sections
+	for _, section := range sectionOrder {
+		content := s.headings.extractSectionContent(text, section)
+		if section == sectionCode {
+			content = translated
+		}
 		if content != "" {
 			chunk := StreamChunk{
 				Type:    ChunkType(section),
 				Content: content,
 				Delta:   false,
 			}
+			switch section {
+			case sectionNotes:
+				chunk.Items = parseNotes(content)
+			case sectionDependencies:
+				chunk.Items = parseDependencies(content)
+			}
+			// mark a translation of selected lines as partial
+			if section == sectionCode && req.StartLine > 0 {
+				chunk.Range = &LineRange{StartLine: req.StartLine, EndLine: req.EndLine}
+			}
 			jsonData, _ := json.Marshal(chunk)
 			if err := onChunk(string(jsonData)); err != nil {
 				return err
@@ -213,39 +518,225 @@ func (s *CodeTranslatorService) sendFinalSections(text string, onChunk func(stri
 		}
 	}
 
-	return nil
+	// a fence for another language usually means the model translated to the wrong one
+	if s.warnLanguageMismatch {
+		if tag, mismatch := s.headings.languageMismatch(text, req.TargetLanguage); mismatch {
+			s.logger.Warn("translated code is tagged with another language",
+				zap.String("target_language", req.TargetLanguage),
+				zap.String("fence_tag", tag),
+			)
+			message := fmt.Sprintf("%s: requested %s but the translated code is tagged %s", WarningLanguageMismatch, req.TargetLanguage, tag)
+			if err := sendChunk(onChunk, ChunkTypeWarning, message, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if missing := missingTerms(translated, req.TermMap); translated != "" && len(missing) > 0 {
+		message := fmt.Sprintf("%s: the translated code does not contain %s", WarningTermMissing, strings.Join(missing, ", "))
+		if err := sendChunk(onChunk, ChunkTypeWarning, message, false); err != nil {
+			return err
+		}
+	}
+
+	if req.Output == OutputPatch && translated != "" {
+		patch := unifiedDiff("a/source", "b/translated", req.Code, translated)
+		if err := sendChunk(onChunk, ChunkTypePatch, patch, false); err != nil {
+			return err
+		}
+	}
+
+	// Summarize the translation once the code section is complete
+	stats := computeStats(req.Code, translated)
+	jsonData, _ := json.Marshal(StreamChunk{
+		Type:  ChunkTypeStats,
+		Stats: &stats,
+	})
+	return onChunk(string(jsonData))
 }
 
-func buildPrompt(code, source, target string) string {
+// PromptTemplateVersion identifies the wording of the prompts built below. Bump it whenever
+// a template changes so cached translations made with the old wording are not reused.
+const PromptTemplateVersion = "1"
+
+// promptInput is everything that goes into a translation prompt
+type promptInput struct {
+	code, source, target string
+	// framework and instructions are optional guidance from the client
+	framework, instructions string
+	// termMap forces source terms to be translated to specific target terms
+	termMap map[string]string
+	// codeOnly asks for the translated code section alone, without explanation and notes
+	codeOnly bool
+	// dependencies asks for a section listing the packages the translated code needs
+	dependencies bool
+	examples     []types.TranslationExample
+	// memory holds prior translations of segments of code
+	memory []types.TranslationExample
+	// excerpt is set when code is a selected line range of a larger file
+	excerpt *excerpt
+}
+
+// buildPrompt assembles the translation prompt. Memory and excerpt context are
+// ignored for pseudocode, which has neither prior translations nor surrounding code.
+func buildPrompt(in promptInput) string {
+	code, source, target := in.code, in.source, in.target
+	if strings.EqualFold(source, LanguagePseudocode) {
+		return buildGenerationPrompt(in)
+	}
+
 	b := strings.Builder{}
 	b.WriteString("You are a code translator. You MUST respond in the EXACT format shown below.\n\n")
-	b.WriteString("CRITICAL: You must include ALL THREE sections in your response:\n")
-	b.WriteString("1. === EXPLANATION ===\n")
-	b.WriteString("2. === TRANSLATION NOTES ===\n")
-	b.WriteString("3. === TRANSLATED CODE ===\n\n")
+	b.WriteString(requiredSections(in.codeOnly, in.dependencies))
 
+	targetLabel := target
+	if in.framework != "" {
+		targetLabel = fmt.Sprintf("%s using %s", target, in.framework)
+	}
 	if source != "" {
-		b.WriteString(fmt.Sprintf("Translate this %s code to %s.\n\n", source, target))
+		b.WriteString(fmt.Sprintf("Translate this %s code to %s.\n\n", source, targetLabel))
 	} else {
-		b.WriteString(fmt.Sprintf("Translate this code to %s.\n\n", target))
+		b.WriteString(fmt.Sprintf("Translate this code to %s.\n\n", targetLabel))
 	}
 
 	b.WriteString("Your response MUST follow this EXACT structure:\n\n")
-	b.WriteString("=== EXPLANATION ===\n")
-	b.WriteString("[Write 2-3 sentences explaining what the original code does]\n\n")
-	b.WriteString("=== TRANSLATION NOTES ===\n")
-	b.WriteString("- [Key difference 1 between source and target language]\n")
-	b.WriteString("- [Key difference 2 between source and target language]\n")
-	b.WriteString("- [Key difference 3 between source and target language]\n\n")
+	if !in.codeOnly {
+		b.WriteString("=== EXPLANATION ===\n")
+		b.WriteString("[Write 2-3 sentences explaining what the original code does]\n\n")
+		b.WriteString("=== TRANSLATION NOTES ===\n")
+		b.WriteString("- [Key difference 1 between source and target language]\n")
+		b.WriteString("- [Key difference 2 between source and target language]\n")
+		b.WriteString("- [Key difference 3 between source and target language]\n\n")
+	}
+	if in.dependencies {
+		b.WriteString(dependencySection(target))
+	}
 	b.WriteString("=== TRANSLATED CODE ===\n")
 	b.WriteString("```" + target + "\n")
 	b.WriteString("[The complete translated code goes here]\n")
 	b.WriteString("```\n\n")
+
+	if len(in.examples) > 0 {
+		b.WriteString("Use these example translations as a reference for style and idioms:\n\n")
+		for i, example := range in.examples {
+			b.WriteString(formatExample(i+1, example))
+		}
+	}
+
+	if len(in.memory) > 0 {
+		b.WriteString("These parts of the source code were translated before. Reuse the earlier translation where the code is unchanged so the codebase stays consistent:\n\n")
+		for i, hint := range in.memory {
+			b.WriteString(formatExample(i+1, hint))
+		}
+	}
+
+	if in.excerpt != nil {
+		b.WriteString(formatExcerptContext(in.excerpt, source))
+	}
+
+	b.WriteString(formatTermMap(in.termMap))
+	b.WriteString(formatInstructions(in.instructions))
+
 	b.WriteString("SOURCE CODE TO TRANSLATE:\n")
 	b.WriteString("```" + source + "\n")
 	b.WriteString(code)
 	b.WriteString("\n```\n\n")
-	b.WriteString("IMPORTANT: You MUST include all three sections (EXPLANATION, TRANSLATION NOTES, and TRANSLATED CODE) in your response. Do not skip any section.")
+	b.WriteString(closingReminder(in.codeOnly, in.dependencies))
 
 	return b.String()
 }
+
+// buildGenerationPrompt asks the model to implement natural-language pseudocode in the
+// target language while keeping the same three-section response format
+func buildGenerationPrompt(in promptInput) string {
+	pseudocode, target, examples := in.code, in.target, in.examples
+	targetLabel := target
+	if in.framework != "" {
+		targetLabel = fmt.Sprintf("%s using %s", target, in.framework)
+	}
+
+	b := strings.Builder{}
+	b.WriteString("You are a software engineer turning pseudocode into working code. You MUST respond in the EXACT format shown below.\n\n")
+	b.WriteString(requiredSections(in.codeOnly, in.dependencies))
+	b.WriteString(fmt.Sprintf("Implement this pseudocode as idiomatic, complete %s code.\n\n", targetLabel))
+
+	b.WriteString("Your response MUST follow this EXACT structure:\n\n")
+	if !in.codeOnly {
+		b.WriteString("=== EXPLANATION ===\n")
+		b.WriteString("[Write 2-3 sentences explaining what the pseudocode describes]\n\n")
+		b.WriteString("=== TRANSLATION NOTES ===\n")
+		b.WriteString("- [Implementation decision 1, e.g. data structures or libraries chosen]\n")
+		b.WriteString("- [Implementation decision 2, e.g. how ambiguous steps were interpreted]\n")
+		b.WriteString("- [Implementation decision 3, e.g. error handling or edge cases]\n\n")
+	}
+	if in.dependencies {
+		b.WriteString(dependencySection(target))
+	}
+	b.WriteString("=== TRANSLATED CODE ===\n")
+	b.WriteString("```" + target + "\n")
+	b.WriteString("[The complete implementation goes here]\n")
+	b.WriteString("```\n\n")
+
+	if len(examples) > 0 {
+		b.WriteString("Use these examples as a reference for style and idioms:\n\n")
+		for i, example := range examples {
+			b.WriteString(formatExample(i+1, example))
+		}
+	}
+
+	b.WriteString(formatTermMap(in.termMap))
+	b.WriteString(formatInstructions(in.instructions))
+
+	b.WriteString("PSEUDOCODE TO IMPLEMENT:\n")
+	b.WriteString("```text\n")
+	b.WriteString(pseudocode)
+	b.WriteString("\n```\n\n")
+	b.WriteString(closingReminder(in.codeOnly, in.dependencies))
+
+	return b.String()
+}
+
+// requiredSections lists the sections the response must contain
+func requiredSections(codeOnly, dependencies bool) string {
+	switch {
+	case codeOnly && dependencies:
+		return "CRITICAL: Your response must contain ONLY these sections, with no explanation or notes:\n" +
+			"1. === DEPENDENCIES ===\n" +
+			"2. === TRANSLATED CODE ===\n\n"
+	case codeOnly:
+		return "CRITICAL: Your response must contain ONLY this section, with no explanation or notes:\n" +
+			"=== TRANSLATED CODE ===\n\n"
+	case dependencies:
+		return "CRITICAL: You must include ALL FOUR sections in your response:\n" +
+			"1. === EXPLANATION ===\n" +
+			"2. === TRANSLATION NOTES ===\n" +
+			"3. === DEPENDENCIES ===\n" +
+			"4. === TRANSLATED CODE ===\n\n"
+	}
+	return "CRITICAL: You must include ALL THREE sections in your response:\n" +
+		"1. === EXPLANATION ===\n" +
+		"2. === TRANSLATION NOTES ===\n" +
+		"3. === TRANSLATED CODE ===\n\n"
+}
+
+// closingReminder repeats the required sections at the end of the prompt
+func closingReminder(codeOnly, dependencies bool) string {
+	switch {
+	case codeOnly && dependencies:
+		return "IMPORTANT: Respond with only the DEPENDENCIES and TRANSLATED CODE sections. Do not add an explanation or notes."
+	case codeOnly:
+		return "IMPORTANT: Respond with only the TRANSLATED CODE section. Do not add an explanation or notes."
+	case dependencies:
+		return "IMPORTANT: You MUST include all four sections (EXPLANATION, TRANSLATION NOTES, DEPENDENCIES, and TRANSLATED CODE) in your response. Do not skip any section."
+	}
+	return "IMPORTANT: You MUST include all three sections (EXPLANATION, TRANSLATION NOTES, and TRANSLATED CODE) in your response. Do not skip any section."
+}
+
+// formatInstructions renders the client's extra instructions, or nothing when there are none
+func formatInstructions(instructions string) string {
+	if strings.TrimSpace(instructions) == "" {
+		return ""
+	}
+	return "Follow these additional instructions as long as they do not conflict with the required response format:\n" +
+		strings.TrimSpace(instructions) + "\n\n"
+}
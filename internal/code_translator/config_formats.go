@@ -0,0 +1,233 @@
+package code_translator
+
+import (
+	"bytes"
+	"code-bridge/pkg/types"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.uber.org/zap"
+	"go.yaml.in/yaml/v3"
+)
+
+// Configuration formats that are converted without a model
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+	formatTOML = "toml"
+)
+
+// configFormatAliases maps the language names accepted in requests to a configuration format
+var configFormatAliases = map[string]string{
+	"json": formatJSON,
+	"yaml": formatYAML,
+	"yml":  formatYAML,
+	"toml": formatTOML,
+}
+
+// configFormatExtensions lets LanguageFromFilename recognize configuration files
+var configFormatExtensions = map[string]string{
+	".json": formatJSON,
+	".yaml": formatYAML,
+	".yml":  formatYAML,
+	".toml": formatTOML,
+}
+
+// configFormatLabels are the format names used in the generated explanation
+var configFormatLabels = map[string]string{
+	formatJSON: "JSON",
+	formatYAML: "YAML",
+	formatTOML: "TOML",
+}
+
+// configFormat returns the configuration format named by language, if any
+func configFormat(language string) (string, bool) {
+	format, ok := configFormatAliases[strings.ToLower(strings.TrimSpace(language))]
+	return format, ok
+}
+
+// ConvertConfig converts a document between configuration formats (JSON, YAML, TOML) without
+// calling the provider, returning a complete response in the translation format. It reports
+// false when deterministic conversion is disabled, the request is not between two supported
+// formats, asks for guidance only a model can follow, or the document does not parse; such
+// requests are translated by the provider as usual.
+func (s *CodeTranslatorService) ConvertConfig(req types.TranslateRequest) (string, bool) {
+	if !s.convertConfig {
+		return "", false
+	}
+	source, ok := configFormat(req.SourceLanguage)
+	if !ok {
+		return "", false
+	}
+	target, ok := configFormat(req.TargetLanguage)
+	if !ok {
+		return "", false
+	}
+	if req.Framework != "" || req.Instructions != "" || len(req.TermMap) > 0 || req.StartLine > 0 || req.UseMemory {
+		return "", false
+	}
+
+	converted, err := convertConfig(req.Code, source, target)
+	if err != nil {
+		s.logger.Info("config conversion failed, falling back to the provider",
+			zap.String("source_language", source),
+			zap.String("target_language", target),
+			zap.Error(err),
+		)
+		return "", false
+	}
+	return configResponse(converted, source, target, req.CodeOnly), true
+}
+
+// SendResponse sends a complete response in the translation format, such as one
+// from ConvertConfig, as the final sections of a translation
+func (s *CodeTranslatorService) SendResponse(req types.TranslateRequest, response string, onChunk func(string) error) error {
+	return s.sendFinalSections(req, response, onChunk)
+}
+
+// configResponse wraps a converted document in the sections a provider would have returned
+func configResponse(converted, source, target string, codeOnly bool) string {
+	var b strings.Builder
+	if !codeOnly {
+		fmt.Fprintf(&b, "%s\nConverted the %s document to %s.\n\n", sectionHeading(sectionExplanation), configFormatLabels[source], configFormatLabels[target])
+		fmt.Fprintf(&b, "%s\n- Converted deterministically without a model.\n- Keys are sorted and comments are not carried over.\n\n", sectionHeading(sectionNotes))
+	}
+	fmt.Fprintf(&b, "%s\n```%s\n%s```\n", sectionHeading(sectionCode), target, converted)
+	return b.String()
+}
+
+// convertConfig parses code as source and encodes it as target
+func convertConfig(code, source, target string) (string, error) {
+	value, err := decodeConfig(code, source)
+	if err != nil {
+		return "", err
+	}
+	return encodeConfig(normalizeConfigValue(value), target)
+}
+
+func decodeConfig(code, format string) (any, error) {
+	var value any
+	switch format {
+	case formatJSON:
+		decoder := json.NewDecoder(strings.NewReader(code))
+		// keep integers exact instead of turning them into float64
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, errors.New("invalid JSON: unexpected data after the top-level value")
+		}
+	case formatYAML:
+		decoder := yaml.NewDecoder(strings.NewReader(code))
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		var next any
+		if err := decoder.Decode(&next); err != io.EOF {
+			return nil, errors.New("YAML streams with several documents are not supported")
+		}
+	case formatTOML:
+		var table map[string]any
+		if err := toml.Unmarshal([]byte(code), &table); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+		value = table
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+	return value, nil
+}
+
+func encodeConfig(value any, format string) (string, error) {
+	var buf bytes.Buffer
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+	case formatYAML:
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+		if err := encoder.Close(); err != nil {
+			return "", err
+		}
+	case formatTOML:
+		if _, ok := value.(map[string]any); !ok {
+			return "", errors.New("TOML documents must be a table at the top level")
+		}
+		if hasNull(value) {
+			return "", errors.New("TOML has no null value")
+		}
+		if err := toml.NewEncoder(&buf).Encode(value); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported config format %q", format)
+	}
+	return buf.String(), nil
+}
+
+// normalizeConfigValue turns decoded values into types every encoder handles:
+// string map keys, and int64 or float64 instead of json.Number
+func normalizeConfigValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeConfigValue(item)
+		}
+		return v
+	case map[any]any:
+		normalized := make(map[string]any, len(v))
+		for key, item := range v {
+			normalized[fmt.Sprint(key)] = normalizeConfigValue(item)
+		}
+		return normalized
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeConfigValue(item)
+		}
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	default:
+		return v
+	}
+}
+
+// hasNull reports whether value contains a null anywhere
+func hasNull(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		for _, item := range v {
+			if hasNull(item) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if hasNull(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package code_translator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dependencyFormats describes how each target language's dependencies are listed, in the form
+// of the manifest they go into so they can be pasted there
+var dependencyFormats = map[string]string{
+	"go":         "go.mod require lines, e.g. github.com/gin-gonic/gin v1.10.0",
+	"python":     "requirements.txt lines, e.g. requests==2.32.3",
+	"javascript": `package.json "dependencies" entries, e.g. "express": "^4.19.2"`,
+	"typescript": `package.json "dependencies" entries, including @types packages, e.g. "express": "^4.19.2"`,
+	"rust":       `Cargo.toml [dependencies] lines, e.g. serde = "1.0"`,
+	"java":       "Maven coordinates, e.g. com.google.code.gson:gson:2.11.0",
+	"kotlin":     "Gradle coordinates, e.g. org.jetbrains.kotlinx:kotlinx-coroutines-core:1.8.1",
+	"scala":      `sbt libraryDependencies entries, e.g. "org.typelevel" %% "cats-core" % "2.12.0"`,
+	"csharp":     "NuGet package names and versions, e.g. Newtonsoft.Json 13.0.3",
+	"php":        `composer.json "require" entries, e.g. "guzzlehttp/guzzle": "^7.8"`,
+	"ruby":       `Gemfile lines, e.g. gem "rails", "~> 7.1"`,
+	"swift":      `Package.swift dependencies, e.g. .package(url: "https://github.com/apple/swift-argument-parser", from: "1.3.0")`,
+	"dart":       "pubspec.yaml dependencies lines, e.g. http: ^1.2.1",
+	"cpp":        "vcpkg package names, e.g. fmt",
+	"c":          "vcpkg package names, e.g. curl",
+	"lua":        "LuaRocks package names and versions, e.g. luasocket 3.1.0",
+}
+
+// dependencyFormat returns how the dependencies of code in target are listed
+func dependencyFormat(target string) string {
+	if format, ok := dependencyFormats[normalizeLanguage(target)]; ok {
+		return format
+	}
+	return "one package per line with the version it needs"
+}
+
+// dependencySection is the prompt's template for the dependencies section
+func dependencySection(target string) string {
+	return sectionHeading(sectionDependencies) + "\n" +
+		fmt.Sprintf("[Every third-party package the translated code needs beyond the %s standard library, one per line as %s. Write None if it needs none]\n\n", target, dependencyFormat(target))
+}
+
+// parseDependencies splits the dependencies section into one entry per package, dropping
+// code fences and list markers. A section reading only "None" has no entries.
+func parseDependencies(text string) []string {
+	var entries []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		if loc := bulletPattern.FindStringIndex(line); loc != nil {
+			line = line[loc[1]:]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.EqualFold(strings.TrimRight(line, "."), "none") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries
+}
@@ -0,0 +1,66 @@
+package code_translator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrCodeContextTooLarge is reported when a prompt would not fit the model context
+const ErrCodeContextTooLarge = "context_too_large"
+
+// ErrEmptyTranslation is returned when the provider's response has no translated code section
+var ErrEmptyTranslation = errors.New("response has no translated code section")
+
+// errResponseComplete stops a provider stream once the response is complete; it never reaches callers
+var errResponseComplete = errors.New("response complete")
+
+// WarningLanguageMismatch prefixes the warning sent when the translated code's fence names another language
+const WarningLanguageMismatch = "language_mismatch"
+
+// Failure classifications returned by ErrorCode
+const (
+	ErrCodeEmptyTranslation = "empty_translation"
+	ErrCodeTimeout          = "timeout"
+	ErrCodeCanceled         = "canceled"
+	ErrCodeProvider         = "provider_error"
+)
+
+// ErrorCode classifies an error returned by TranslateCode so failures can be aggregated by reason
+func ErrorCode(err error) string {
+	var tooLarge *ContextTooLargeError
+	var format *FormatError
+	// errors from further down, e.g. a rate limited provider, may classify themselves
+	var coded interface{ Code() string }
+	switch {
+	case errors.As(err, &tooLarge):
+		return ErrCodeContextTooLarge
+	case errors.As(err, &format):
+		return ErrCodeFormatViolation
+	case errors.Is(err, ErrEmptyTranslation):
+		return ErrCodeEmptyTranslation
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrCodeCanceled
+	case errors.As(err, &coded):
+		return coded.Code()
+	default:
+		return ErrCodeProvider
+	}
+}
+
+// ContextTooLargeError is returned when the estimated prompt size exceeds the configured limit
+type ContextTooLargeError struct {
+	Limit  int
+	Actual int
+}
+
+func (e *ContextTooLargeError) Error() string {
+	return fmt.Sprintf("%s: prompt is ~%d tokens, limit is %d", ErrCodeContextTooLarge, e.Actual, e.Limit)
+}
+
+// Code returns the machine-readable error code
+func (e *ContextTooLargeError) Code() string {
+	return ErrCodeContextTooLarge
+}
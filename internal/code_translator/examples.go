@@ -0,0 +1,45 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"fmt"
+	"strings"
+)
+
+// selectExamples picks the configured examples for a language pair, skipping any longer
+// than maxChars and stopping once the remaining token budget is used up
+func selectExamples(examples []types.TranslationExample, sourceLang, targetLang string, maxChars, budgetTokens int) []types.TranslationExample {
+	if sourceLang == "" || budgetTokens <= 0 {
+		return nil
+	}
+
+	var selected []types.TranslationExample
+	for _, example := range examples {
+		if !strings.EqualFold(example.SourceLanguage, sourceLang) || !strings.EqualFold(example.TargetLanguage, targetLang) {
+			continue
+		}
+		if maxChars > 0 && len(example.Source)+len(example.Target) > maxChars {
+			continue
+		}
+		cost := estimateTokens(formatExample(len(selected)+1, example))
+		if cost > budgetTokens {
+			break
+		}
+		budgetTokens -= cost
+		selected = append(selected, example)
+	}
+	return selected
+}
+
+func formatExample(n int, example types.TranslationExample) string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("Example %d:\n", n))
+	b.WriteString("```" + example.SourceLanguage + "\n")
+	b.WriteString(example.Source)
+	b.WriteString("\n```\n")
+	b.WriteString("translates to:\n")
+	b.WriteString("```" + example.TargetLanguage + "\n")
+	b.WriteString(example.Target)
+	b.WriteString("\n```\n\n")
+	return b.String()
+}
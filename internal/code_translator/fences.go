@@ -0,0 +1,75 @@
+package code_translator
+
+import "strings"
+
+// fenceTagAliases maps alternative code fence tags to the language names used in requests
+var fenceTagAliases = map[string]string{
+	"js":     "javascript",
+	"jsx":    "javascript",
+	"ts":     "typescript",
+	"tsx":    "typescript",
+	"py":     "python",
+	"golang": "go",
+	"rs":     "rust",
+	"cs":     "csharp",
+	"c#":     "csharp",
+	"c++":    "cpp",
+	"rb":     "ruby",
+	"kt":     "kotlin",
+	"sh":     "bash",
+	"shell":  "bash",
+}
+
+// normalizeLanguage lowercases a language name and resolves common fence tag aliases
+func normalizeLanguage(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := fenceTagAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// codeFenceTag returns the language tag of the fence opening the translated code
+// section, or an empty string if the code is unfenced or the fence has no tag
+func (h *sectionHeadings) codeFenceTag(text string) string {
+	start := h.contentStart(text, sectionCode)
+	if start == -1 {
+		return ""
+	}
+	content := strings.TrimSpace(text[start:])
+	if !strings.HasPrefix(content, "```") {
+		return ""
+	}
+	line, _, _ := strings.Cut(content[len("```"):], "\n")
+	tag, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	return tag
+}
+
+// languageMismatch reports the fence tag of the translated code when it names a
+// different language than target. Unknown targets and untagged fences never mismatch.
+func (h *sectionHeadings) languageMismatch(text, target string) (string, bool) {
+	tag := h.codeFenceTag(text)
+	if tag == "" || !IsKnownLanguage(normalizeLanguage(target)) {
+		return "", false
+	}
+	return tag, normalizeLanguage(tag) != normalizeLanguage(target)
+}
+
+// responseComplete reports whether text has all required sections and the code
+// section's fence has been closed, i.e. anything further is trailing chatter.
+// Code-only responses have no explanation or notes to wait for.
+func (h *sectionHeadings) responseComplete(text string, codeOnly bool) bool {
+	if !codeOnly && (!h.has(text, sectionExplanation) || !h.has(text, sectionNotes)) {
+		return false
+	}
+	start := h.contentStart(text, sectionCode)
+	if start == -1 {
+		return false
+	}
+	rest := text[start:]
+	open := strings.Index(rest, "```")
+	if open == -1 {
+		return false
+	}
+	return strings.Contains(rest[open+len("```"):], "\n```")
+}
@@ -0,0 +1,45 @@
+package code_translator
+
+import "time"
+
+// deltaFlusher decides when a section's growing content is worth another delta
+// chunk, so a stream of tiny provider tokens doesn't become a stream of tiny SSE frames.
+// Complete sections are always sent at section boundaries and on completion regardless.
+type deltaFlusher struct {
+	minBytes    int
+	minInterval time.Duration
+
+	lastLen  int
+	lastSent time.Time
+}
+
+func newDeltaFlusher(minBytes int, minInterval time.Duration) *deltaFlusher {
+	return &deltaFlusher{minBytes: minBytes, minInterval: minInterval}
+}
+
+// due reports whether content has changed enough since the last delta, by size or elapsed time
+func (f *deltaFlusher) due(content string) bool {
+	if len(content) == f.lastLen {
+		return false
+	}
+	// the first delta of a section goes out immediately so clients see it start
+	if f.lastSent.IsZero() {
+		return true
+	}
+	if len(content)-f.lastLen >= f.minBytes {
+		return true
+	}
+	return f.minInterval > 0 && time.Since(f.lastSent) >= f.minInterval
+}
+
+// sent records that a delta with content was emitted
+func (f *deltaFlusher) sent(content string) {
+	f.lastLen = len(content)
+	f.lastSent = time.Now()
+}
+
+// reset starts tracking a new section
+func (f *deltaFlusher) reset() {
+	f.lastLen = 0
+	f.lastSent = time.Time{}
+}
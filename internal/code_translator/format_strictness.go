@@ -0,0 +1,111 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Format strictness decides what happens when a response lacks sections the prompt asked for
+const (
+	// FormatLenient sends whatever sections the response has
+	FormatLenient = "lenient"
+	// FormatStrict asks the provider once more and fails the translation with a
+	// FormatError if the second response is still incomplete
+	FormatStrict = "strict"
+)
+
+// ErrCodeFormatViolation classifies translations failed by strict format checking
+const ErrCodeFormatViolation = "format_violation"
+
+// WarningFormatViolation prefixes the warning sent before a strict request is asked again
+const WarningFormatViolation = "format_violation"
+
+// ParseFormatStrictness validates a FORMAT_STRICTNESS or format_strictness value
+func ParseFormatStrictness(name string) (string, error) {
+	switch name {
+	case FormatLenient, FormatStrict:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unsupported format strictness %q, must be %s or %s", name, FormatStrict, FormatLenient)
+	}
+}
+
+// FormatError is returned in strict mode when the response is still missing sections after asking again
+type FormatError struct {
+	Missing []string
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf("%s: response is missing the %s section(s)", ErrCodeFormatViolation, strings.Join(e.Missing, ", "))
+}
+
+// Code returns the machine-readable error code
+func (e *FormatError) Code() string {
+	return ErrCodeFormatViolation
+}
+
+// strictFormat reports whether req is checked strictly; the request overrides the configured default
+func (s *CodeTranslatorService) strictFormat(requested string) bool {
+	if requested != "" {
+		return requested == FormatStrict
+	}
+	return s.formatStrictness == FormatStrict
+}
+
+// missingSections returns the sections the prompt asked for that are absent or empty in text
+func (h *sectionHeadings) missingSections(text string, codeOnly, dependencies bool) []string {
+	var missing []string
+	for _, section := range sectionOrder {
+		if section == sectionDependencies && !dependencies {
+			continue
+		}
+		if codeOnly && section != sectionCode && section != sectionDependencies {
+			continue
+		}
+		if h.extractSectionContent(text, section) == "" {
+			missing = append(missing, section)
+		}
+	}
+	return missing
+}
+
+// formatReminder is appended to the prompt when asking again for a response with missing sections
+func formatReminder(missing []string) string {
+	headings := make([]string, len(missing))
+	for i, section := range missing {
+		headings[i] = sectionHeading(section)
+	}
+	return fmt.Sprintf("\n\nIMPORTANT: A previous answer to this request was missing %s. Answer again with every section, each starting with its exact heading.",
+		strings.Join(headings, ", "))
+}
+
+// enforceFormat asks the provider once more, with a reminder of the format, when response
+// is missing sections, and returns a FormatError if the second response is missing any too.
+// The second response is not streamed: its deltas would be appended by clients to the sections
+// of the first, so only its final sections are sent, by the caller, once it is complete.
+func (s *CodeTranslatorService) enforceFormat(ctx context.Context, req types.TranslateRequest, provider TranslatorProviderInterface, prompt string, opts types.CompletionOptions, response string, onChunk func(string) error) (string, error) {
+	missing := s.headings.missingSections(response, req.CodeOnly, req.IncludeDependencies)
+	if len(missing) == 0 {
+		return response, nil
+	}
+
+	s.logger.Warn("response is missing sections, asking again", zap.Strings("missing", missing))
+	message := fmt.Sprintf("%s: the response is missing the %s section(s), asking the model again; its complete sections replace these", WarningFormatViolation, strings.Join(missing, ", "))
+	if err := sendChunk(onChunk, ChunkTypeWarning, message, false); err != nil {
+		return "", err
+	}
+
+	discard := func(string) error { return nil }
+	response, err := s.streamResponse(ctx, req, provider, prompt+formatReminder(missing), opts, discard)
+	if err != nil {
+		return "", err
+	}
+	if missing := s.headings.missingSections(response, req.CodeOnly, req.IncludeDependencies); len(missing) > 0 {
+		return "", &FormatError{Missing: missing}
+	}
+	return response, nil
+}
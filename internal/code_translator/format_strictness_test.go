@@ -0,0 +1,70 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// sequenceProvider answers the nth completion with responses[n]
+type sequenceProvider struct {
+	responses []string
+	calls     int
+}
+
+func (p *sequenceProvider) StreamCompletion(_ context.Context, _ string, _ types.CompletionOptions, onChunk func(string) error) error {
+	response := p.responses[p.calls]
+	p.calls++
+	// in small pieces, so deltas would be sent for them
+	for _, line := range strings.SplitAfter(response, "\n") {
+		if err := onChunk(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStrictRetryIsNotStreamedIntoTheFirstResponse(t *testing.T) {
+	provider := &sequenceProvider{responses: []string{
+		"=== EXPLANATION ===\nFirst.\n=== TRANSLATED CODE ===\nfirst()",
+		"=== EXPLANATION ===\nSecond.\n=== TRANSLATION NOTES ===\n- none\n=== TRANSLATED CODE ===\nsecond()",
+	}}
+	service := NewCodeTranslatorService(zap.NewNop(), provider, nil, nil, types.TranslatorConfig{FormatStrictness: FormatStrict})
+	req := types.TranslateRequest{Code: "first()", SourceLanguage: "python", TargetLanguage: "go"}
+
+	var afterWarning []StreamChunk
+	warned := false
+	err := service.TranslateCode(context.Background(), req, func(raw string) error {
+		var chunk StreamChunk
+		if err := json.Unmarshal([]byte(raw), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		if chunk.Type == ChunkTypeWarning && strings.HasPrefix(chunk.Content, WarningFormatViolation) {
+			warned = true
+		} else if warned {
+			afterWarning = append(afterWarning, chunk)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("TranslateCode: %v", err)
+	}
+	if !warned || provider.calls != 2 {
+		t.Fatalf("warned = %v after %d completions, want a warning and a second completion", warned, provider.calls)
+	}
+
+	final := map[ChunkType]string{}
+	for _, chunk := range afterWarning {
+		if chunk.Delta {
+			t.Errorf("delta %s chunk %q was streamed after the retry started", chunk.Type, chunk.Content)
+		}
+		final[chunk.Type] = chunk.Content
+	}
+	if final[ChunkTypeExplanation] != "Second." || final[ChunkTypeNotes] != "- none" || final[ChunkTypeCode] != "second()" {
+		t.Errorf("sections after the retry = %q, want the second response's", final)
+	}
+}
@@ -0,0 +1,61 @@
+package code_translator
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// LanguagePseudocode as a source language switches the prompt from translation to generation
+const LanguagePseudocode = "pseudocode"
+
+// ExtensionToLanguage maps lowercase file extensions to the language names used in requests
+var ExtensionToLanguage = map[string]string{
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".cjs":   "javascript",
+	".jsx":   "javascript",
+	".ts":    "typescript",
+	".tsx":   "typescript",
+	".py":    "python",
+	".go":    "go",
+	".rs":    "rust",
+	".java":  "java",
+	".cs":    "csharp",
+	".cpp":   "cpp",
+	".cc":    "cpp",
+	".cxx":   "cpp",
+	".hpp":   "cpp",
+	".c":     "c",
+	".h":     "c",
+	".php":   "php",
+	".rb":    "ruby",
+	".swift": "swift",
+	".kt":    "kotlin",
+	".kts":   "kotlin",
+	".scala": "scala",
+	".dart":  "dart",
+	".lua":   "lua",
+	".sh":    "bash",
+	".sql":   "sql",
+}
+
+// LanguageFromFilename infers a language from a filename's extension,
+// returning an empty string when the extension is unknown
+func LanguageFromFilename(filename string) string {
+	extension := strings.ToLower(filepath.Ext(filename))
+	if language, ok := ExtensionToLanguage[extension]; ok {
+		return language
+	}
+	return configFormatExtensions[extension]
+}
+
+// IsKnownLanguage reports whether name is one of the programming languages in ExtensionToLanguage
+func IsKnownLanguage(name string) bool {
+	name = strings.ToLower(name)
+	for _, language := range ExtensionToLanguage {
+		if language == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package code_translator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// excerptContextLines is how many lines around a selected range are shown to the model as context
+const excerptContextLines = 5
+
+// LineRange identifies the 1-based, inclusive lines of the source that were translated
+type LineRange struct {
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+}
+
+// excerpt is a selected line range of a larger source along with the lines around it
+type excerpt struct {
+	LineRange
+	before, after string
+}
+
+// ValidateLineRange checks an optional start_line/end_line selection against code.
+// Zero means unset: start defaults to the first line and end to the last.
+func ValidateLineRange(code string, start, end int) error {
+	if start == 0 && end == 0 {
+		return nil
+	}
+	if start < 0 || end < 0 {
+		return fmt.Errorf("start_line and end_line must be positive")
+	}
+	total := len(strings.Split(code, "\n"))
+	if start > total {
+		return fmt.Errorf("start_line %d is past the end of the code (%d lines)", start, total)
+	}
+	if end > total {
+		return fmt.Errorf("end_line %d is past the end of the code (%d lines)", end, total)
+	}
+	if end != 0 && start > end {
+		return fmt.Errorf("start_line %d is after end_line %d", start, end)
+	}
+	return nil
+}
+
+// selectLines returns the selected lines of code and the excerpt describing them,
+// or code unchanged and a nil excerpt when no range is selected. The range must
+// have passed ValidateLineRange.
+func selectLines(code string, start, end int) (string, *excerpt) {
+	if start == 0 && end == 0 {
+		return code, nil
+	}
+	lines := strings.Split(code, "\n")
+	if start == 0 {
+		start = 1
+	}
+	if end == 0 {
+		end = len(lines)
+	}
+
+	ex := &excerpt{LineRange: LineRange{StartLine: start, EndLine: end}}
+	ex.before = strings.Join(lines[max(0, start-1-excerptContextLines):start-1], "\n")
+	ex.after = strings.Join(lines[end:min(len(lines), end+excerptContextLines)], "\n")
+	return strings.Join(lines[start-1:end], "\n"), ex
+}
+
+// formatExcerptContext tells the model the code is part of a larger file and shows
+// the surrounding lines without asking for them to be translated
+func formatExcerptContext(ex *excerpt, source string) string {
+	if ex.before == "" && ex.after == "" {
+		return fmt.Sprintf("The code to translate is lines %d-%d of a larger file.\n\n", ex.StartLine, ex.EndLine)
+	}
+
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("The code to translate is lines %d-%d of a larger file. ", ex.StartLine, ex.EndLine))
+	b.WriteString("The surrounding lines are shown for context only; do NOT translate them.\n\n")
+	if ex.before != "" {
+		b.WriteString("Lines before:\n```" + source + "\n" + ex.before + "\n```\n\n")
+	}
+	if ex.after != "" {
+		b.WriteString("Lines after:\n```" + source + "\n" + ex.after + "\n```\n\n")
+	}
+	return b.String()
+}
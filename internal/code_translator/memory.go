@@ -0,0 +1,119 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// TranslationMemory stores prior segment translations so similar files translate consistently.
+// Segments are kept per scope, the caller that translated them, and never shared between scopes.
+type TranslationMemory interface {
+	// Lookup returns the scope's stored segments for the language pair whose keys are in keys
+	Lookup(ctx context.Context, scope, sourceLang, targetLang string, keys []string) ([]types.MemorySegment, error)
+	// Store saves segments for the scope and language pair, replacing earlier translations with the same key
+	Store(ctx context.Context, scope, sourceLang, targetLang string, segments []types.MemorySegment) error
+}
+
+// segmentStartPattern matches an unindented line that opens a top-level definition
+var segmentStartPattern = regexp.MustCompile(`^(?:(?:export|pub|public|private|protected|internal|static|async|abstract|final|default)\s+)*(?:func|function|def|fn|fun|class|struct|interface|enum|trait|impl|type)\b`)
+
+// splitSegments splits code into top-level definitions. Anything before the
+// first definition (package clauses, imports) is not a segment.
+func splitSegments(code string) []string {
+	var segments []string
+	var current []string
+
+	flush := func() {
+		if segment := strings.TrimSpace(strings.Join(current, "\n")); segment != "" {
+			segments = append(segments, segment)
+		}
+		current = nil
+	}
+
+	inSegment := false
+	for _, line := range strings.Split(code, "\n") {
+		if segmentStartPattern.MatchString(line) {
+			flush()
+			inSegment = true
+		}
+		if inSegment {
+			current = append(current, line)
+		}
+	}
+	flush()
+	return segments
+}
+
+// segmentKey hashes a segment after dropping blank lines and trailing whitespace,
+// so formatting-only differences still hit the memory
+func segmentKey(segment string) string {
+	var lines []string
+	for _, line := range strings.Split(segment, "\n") {
+		if line = strings.TrimRight(line, " \t\r"); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// recallMemory looks up scope's prior translations for the segments of code. Memory is a
+// quality hint, so lookup failures are logged and the translation goes ahead without it.
+func (s *CodeTranslatorService) recallMemory(ctx context.Context, scope, code, sourceLang, targetLang string) []types.TranslationExample {
+	if s.memory == nil || sourceLang == "" {
+		return nil
+	}
+	segments := splitSegments(code)
+	if len(segments) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(segments))
+	for i, segment := range segments {
+		keys[i] = segmentKey(segment)
+	}
+	found, err := s.memory.Lookup(ctx, scope, strings.ToLower(sourceLang), strings.ToLower(targetLang), keys)
+	if err != nil {
+		s.logger.Warn("translation memory lookup failed", zap.Error(err))
+		return nil
+	}
+
+	hints := make([]types.TranslationExample, 0, len(found))
+	for _, segment := range found {
+		hints = append(hints, types.TranslationExample{
+			SourceLanguage: sourceLang,
+			TargetLanguage: targetLang,
+			Source:         segment.Source,
+			Target:         segment.Target,
+		})
+	}
+	s.logger.Debug("translation memory hits", zap.Int("segments", len(segments)), zap.Int("hits", len(hints)))
+	return hints
+}
+
+// rememberTranslation stores source/target segment pairs for scope. Segments can only be
+// paired when both sides split into the same number of definitions, so other
+// translations are not remembered.
+func (s *CodeTranslatorService) rememberTranslation(ctx context.Context, scope, source, translated, sourceLang, targetLang string) {
+	if s.memory == nil || sourceLang == "" || translated == "" {
+		return
+	}
+	sources, targets := splitSegments(source), splitSegments(translated)
+	if len(sources) == 0 || len(sources) != len(targets) {
+		return
+	}
+
+	segments := make([]types.MemorySegment, len(sources))
+	for i := range sources {
+		segments[i] = types.MemorySegment{Key: segmentKey(sources[i]), Source: sources[i], Target: targets[i]}
+	}
+	if err := s.memory.Store(ctx, scope, strings.ToLower(sourceLang), strings.ToLower(targetLang), segments); err != nil {
+		s.logger.Warn("failed to store translation memory", zap.Error(err))
+	}
+}
@@ -0,0 +1,71 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// mapMemory is a translation memory kept in a map
+type mapMemory map[string]types.MemorySegment
+
+func (m mapMemory) Lookup(_ context.Context, scope, sourceLang, targetLang string, keys []string) ([]types.MemorySegment, error) {
+	var found []types.MemorySegment
+	for _, key := range keys {
+		if segment, ok := m[scope+"/"+sourceLang+"/"+targetLang+"/"+key]; ok {
+			found = append(found, segment)
+		}
+	}
+	return found, nil
+}
+
+func (m mapMemory) Store(_ context.Context, scope, sourceLang, targetLang string, segments []types.MemorySegment) error {
+	for _, segment := range segments {
+		m[scope+"/"+sourceLang+"/"+targetLang+"/"+segment.Key] = segment
+	}
+	return nil
+}
+
+func TestMemoryIsScopedToTheCaller(t *testing.T) {
+	provider := &recordingPromptProvider{response: "=== EXPLANATION ===\nAdds.\n=== TRANSLATION NOTES ===\n- none\n=== TRANSLATED CODE ===\nfunc secretTotal() int { return 42 }"}
+	service := NewCodeTranslatorService(zap.NewNop(), provider, nil, mapMemory{}, types.TranslatorConfig{MaxPromptTokens: 10000})
+	req := types.TranslateRequest{
+		Code:           "def secret_total():\n    return 42",
+		SourceLanguage: "python",
+		TargetLanguage: "go",
+		UseMemory:      true,
+	}
+	discard := func(string) error { return nil }
+
+	for _, scope := range []string{"key:tenant-a", "key:tenant-b", "key:tenant-a"} {
+		req.MemoryScope = scope
+		if err := service.TranslateCode(context.Background(), req, discard); err != nil {
+			t.Fatalf("TranslateCode for %s: %v", scope, err)
+		}
+	}
+
+	recalled := func(prompt string) bool { return strings.Contains(prompt, "func secretTotal()") }
+	if recalled(provider.prompts[0]) {
+		t.Error("the first translation was given memory before anything was stored")
+	}
+	if recalled(provider.prompts[1]) {
+		t.Error("tenant-b was given tenant-a's translation memory")
+	}
+	if !recalled(provider.prompts[2]) {
+		t.Error("tenant-a was not given its own translation memory")
+	}
+}
+
+// recordingPromptProvider answers every prompt with response, recording the prompts
+type recordingPromptProvider struct {
+	response string
+	prompts  []string
+}
+
+func (p *recordingPromptProvider) StreamCompletion(_ context.Context, prompt string, _ types.CompletionOptions, onChunk func(string) error) error {
+	p.prompts = append(p.prompts, prompt)
+	return onChunk(p.response)
+}
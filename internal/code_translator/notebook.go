@@ -0,0 +1,251 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// CellProgress reports which notebook cell a ChunkTypeCell chunk belongs to
+type CellProgress struct {
+	// Cell is the index of the cell in the notebook
+	Cell int `json:"cell"`
+	// Done and Total count translated code cells
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// notebookProbe holds just enough of a document to recognise a Jupyter notebook
+type notebookProbe struct {
+	Cells    []json.RawMessage `json:"cells"`
+	Nbformat *int              `json:"nbformat"`
+}
+
+// IsNotebook reports whether code is a Jupyter notebook (.ipynb JSON)
+func IsNotebook(code string) bool {
+	trimmed := strings.TrimSpace(code)
+	if !strings.HasPrefix(trimmed, "{") {
+		return false
+	}
+	var probe notebookProbe
+	if err := json.Unmarshal([]byte(trimmed), &probe); err != nil {
+		return false
+	}
+	return probe.Nbformat != nil && probe.Cells != nil
+}
+
+// notebook keeps every field of the document as raw JSON so that fields the
+// translator doesn't touch round-trip unchanged
+type notebook struct {
+	fields map[string]json.RawMessage
+	cells  []map[string]json.RawMessage
+}
+
+func parseNotebook(code string) (*notebook, error) {
+	nb := &notebook{}
+	if err := json.Unmarshal([]byte(code), &nb.fields); err != nil {
+		return nil, fmt.Errorf("invalid notebook: %w", err)
+	}
+	if err := json.Unmarshal(nb.fields["cells"], &nb.cells); err != nil {
+		return nil, fmt.Errorf("invalid notebook cells: %w", err)
+	}
+	return nb, nil
+}
+
+// codeCells returns the indices of code cells with non-empty source
+func (nb *notebook) codeCells() []int {
+	var indices []int
+	for i, cell := range nb.cells {
+		var cellType string
+		_ = json.Unmarshal(cell["cell_type"], &cellType)
+		if cellType == "code" && strings.TrimSpace(cellSource(cell)) != "" {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// language returns the kernel language recorded in the notebook metadata
+func (nb *notebook) language() string {
+	var metadata struct {
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+	}
+	_ = json.Unmarshal(nb.fields["metadata"], &metadata)
+	if metadata.LanguageInfo.Name != "" {
+		return metadata.LanguageInfo.Name
+	}
+	return metadata.Kernelspec.Language
+}
+
+// setLanguage records the target language in the notebook metadata. The kernel
+// name is left alone since there is no way to know which kernel will run it.
+func (nb *notebook) setLanguage(language string) {
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(nb.fields["metadata"], &metadata); err != nil || metadata == nil {
+		return
+	}
+	for key, field := range map[string]string{"language_info": "name", "kernelspec": "language"} {
+		var section map[string]any
+		if err := json.Unmarshal(metadata[key], &section); err != nil || section == nil {
+			continue
+		}
+		section[field] = language
+		metadata[key], _ = json.Marshal(section)
+	}
+	nb.fields["metadata"], _ = json.Marshal(metadata)
+}
+
+func (nb *notebook) marshal() (string, error) {
+	cells, err := json.Marshal(nb.cells)
+	if err != nil {
+		return "", err
+	}
+	nb.fields["cells"] = cells
+	// nbformat writes notebooks with a one-space indent
+	data, err := json.MarshalIndent(nb.fields, "", " ")
+	if err != nil {
+		return "", err
+	}
+	return string(data) + "\n", nil
+}
+
+// cellSource joins a cell's source, which nbformat allows as a string or a list of lines
+func cellSource(cell map[string]json.RawMessage) string {
+	var lines []string
+	if err := json.Unmarshal(cell["source"], &lines); err == nil {
+		return strings.Join(lines, "")
+	}
+	var source string
+	_ = json.Unmarshal(cell["source"], &source)
+	return source
+}
+
+// setCodeCell replaces a code cell's source and clears outputs that belonged to the original language
+func setCodeCell(cell map[string]json.RawMessage, source string) {
+	lines := strings.SplitAfter(source, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	cell["source"], _ = json.Marshal(lines)
+	cell["outputs"] = json.RawMessage("[]")
+	cell["execution_count"] = json.RawMessage("null")
+}
+
+// NotebookTranslator translates the code cells of a Jupyter notebook one at a
+// time, keeping markdown and raw cells, and reassembles the notebook
+type NotebookTranslator struct {
+	service *CodeTranslatorService
+}
+
+// NewNotebookTranslator creates a notebook translator using the service's providers and prompts
+func NewNotebookTranslator(service *CodeTranslatorService) *NotebookTranslator {
+	return &NotebookTranslator{service: service}
+}
+
+// CheckPromptSize returns a ContextTooLargeError if any code cell's prompt would exceed the token limit
+func (t *NotebookTranslator) CheckPromptSize(code, sourceLang, targetLang string) error {
+	nb, err := parseNotebook(code)
+	if err != nil {
+		return err
+	}
+	if sourceLang == "" {
+		sourceLang = nb.language()
+	}
+	for _, i := range nb.codeCells() {
+		if err := t.service.checkPrompt(t.service.preparePrompt(promptInput{code: cellSource(nb.cells[i]), source: sourceLang, target: targetLang})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Translate translates each code cell, sending a cell chunk with the translated
+// source as it completes, then a notebook chunk with the rebuilt notebook JSON.
+// The term map is enforced and checked per cell, for the terms the cell uses.
+func (t *NotebookTranslator) Translate(ctx context.Context, req types.TranslateRequest, onChunk func(string) error) error {
+	nb, err := parseNotebook(req.Code)
+	if err != nil {
+		return err
+	}
+
+	sourceLang := req.SourceLanguage
+	if sourceLang == "" {
+		sourceLang = nb.language()
+	}
+
+	provider, opts, err := t.service.completionSetup(req, onChunk)
+	if err != nil {
+		return err
+	}
+
+	codeCells := nb.codeCells()
+	t.service.logger.Info("translating notebook",
+		zap.String("source_language", sourceLang),
+		zap.String("target_language", req.TargetLanguage),
+		zap.Int("code_cells", len(codeCells)),
+	)
+
+	for n, i := range codeCells {
+		source := cellSource(nb.cells[i])
+		prompt := t.service.preparePrompt(promptInput{
+			code:         source,
+			source:       sourceLang,
+			target:       req.TargetLanguage,
+			framework:    req.Framework,
+			instructions: req.Instructions,
+			termMap:      req.TermMap,
+			codeOnly:     req.CodeOnly,
+		})
+		if err := t.service.checkPrompt(prompt); err != nil {
+			return fmt.Errorf("cell %d: %w", i, err)
+		}
+
+		var response strings.Builder
+		err := provider.StreamCompletion(ctx, prompt, opts, func(chunk string) error {
+			response.WriteString(chunk)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("cell %d: %w", i, err)
+		}
+
+		translated := t.service.finalCode(req, response.String())
+		if translated == "" {
+			return fmt.Errorf("cell %d: response has no translated code section", i)
+		}
+		setCodeCell(nb.cells[i], translated)
+
+		jsonData, _ := json.Marshal(StreamChunk{
+			Type:     ChunkTypeCell,
+			Content:  translated,
+			Progress: &CellProgress{Cell: i, Done: n + 1, Total: len(codeCells)},
+		})
+		if err := onChunk(string(jsonData)); err != nil {
+			return err
+		}
+
+		// a cell only has to contain the target terms of the source terms it uses
+		if missing := missingTerms(translated, termsUsedIn(source, req.TermMap)); len(missing) > 0 {
+			message := fmt.Sprintf("%s: cell %d: the translated code does not contain %s", WarningTermMissing, i, strings.Join(missing, ", "))
+			if err := sendChunk(onChunk, ChunkTypeWarning, message, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	nb.setLanguage(strings.ToLower(req.TargetLanguage))
+	rebuilt, err := nb.marshal()
+	if err != nil {
+		return fmt.Errorf("failed to rebuild notebook: %w", err)
+	}
+	return sendChunk(onChunk, ChunkTypeNotebook, rebuilt, false)
+}
@@ -0,0 +1,67 @@
+package code_translator
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// termNotebook has a cell using the mapped term fetch_data and one that doesn't
+const termNotebook = `{"nbformat": 4, "nbformat_minor": 5, "metadata": {}, "cells": [
+	{"cell_type": "code", "source": ["rows = fetch_data()\n"], "metadata": {}, "outputs": []},
+	{"cell_type": "code", "source": ["print(1)\n"], "metadata": {}, "outputs": []}
+]}`
+
+func TestNotebookAppliesTermMap(t *testing.T) {
+	// the model ignores the term map in every cell
+	provider := &scriptedProvider{response: "=== EXPLANATION ===\nCalls it.\n=== TRANSLATION NOTES ===\n- none\n=== TRANSLATED CODE ===\nrows := fetch_data()"}
+	service := NewCodeTranslatorService(zap.NewNop(), provider, nil, nil, types.TranslatorConfig{})
+	req := types.TranslateRequest{
+		Code:           termNotebook,
+		SourceLanguage: "python",
+		TargetLanguage: "go",
+		TermMap:        map[string]string{"fetch_data": "FetchData"},
+	}
+
+	tests := []struct {
+		name     string
+		enforce  bool
+		code     string
+		warnings []string
+	}{
+		{"checked", false, "rows := fetch_data()", []string{"term_missing: cell 0: the translated code does not contain FetchData"}},
+		{"enforced", true, "rows := FetchData()", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req.EnforceTermMap = tt.enforce
+			var cells, warnings []string
+			err := service.TranslateCode(context.Background(), req, func(raw string) error {
+				var chunk StreamChunk
+				if err := json.Unmarshal([]byte(raw), &chunk); err != nil {
+					t.Fatal(err)
+				}
+				switch chunk.Type {
+				case ChunkTypeCell:
+					cells = append(cells, chunk.Content)
+				case ChunkTypeWarning:
+					warnings = append(warnings, chunk.Content)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("TranslateCode: %v", err)
+			}
+			if len(cells) != 2 || cells[0] != tt.code {
+				t.Errorf("cells = %q, want the first to be %q", cells, tt.code)
+			}
+			if strings.Join(warnings, "|") != strings.Join(tt.warnings, "|") {
+				t.Errorf("warnings = %q, want %q", warnings, tt.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package code_translator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// bulletPattern matches "-", "*", "•" and numbered ("1." / "1)") list markers
+var bulletPattern = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s+`)
+
+// parseNotes splits the translation notes section into individual list items.
+// Lines without a bullet marker are treated as a continuation of the previous item,
+// or as an item of their own if no bullet has been seen yet.
+func parseNotes(text string) []string {
+	var items []string
+	continuing := false
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continuing = false
+			continue
+		}
+
+		if loc := bulletPattern.FindStringIndex(line); loc != nil {
+			items = append(items, strings.TrimSpace(line[loc[1]:]))
+			continuing = true
+			continue
+		}
+
+		if continuing && len(items) > 0 {
+			items[len(items)-1] += " " + trimmed
+			continue
+		}
+		items = append(items, trimmed)
+		continuing = true
+	}
+
+	return items
+}
@@ -0,0 +1,151 @@
+package code_translator
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Response sections, in the order the prompt asks for them. Dependencies are only asked
+// for on request, and come before the code since the code section runs to the end.
+const (
+	sectionExplanation  = "explanation"
+	sectionNotes        = "notes"
+	sectionDependencies = "dependencies"
+	sectionCode         = "code"
+)
+
+var sectionOrder = []string{sectionExplanation, sectionNotes, sectionDependencies, sectionCode}
+
+// defaultHeadingSynonyms are the headings recognized for each section. The === form is
+// what the prompt asks for and is matched anywhere; the others are variants some
+// models produce instead and only match as a line of their own. Matching ignores case.
+// Dependencies has no "Dependencies:" form since docstrings in translated code often have one.
+var defaultHeadingSynonyms = map[string][]string{
+	sectionExplanation:  {"=== EXPLANATION ===", "## Explanation", "### Explanation", "**Explanation**", "Explanation:"},
+	sectionNotes:        {"=== TRANSLATION NOTES ===", "## Translation Notes", "### Translation Notes", "**Translation Notes**", "Translation Notes:"},
+	sectionDependencies: {"=== DEPENDENCIES ===", "## Dependencies", "### Dependencies", "**Dependencies**"},
+	sectionCode:         {"=== TRANSLATED CODE ===", "## Translated Code", "### Translated Code", "**Translated Code**", "Translated Code:"},
+}
+
+// sectionHeading returns the heading the prompt asks for, for building responses locally
+func sectionHeading(section string) string {
+	return defaultHeadingSynonyms[section][0]
+}
+
+// sectionHeadings finds section headings in a provider response
+type sectionHeadings struct {
+	patterns map[string]*regexp.Regexp
+	// stripArtifacts removes echoed prompt lines from extracted sections
+	stripArtifacts bool
+}
+
+// newSectionHeadings compiles the default heading synonyms plus extra ones per section
+func newSectionHeadings(extra map[string][]string, stripArtifacts bool) *sectionHeadings {
+	h := &sectionHeadings{
+		patterns:       make(map[string]*regexp.Regexp, len(sectionOrder)),
+		stripArtifacts: stripArtifacts,
+	}
+	for _, section := range sectionOrder {
+		synonyms := append(append([]string(nil), defaultHeadingSynonyms[section]...), extra[section]...)
+		alternatives := make([]string, 0, len(synonyms))
+		for _, synonym := range synonyms {
+			synonym = strings.TrimSpace(synonym)
+			if synonym == "" {
+				continue
+			}
+			if strings.HasPrefix(synonym, "===") {
+				alternatives = append(alternatives, regexp.QuoteMeta(synonym))
+			} else {
+				alternatives = append(alternatives, `(?m:^[ \t]*`+regexp.QuoteMeta(synonym)+`[ \t]*$)`)
+			}
+		}
+		h.patterns[section] = regexp.MustCompile(`(?i)` + strings.Join(alternatives, "|"))
+	}
+	return h
+}
+
+// first returns the start and end of the first heading of section at or after offset, or -1, -1
+func (h *sectionHeadings) first(text, section string, offset int) (int, int) {
+	loc := h.patterns[section].FindStringIndex(text[offset:])
+	if loc == nil {
+		return -1, -1
+	}
+	return offset + loc[0], offset + loc[1]
+}
+
+// last returns the start of the last heading of section, or -1
+func (h *sectionHeadings) last(text, section string) int {
+	matches := h.patterns[section].FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return -1
+	}
+	return matches[len(matches)-1][0]
+}
+
+// has reports whether text contains a heading for section
+func (h *sectionHeadings) has(text, section string) bool {
+	return h.patterns[section].MatchString(text)
+}
+
+// contentStart returns where the content of section begins, or -1 if its heading hasn't appeared
+func (h *sectionHeadings) contentStart(text, section string) int {
+	_, end := h.first(text, section, 0)
+	return end
+}
+
+// detectCurrentSection returns the section whose heading appeared last, or "" before any heading
+func (h *sectionHeadings) detectCurrentSection(text string) string {
+	current, currentAt := "", -1
+	for _, section := range sectionOrder {
+		if at := h.last(text, section); at > currentAt {
+			current, currentAt = section, at
+		}
+	}
+	return current
+}
+
+// extractSectionContent returns the trimmed content of section: from its heading to
+// the heading of any later section, or to the end of the text for the code section.
+// Echoed prompt lines are dropped when stripArtifacts is set.
+func (h *sectionHeadings) extractSectionContent(text, section string) string {
+	start := h.contentStart(text, section)
+	if start == -1 {
+		return ""
+	}
+
+	end := len(text)
+	later := false
+	for _, next := range sectionOrder {
+		if later {
+			if at, _ := h.first(text, next, start); at != -1 && at < end {
+				end = at
+			}
+		}
+		later = later || next == section
+	}
+	content := strings.TrimSpace(text[start:end])
+	if h.stripArtifacts {
+		content = stripPromptArtifacts(content)
+	}
+	if section != sectionCode {
+		return content
+	}
+
+	// Remove markdown code fences
+	content = strings.TrimPrefix(content, "```javascript")
+	content = strings.TrimPrefix(content, "```typescript")
+	content = strings.TrimPrefix(content, "```python")
+	content = strings.TrimPrefix(content, "```go")
+	content = strings.TrimPrefix(content, "```rust")
+	content = strings.TrimPrefix(content, "```java")
+	content = strings.TrimPrefix(content, "```csharp")
+	content = strings.TrimPrefix(content, "```cpp")
+	content = strings.TrimPrefix(content, "```php")
+	content = strings.TrimPrefix(content, "```ruby")
+	content = strings.TrimPrefix(content, "```swift")
+	content = strings.TrimPrefix(content, "```kotlin")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	return strings.TrimSpace(content)
+}
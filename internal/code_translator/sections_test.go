@@ -0,0 +1,74 @@
+package code_translator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSectionHeadingSynonyms(t *testing.T) {
+	// extras as SECTION_HEADING_SYNONYMS=notes=## Caveats|Caveats:,code==== CODE ===|Converted Code:
+	extra := map[string][]string{
+		sectionNotes: {"## Caveats", "Caveats:"},
+		sectionCode:  {"=== CODE ===", "Converted Code:"},
+	}
+	headings := newSectionHeadings(extra, false)
+
+	type synonymCase struct {
+		section, heading string
+	}
+	var cases []synonymCase
+	for _, section := range sectionOrder {
+		for _, heading := range defaultHeadingSynonyms[section] {
+			cases = append(cases, synonymCase{section, heading})
+		}
+		for _, heading := range extra[section] {
+			cases = append(cases, synonymCase{section, heading})
+		}
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.section+"/"+tc.heading, func(t *testing.T) {
+			for _, heading := range []string{tc.heading, strings.ToLower(tc.heading), "  " + tc.heading + " "} {
+				text := "Intro\n" + heading + "\nsection body\n"
+				if got := headings.detectCurrentSection(text); got != tc.section {
+					t.Errorf("detectCurrentSection(%q) = %q, want %q", heading, got, tc.section)
+				}
+				if got := headings.extractSectionContent(text, tc.section); got != "section body" {
+					t.Errorf("extractSectionContent after %q = %q, want %q", heading, got, "section body")
+				}
+			}
+
+			// only the === form is recognized inside a line
+			inline := "the model wrote " + tc.heading + " mid-sentence"
+			if want := strings.HasPrefix(tc.heading, "==="); headings.has(inline, tc.section) != want {
+				t.Errorf("has(%q) = %v, want %v", inline, !want, want)
+			}
+		})
+	}
+}
+
+func TestSectionHeadingExtrasDoNotLeakIntoDefaults(t *testing.T) {
+	headings := newSectionHeadings(nil, false)
+
+	for _, heading := range []string{"## Caveats", "Caveats:", "=== CODE ===", "Converted Code:"} {
+		if got := headings.detectCurrentSection(heading + "\nbody"); got != "" {
+			t.Errorf("default headings recognized %q as %q", heading, got)
+		}
+	}
+}
+
+func TestExtractSectionContentStopsAtLaterSynonym(t *testing.T) {
+	headings := newSectionHeadings(map[string][]string{sectionNotes: {"Caveats:"}}, false)
+	text := "## Explanation\nIt adds numbers.\nCaveats:\n- none\n**Translated Code**\n```go\nfunc add() {}\n```"
+
+	want := map[string]string{
+		sectionExplanation: "It adds numbers.",
+		sectionNotes:       "- none",
+		sectionCode:        "func add() {}",
+	}
+	for section, content := range want {
+		if got := headings.extractSectionContent(text, section); got != content {
+			t.Errorf("%s = %q, want %q", section, got, content)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package code_translator
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// TranslationStats summarizes the size of a translation for analytics
+type TranslationStats struct {
+	SourceLines    int     `json:"source_lines"`
+	TargetLines    int     `json:"target_lines"`
+	ExpansionRatio float64 `json:"expansion_ratio"`
+	Constructs     int     `json:"constructs"`
+}
+
+// constructPattern matches keywords that open a definition, branch, loop or error-handling block
+// in the languages the service commonly targets
+var constructPattern = regexp.MustCompile(`\b(func|function|def|fn|fun|class|struct|interface|enum|trait|impl|if|for|foreach|while|switch|match|when|try)\b`)
+
+// computeStats derives line counts and a construct count from the source and translated code
+func computeStats(source, target string) TranslationStats {
+	stats := TranslationStats{
+		SourceLines: countLines(source),
+		TargetLines: countLines(target),
+		Constructs:  len(constructPattern.FindAllStringIndex(target, -1)),
+	}
+	if stats.SourceLines > 0 {
+		ratio := float64(stats.TargetLines) / float64(stats.SourceLines)
+		stats.ExpansionRatio = math.Round(ratio*100) / 100
+	}
+	return stats
+}
+
+func countLines(text string) int {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return 0
+	}
+	return strings.Count(text, "\n") + 1
+}
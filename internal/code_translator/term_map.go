@@ -0,0 +1,119 @@
+package code_translator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxTermMapEntries bounds the number of forced term mappings per request
+	MaxTermMapEntries = 50
+	// MaxTermLength bounds the length of a single source or target term
+	MaxTermLength = 100
+)
+
+// WarningTermMissing prefixes the warning sent when a forced term is absent from the translated code
+const WarningTermMissing = "term_missing"
+
+// ValidateTermMap checks the size of a term map and that every term is a single non-empty line
+func ValidateTermMap(termMap map[string]string) error {
+	if len(termMap) > MaxTermMapEntries {
+		return fmt.Errorf("term_map may have at most %d entries", MaxTermMapEntries)
+	}
+	for source, target := range termMap {
+		for _, term := range []string{source, target} {
+			if strings.TrimSpace(term) == "" {
+				return fmt.Errorf("term_map terms must not be empty")
+			}
+			if len(term) > MaxTermLength {
+				return fmt.Errorf("term_map term %q exceeds %d characters", term, MaxTermLength)
+			}
+			if strings.ContainsAny(term, "\r\n") {
+				return fmt.Errorf("term_map term %q must be a single line", term)
+			}
+		}
+	}
+	return nil
+}
+
+// sortedTerms returns the source terms of termMap in a stable order, longest first so
+// that enforcing a term never rewrites part of a longer one that was already mapped
+func sortedTerms(termMap map[string]string) []string {
+	terms := make([]string, 0, len(termMap))
+	for source := range termMap {
+		terms = append(terms, source)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i]) != len(terms[j]) {
+			return len(terms[i]) > len(terms[j])
+		}
+		return terms[i] < terms[j]
+	})
+	return terms
+}
+
+// formatTermMap renders the forced term mappings as hard constraints, or nothing when there are none
+func formatTermMap(termMap map[string]string) string {
+	if len(termMap) == 0 {
+		return ""
+	}
+	b := strings.Builder{}
+	b.WriteString("REQUIRED TERM MAPPINGS (hard constraints): wherever the source uses the term on the left, the translated code MUST use exactly the term on the right, with the same spelling and casing:\n")
+	for _, source := range sortedTerms(termMap) {
+		b.WriteString(fmt.Sprintf("- %s -> %s\n", source, termMap[source]))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// termPattern matches term as a whole word, or anywhere if it doesn't start and end with a word character
+func termPattern(term string) *regexp.Regexp {
+	pattern := regexp.QuoteMeta(term)
+	if isWordByte(term[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordByte(term[len(term)-1]) {
+		pattern += `\b`
+	}
+	return regexp.MustCompile(pattern)
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// enforceTermMap replaces whole-word occurrences of each source term left in code with its target term
+func enforceTermMap(code string, termMap map[string]string) string {
+	for _, source := range sortedTerms(termMap) {
+		if target := termMap[source]; target != source {
+			code = termPattern(source).ReplaceAllLiteralString(code, target)
+		}
+	}
+	return code
+}
+
+// termsUsedIn returns the entries of termMap whose source term appears in code
+func termsUsedIn(code string, termMap map[string]string) map[string]string {
+	used := make(map[string]string, len(termMap))
+	for source, target := range termMap {
+		if termPattern(source).MatchString(code) {
+			used[source] = target
+		}
+	}
+	return used
+}
+
+// missingTerms returns the target terms that don't appear in code, sorted
+func missingTerms(code string, termMap map[string]string) []string {
+	var missing []string
+	for _, source := range sortedTerms(termMap) {
+		target := termMap[source]
+		if !termPattern(target).MatchString(code) {
+			missing = append(missing, target)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,80 @@
+package code_translator
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxStopSequences matches the lowest limit among supported providers (OpenAI allows 4)
+	MaxStopSequences = 4
+	// MaxStopSequenceLength bounds the length of a single stop sequence
+	MaxStopSequenceLength = 64
+	// MaxInstructionsLength bounds the free-form instructions added to the prompt
+	MaxInstructionsLength = 2000
+	// MaxFrameworkLength bounds the framework name
+	MaxFrameworkLength = 64
+)
+
+// formatMarkers are the strings the response parser depends on, each starting a line; a stop
+// sequence that overlaps any of them would end generation before all sections are written
+var formatMarkers = []string{
+	"=== explanation ===",
+	"=== translation notes ===",
+	"=== dependencies ===",
+	"=== translated code ===",
+	"```",
+}
+
+// ValidateGuidance checks the optional framework and instructions of a request
+func ValidateGuidance(framework, instructions string) error {
+	if len(framework) > MaxFrameworkLength {
+		return fmt.Errorf("framework exceeds %d characters", MaxFrameworkLength)
+	}
+	if strings.ContainsAny(framework, "\r\n") {
+		return fmt.Errorf("framework must be a single line")
+	}
+	if len(instructions) > MaxInstructionsLength {
+		return fmt.Errorf("instructions exceed %d characters", MaxInstructionsLength)
+	}
+	return nil
+}
+
+// ValidateStopSequences checks the count and length of stop sequences and rejects any
+// that would fire on the section headers or code fence required by the response format
+func ValidateStopSequences(stops []string) error {
+	if len(stops) > MaxStopSequences {
+		return fmt.Errorf("at most %d stop sequences are allowed", MaxStopSequences)
+	}
+	for _, stop := range stops {
+		if strings.TrimSpace(stop) == "" {
+			return fmt.Errorf("stop sequences must not be empty")
+		}
+		if len(stop) > MaxStopSequenceLength {
+			return fmt.Errorf("stop sequence %q exceeds %d characters", stop, MaxStopSequenceLength)
+		}
+		lower := strings.ToLower(stop)
+		for _, marker := range formatMarkers {
+			if overlapsMarker(lower, marker) {
+				return fmt.Errorf("stop sequence %q would truncate the required section format", stop)
+			}
+		}
+	}
+	return nil
+}
+
+// overlapsMarker reports whether stop could match text that includes part of marker:
+// whether, placed somewhere over the newline the marker follows and the marker itself,
+// stop agrees with every character it covers. Characters of stop beyond either end can
+// match whatever the model writes there, so "\n===", "===\n" and "```go" all overlap.
+func overlapsMarker(stop, marker string) bool {
+	line := "\n" + marker
+	// offset is where stop starts relative to line; the overlap must reach past the newline
+	for offset := 2 - len(stop); offset < len(line); offset++ {
+		start, end := max(offset, 0), min(offset+len(stop), len(line))
+		if line[start:end] == stop[start-offset:end-offset] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package code_translator
+
+import "testing"
+
+func TestValidateStopSequencesRejectsMarkerOverlaps(t *testing.T) {
+	tests := []struct {
+		stop  string
+		valid bool
+	}{
+		{"<|end|>", true},
+		{"STOP", true},
+		{"x =", true},
+		{"END\n", true},
+		{"EXPLANATION", false},
+		{"===", false},
+		{"\n===", false},
+		{"===\n", false},
+		{"notes ===\n", false},
+		{"\n=== TRANSLATED", false},
+		{"DEPENDENCIES", false},
+		{"=== DEPENDENCIES ===\n", false},
+		{"}\n=== EXPLANATION ===", false},
+		{"=== TRANSLATED CODE ===\npackage", false},
+		{"\n```", false},
+		{"```go", false},
+		{"return x\n```", false},
+		{"`\n", false},
+	}
+	for _, tt := range tests {
+		err := ValidateStopSequences([]string{tt.stop})
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateStopSequences(%q) = %v, want valid %v", tt.stop, err, tt.valid)
+		}
+	}
+}
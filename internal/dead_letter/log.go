@@ -0,0 +1,32 @@
+package dead_letter
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// LogStore writes failed translations as structured log entries, for deployments
+// that collect logs rather than keep them in the database
+type LogStore struct {
+	logger *zap.Logger
+}
+
+// NewLogStore creates a LogStore writing to logger
+func NewLogStore(logger *zap.Logger) *LogStore {
+	return &LogStore{logger: logger.Named("dead_letter")}
+}
+
+// Record logs the failure together with the request needed to replay it
+func (s *LogStore) Record(_ context.Context, failure types.FailedTranslation) error {
+	s.logger.Warn("failed translation",
+		zap.String("id", failure.JobID),
+		zap.String("client", failure.Client),
+		zap.String("error_code", failure.ErrorCode),
+		zap.String("error", failure.Error),
+		zap.Time("failed_at", failure.FailedAt),
+		zap.Any("request", failure.Request),
+	)
+	return nil
+}
@@ -0,0 +1,62 @@
+package dead_letter
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// failedTranslation is one row of the failed_translations table
+type failedTranslation struct {
+	bun.BaseModel `bun:"table:failed_translations"`
+
+	ID        int64                  `bun:"id,pk,autoincrement"`
+	JobID     string                 `bun:"job_id,notnull"`
+	Client    string                 `bun:"client,notnull"`
+	ErrorCode string                 `bun:"error_code,notnull"`
+	Error     string                 `bun:"error,notnull"`
+	Request   types.TranslateRequest `bun:"request,type:jsonb,notnull"`
+	FailedAt  time.Time              `bun:"failed_at,notnull,default:current_timestamp"`
+}
+
+// PostgresStore records failed translations in the failed_translations table
+type PostgresStore struct {
+	db *bun.DB
+}
+
+// NewPostgresStore creates the failed_translations table if needed
+func NewPostgresStore(ctx context.Context, db *bun.DB) (*PostgresStore, error) {
+	if _, err := db.NewCreateTable().Model((*failedTranslation)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create failed_translations table: %w", err)
+	}
+	// failures are usually aggregated by reason over a time window
+	_, err := db.NewCreateIndex().
+		Model((*failedTranslation)(nil)).
+		Index("failed_translations_error_code_idx").
+		Column("error_code", "failed_at").
+		IfNotExists().
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failed_translations index: %w", err)
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Record inserts a failed translation
+func (s *PostgresStore) Record(ctx context.Context, failure types.FailedTranslation) error {
+	row := failedTranslation{
+		JobID:     failure.JobID,
+		Client:    failure.Client,
+		ErrorCode: failure.ErrorCode,
+		Error:     failure.Error,
+		Request:   failure.Request,
+		FailedAt:  failure.FailedAt,
+	}
+	if _, err := s.db.NewInsert().Model(&row).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record failed translation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package dead_letter
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+)
+
+// Sink names accepted by DEAD_LETTER_SINK
+const (
+	SinkPostgres = "postgres"
+	SinkLog      = "log"
+	SinkOff      = "off"
+)
+
+// Store records failed translations for later analysis and replay
+type Store interface {
+	Record(ctx context.Context, failure types.FailedTranslation) error
+}
+
+// ParseSink validates a DEAD_LETTER_SINK value
+func ParseSink(name string) (string, error) {
+	switch name {
+	case SinkPostgres, SinkLog, SinkOff:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unsupported dead letter sink: %s", name)
+	}
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import "time"
+
+// durationBuckets are the upper bounds, in seconds, of the translation duration histogram.
+// Translations are cut off after a few minutes, so longer buckets would stay empty.
+var durationBuckets = []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 180}
+
+// Metrics are the translation metrics exported at GET /metrics. A nil *Metrics records
+// nothing, so components can be created without them.
+type Metrics struct {
+	*Registry
+	requests     *Counter
+	duration     *Histogram
+	streamErrors *Counter
+}
+
+// New creates a registry with the translation metrics registered
+func New() *Metrics {
+	registry := NewRegistry()
+	return &Metrics{
+		Registry: registry,
+		requests: registry.NewCounter("codebridge_translation_requests_total",
+			"Translation jobs accepted, by provider and target language.", "provider", "target_language"),
+		duration: registry.NewHistogram("codebridge_translation_duration_seconds",
+			"Time from accepting a translation job to its end, including time queued.", durationBuckets, "provider", "status"),
+		streamErrors: registry.NewCounter("codebridge_provider_stream_errors_total",
+			"Provider completion streams that failed, after retries and streaming fallback.", "provider"),
+	}
+}
+
+// TranslationRequested counts an accepted translation job
+func (m *Metrics) TranslationRequested(provider, targetLanguage string) {
+	if m == nil {
+		return
+	}
+	m.requests.Inc(provider, targetLanguage)
+}
+
+// TranslationFinished records how long a translation job took, labelled by whether it failed
+func (m *Metrics) TranslationFinished(provider string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	m.duration.Observe(duration.Seconds(), provider, status)
+}
+
+// StreamError counts a failed completion stream of provider
+func (m *Metrics) StreamError(provider string) {
+	if m == nil {
+		return
+	}
+	m.streamErrors.Inc(provider)
+}
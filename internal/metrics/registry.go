@@ -0,0 +1,286 @@
+// Package metrics exports counters, gauges and histograms in the Prometheus text
+// exposition format. Callers choose the label values, and are expected to map
+// them onto a fixed set so every metric has a bounded number of series.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metric is a registered metric family
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds metrics and writes them out in the order they were registered
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every registered metric to w
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	buffered := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(buffered)
+	}
+	return buffered.Flush()
+}
+
+// NewCounter registers a counter with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	counter := &Counter{family: family{name: name, help: help, labels: labels}, values: make(map[string]*counterSeries)}
+	r.register(counter)
+	return counter
+}
+
+// NewHistogram registers a histogram with the given upper bucket bounds, in increasing order,
+// and label names
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{family: family{name: name, help: help, labels: labels}, buckets: buckets, values: make(map[string]*histogramSeries)}
+	r.register(histogram)
+	return histogram
+}
+
+// NewGaugeFunc registers a gauge without labels whose value is read from value on every write
+func (r *Registry) NewGaugeFunc(name, help string, value func() float64) {
+	r.register(&valueFunc{family: family{name: name, help: help}, kind: "gauge", value: value})
+}
+
+// NewCounterFunc registers a counter without labels whose value is read from value on every write
+func (r *Registry) NewCounterFunc(name, help string, value func() float64) {
+	r.register(&valueFunc{family: family{name: name, help: help}, kind: "counter", value: value})
+}
+
+// NewGroup registers a group of value functions that are read together: on every write
+// snapshot runs once, then the group's metrics are written, so all of them can report
+// the same snapshot, taken once per write
+func (r *Registry) NewGroup(snapshot func()) *Group {
+	group := &Group{snapshot: snapshot}
+	r.register(group)
+	return group
+}
+
+// Group holds value functions reading a snapshot shared by the group; see NewGroup
+type Group struct {
+	mu       sync.Mutex
+	snapshot func()
+	metrics  []*valueFunc
+}
+
+// NewGaugeFunc adds a gauge without labels whose value is read from value after the group's snapshot
+func (g *Group) NewGaugeFunc(name, help string, value func() float64) {
+	g.add(&valueFunc{family: family{name: name, help: help}, kind: "gauge", value: value})
+}
+
+// NewCounterFunc adds a counter without labels whose value is read from value after the group's snapshot
+func (g *Group) NewCounterFunc(name, help string, value func() float64) {
+	g.add(&valueFunc{family: family{name: name, help: help}, kind: "counter", value: value})
+}
+
+func (g *Group) add(v *valueFunc) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.metrics = append(g.metrics, v)
+}
+
+// write holds the group's lock throughout, so concurrent writes don't replace the
+// snapshot while the values are read from it
+func (g *Group) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.snapshot()
+	for _, v := range g.metrics {
+		v.write(w)
+	}
+}
+
+// family is the name, help text and label names shared by the series of a metric
+type family struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (f family) writeHeader(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, kind)
+}
+
+// key identifies the series with values; it panics if values don't match the label names
+func (f family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", f.name, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelPairs formats the labels of a series, with extra appended, as {a="x",b="y"}
+func (f family) labelPairs(values []string, extra ...string) string {
+	if len(values) == 0 && len(extra) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, value := range values {
+		pairs = append(pairs, f.labels[i]+`="`+escapeLabel(value)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escapeLabel(extra[i+1])+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter is a monotonically increasing value per label combination
+type Counter struct {
+	family
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labels []string
+	value  float64
+}
+
+// Inc adds one to the series with labels
+func (c *Counter) Inc(labels ...string) {
+	key := c.key(labels)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	series, ok := c.values[key]
+	if !ok {
+		series = &counterSeries{labels: labels}
+		c.values[key] = series
+	}
+	series.value++
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.writeHeader(w, "counter")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		series := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(series.labels), formatValue(series.value))
+	}
+}
+
+// Histogram counts observations into buckets per label combination
+type Histogram struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labels []string
+	// counts[i] is the number of observations in bucket i alone; they are summed on write
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe records value in the series with labels
+func (h *Histogram) Observe(value float64, labels ...string) {
+	key := h.key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = series
+	}
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		series.counts[i]++
+	}
+	series.count++
+	series.sum += value
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.writeHeader(w, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		series := h.values[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(series.labels, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(series.labels, "le", "+Inf"), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(series.labels), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(series.labels), series.count)
+	}
+}
+
+// valueFunc is a gauge or counter read from a function, for values another component already tracks
+type valueFunc struct {
+	family
+	kind  string
+	value func() float64
+}
+
+func (v *valueFunc) write(w *bufio.Writer) {
+	v.writeHeader(w, v.kind)
+	fmt.Fprintf(w, "%s %s\n", v.name, formatValue(v.value()))
+}
+
+func sortedKeys[T any](values map[string]T) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
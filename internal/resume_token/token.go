@@ -0,0 +1,88 @@
+package resume_token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned for malformed tokens, bad signatures and tokens issued for another job
+	ErrInvalidToken = errors.New("invalid resume token")
+	// ErrTokenExpired is returned for correctly signed tokens past their expiry
+	ErrTokenExpired = errors.New("resume token expired")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Signer issues and verifies HMAC-signed tokens that grant access to a job's stream
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSigner creates a signer. An empty secret is replaced by a random one, so
+// tokens stop verifying after a restart (the in-memory streams are gone by then anyway).
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		// crypto/rand.Read never returns an error since Go 1.24
+		_, _ = rand.Read(key)
+	}
+	return &Signer{secret: key, ttl: ttl}
+}
+
+// Issue returns a token for jobID and the time it expires.
+// The token is base64url(jobID "|" unix expiry) "." base64url(HMAC-SHA256).
+func (s *Signer) Issue(jobID string) (string, time.Time) {
+	expiresAt := time.Now().Add(s.ttl).Truncate(time.Second)
+	payload := jobID + "|" + strconv.FormatInt(expiresAt.Unix(), 10)
+	return encoding.EncodeToString([]byte(payload)) + "." + encoding.EncodeToString(s.sign(payload)), expiresAt
+}
+
+// Verify checks that token was issued by this signer for jobID and has not expired
+func (s *Signer) Verify(token, jobID string) error {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidToken
+	}
+	payloadBytes, err := encoding.DecodeString(encodedPayload)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	mac, err := encoding.DecodeString(encodedMAC)
+	if err != nil {
+		return ErrInvalidToken
+	}
+
+	payload := string(payloadBytes)
+	if !hmac.Equal(mac, s.sign(payload)) {
+		return ErrInvalidToken
+	}
+
+	// job ids never contain '|', so the last separator splits off the expiry
+	idx := strings.LastIndex(payload, "|")
+	if idx < 0 || payload[:idx] != jobID {
+		return ErrInvalidToken
+	}
+	expiry, err := strconv.ParseInt(payload[idx+1:], 10, 64)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if time.Now().After(time.Unix(expiry, 0)) {
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+func (s *Signer) sign(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -1,15 +1,59 @@
 package services
 
-import "code-bridge/internal/code_translator"
+import (
+	"code-bridge/internal/chunked_job"
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/dead_letter"
+	"code-bridge/internal/metrics"
+	"code-bridge/internal/source_fetcher"
+	"code-bridge/internal/translation_history"
+	"code-bridge/internal/translator_provider"
+	"code-bridge/internal/worker_pool"
+)
 
 // Services holds all application services
 type Services struct {
 	CodeTranslatorService *code_translator.CodeTranslatorService
+	SourceFetcher         *source_fetcher.Fetcher
+	WorkerPool            *worker_pool.Pool
+	// DeadLetter records failed translations; nil when DEAD_LETTER_SINK=off
+	DeadLetter dead_letter.Store
+	// ChunkedJobs persists chunked translations so they can be resumed
+	ChunkedJobs chunked_job.Store
+	// History keeps finished translations for GET /translate/history and reruns
+	History translation_history.Store
+	// ProviderSelector picks a provider for requests without one; nil when PROVIDER_STRATEGY is unset
+	ProviderSelector *translator_provider.ProviderSelector
+	// Metrics are exported at GET /metrics
+	Metrics *metrics.Metrics
+}
+
+// Deps are the dependencies NewServices wires together. New services are added
+// here as fields, so callers only set what they have and the signature stays stable.
+type Deps struct {
+	CodeTranslatorService *code_translator.CodeTranslatorService
+	SourceFetcher         *source_fetcher.Fetcher
+	WorkerPool            *worker_pool.Pool
+	// DeadLetter is optional
+	DeadLetter  dead_letter.Store
+	ChunkedJobs chunked_job.Store
+	History     translation_history.Store
+	// ProviderSelector is optional
+	ProviderSelector *translator_provider.ProviderSelector
+	// Metrics is optional; without it nothing is recorded and GET /metrics is not served
+	Metrics *metrics.Metrics
 }
 
 // NewServices creates and initializes all services
-func NewServices(translatorService *code_translator.CodeTranslatorService) *Services {
+func NewServices(deps Deps) *Services {
 	return &Services{
-		CodeTranslatorService: translatorService,
+		CodeTranslatorService: deps.CodeTranslatorService,
+		SourceFetcher:         deps.SourceFetcher,
+		WorkerPool:            deps.WorkerPool,
+		DeadLetter:            deps.DeadLetter,
+		ChunkedJobs:           deps.ChunkedJobs,
+		History:               deps.History,
+		ProviderSelector:      deps.ProviderSelector,
+		Metrics:               deps.Metrics,
 	}
 }
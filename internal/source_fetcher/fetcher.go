@@ -0,0 +1,146 @@
+package source_fetcher
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"unicode/utf8"
+)
+
+var (
+	// ErrURLNotAllowed is returned when the URL scheme, host or resolved address is not permitted
+	ErrURLNotAllowed = errors.New("source url not allowed")
+	// ErrSourceTooLarge is returned when the remote content exceeds the configured size limit
+	ErrSourceTooLarge = errors.New("source content too large")
+)
+
+const maxRedirects = 5
+
+// Fetcher downloads source code from an allowlisted set of hosts
+type Fetcher struct {
+	allowedHosts map[string]struct{}
+	maxBytes     int64
+	client       *http.Client
+}
+
+// NewFetcher creates a Fetcher that only reaches the configured hosts over https
+// and refuses to connect to loopback, private or link-local addresses
+func NewFetcher(cfg types.SourceFetchConfig) *Fetcher {
+	allowed := make(map[string]struct{}, len(cfg.AllowedHosts))
+	for _, host := range cfg.AllowedHosts {
+		allowed[strings.ToLower(host)] = struct{}{}
+	}
+
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s resolves to a non-public address", ErrURLNotAllowed, host)
+			}
+			return nil
+		},
+	}
+
+	f := &Fetcher{
+		allowedHosts: allowed,
+		maxBytes:     cfg.MaxBytes,
+	}
+	f.client = &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: cfg.Timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			_, err := f.validateURL(req.URL.String())
+			return err
+		},
+	}
+	return f
+}
+
+// Fetch retrieves the content at rawURL as text
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (string, error) {
+	u, err := f.validateURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build source request: %w", err)
+	}
+	req.Header.Set("Accept", "text/plain")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch source: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch source: unexpected status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > f.maxBytes {
+		return "", fmt.Errorf("%w: %d bytes exceeds limit of %d", ErrSourceTooLarge, resp.ContentLength, f.maxBytes)
+	}
+
+	// read one byte past the limit so oversized bodies without a Content-Length are detected
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read source: %w", err)
+	}
+	if int64(len(body)) > f.maxBytes {
+		return "", fmt.Errorf("%w: exceeds limit of %d bytes", ErrSourceTooLarge, f.maxBytes)
+	}
+	if !utf8.Valid(body) {
+		return "", errors.New("source content is not valid UTF-8 text")
+	}
+
+	return string(body), nil
+}
+
+// validateURL checks the scheme and host against the allowlist and strips any credentials
+func (f *Fetcher) validateURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrURLNotAllowed, err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: scheme must be https", ErrURLNotAllowed)
+	}
+	if _, ok := f.allowedHosts[strings.ToLower(u.Hostname())]; !ok {
+		return nil, fmt.Errorf("%w: host %q is not in the allowlist", ErrURLNotAllowed, u.Hostname())
+	}
+	if port := u.Port(); port != "" && port != "443" {
+		return nil, fmt.Errorf("%w: port %s is not permitted", ErrURLNotAllowed, port)
+	}
+
+	u.User = nil
+	return u, nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast()
+}
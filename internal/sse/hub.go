@@ -1,117 +1,539 @@
 package sse
 
 import (
+	"errors"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.uber.org/zap"
 )
 
+// ErrTooManyStreams is returned by Create when the hub is at capacity and no
+// finished stream can be evicted to make room
+var ErrTooManyStreams = errors.New("too many active streams")
+
+// ErrStreamNotFound is returned by AddClient for ids that were never created or have been cleaned up
+var ErrStreamNotFound = errors.New("stream not found")
+
+// ErrStreamActive is returned by Restart while id's stream has not finished
+var ErrStreamActive = errors.New("stream is still active")
+
+// ErrStreamEnded is returned by AddClient for a finished stream the client has already read to the end
+var ErrStreamEnded = errors.New("stream has ended")
+
 // Hub manages channels per job id
 type Hub struct {
-	mu    sync.RWMutex
-	chans map[string]*Stream
+	mu         sync.RWMutex
+	chans      map[string]*Stream
+	maxStreams int
+	// orphanTimeout is how long a stream may go without messages before it is failed
+	orphanTimeout time.Duration
+	// streamTTL is how long after its creation a stream is evicted, finished or not
+	streamTTL   time.Duration
+	bufferLimit BufferLimit
+	logger      *zap.Logger
+	// completed counts the streams that received [DONE] since the hub was created
+	completed atomic.Int64
+	// quit is closed by Stop; finished is closed when Run returns
+	quit     chan struct{}
+	finished chan struct{}
+	stopOnce sync.Once
+	running  atomic.Bool
+}
+
+// BufferLimit bounds the messages a stream keeps for clients that attach or reconnect late.
+// Past MaxBytes, partial updates that a later message of the same section replaces are
+// dropped, so late clients skip straight to the newest version of each section. Messages
+// that are not replaced are always kept, so a stream may stay above MaxBytes.
+type BufferLimit struct {
+	// MaxBytes is the total size of a stream's messages above which replaced updates are dropped; zero keeps everything
+	MaxBytes int
+	// Section returns the section msg belongs to, if any, and whether msg is a partial update
+	// replaced by any later message of that section
+	Section func(msg string) (section string, partial bool)
+}
+
+// Message is a published message and its id, which numbers the stream's messages from 1
+// and is sent as the SSE event id so reconnecting clients can resume after the last one they got
+type Message struct {
+	ID   int64
+	Data string
+	// section and partial are BufferLimit.Section's verdict, kept for compacting
+	section string
+	partial bool
 }
 
 // Stream holds channels and state for a translation job
 type Stream struct {
 	clients []*Client
-	buffer  []string
-	done    bool
-	mu      sync.RWMutex
+	buffer  []Message
+	// lastID is the id of the most recently published message
+	lastID int64
+	// bufferBytes is the total size of the buffered messages. compactAbove is twice what the
+	// last compaction kept; the buffer is only compacted again past it, so a buffer of messages
+	// that can't be dropped isn't scanned on every publish.
+	bufferBytes  int
+	compactAbove int
+	limit        BufferLimit
+	// completed is the hub's count of finished streams
+	completed *atomic.Int64
+	done      bool
+	owner     string
+	createdAt time.Time
+	// lastActivity is when the stream was created or last received a message
+	lastActivity time.Time
+	// everAttached records whether any client has attached; attached is closed when the first one does
+	everAttached bool
+	attached     chan struct{}
+	mu           sync.RWMutex
 }
 
-// Client holds a channel where messages for a job are pushed
+func newStream(owner string, limit BufferLimit, completed *atomic.Int64) *Stream {
+	return &Stream{
+		limit:        limit,
+		completed:    completed,
+		clients:      make([]*Client, 0),
+		buffer:       make([]Message, 0),
+		owner:        owner,
+		createdAt:    time.Now(),
+		lastActivity: time.Now(),
+		attached:     make(chan struct{}),
+	}
+}
+
+// Client reads a stream's messages from the stream's own buffer through a cursor,
+// so any number of clients share one copy of the history
 type Client struct {
-	Ch chan string
+	// Notify receives a value when messages are available from Next; it is closed by RemoveClient
+	Notify chan struct{}
+	stream *Stream
+	// delivered is the id of the last message returned by Next; ids rather than buffer
+	// positions are tracked since compacting the buffer moves messages
+	delivered int64
 }
 
-func NewHub() *Hub {
-	return &Hub{chans: make(map[string]*Stream)}
+// Next returns the messages published since the previous call, oldest first. The
+// returned slice shares the stream's buffer and must not be modified.
+func (c *Client) Next() []Message {
+	c.stream.mu.RLock()
+	defer c.stream.mu.RUnlock()
+	// the buffer is only appended to or replaced by a compacted copy, so the slice stays valid
+	// after later publishes; capping its capacity keeps callers from appending into the shared array
+	end := len(c.stream.buffer)
+	msgs := c.stream.buffer[c.stream.indexAfter(c.delivered):end:end]
+	if end > 0 {
+		c.delivered = c.stream.buffer[end-1].ID
+	}
+	return msgs
+}
+
+// notify wakes the client's reader without blocking; one pending signal covers any number of messages
+func (c *Client) notify() {
+	select {
+	case c.Notify <- struct{}{}:
+	default:
+	}
 }
 
+// orphanMessage is sent to streams whose job stopped producing output
+const orphanMessage = "ERROR: translation stopped responding"
+
+// shutdownMessage is sent to unfinished streams when the server shuts down
+const shutdownMessage = "ERROR: server is shutting down"
+
+// expiredMessage is sent to unfinished streams evicted for outliving the stream TTL
+const expiredMessage = "ERROR: stream expired"
+
+// NewHub creates a hub holding at most maxStreams streams; zero means unbounded.
+// Unfinished streams without messages for orphanTimeout are failed, and streams older
+// than streamTTL are evicted with their clients; zero disables either. Each stream's
+// buffer is bounded by bufferLimit.
+func NewHub(maxStreams int, orphanTimeout, streamTTL time.Duration, bufferLimit BufferLimit, logger *zap.Logger) *Hub {
+	return &Hub{
+		chans:         make(map[string]*Stream),
+		maxStreams:    maxStreams,
+		orphanTimeout: orphanTimeout,
+		streamTTL:     streamTTL,
+		bufferLimit:   bufferLimit,
+		logger:        logger,
+		quit:          make(chan struct{}),
+		finished:      make(chan struct{}),
+	}
+}
+
+// Run fails orphaned streams every minute and cleans up old streams periodically
+// until Stop is called; finished streams are usually dropped by RemoveClient already,
+// so cleanup mostly catches those no client attached to. It blocks, so start it in
+// its own goroutine.
 func (h *Hub) Run() {
-	// cleanup old streams periodically
-	ticker := time.NewTicker(5 * time.Minute)
-	go func() {
-		for range ticker.C {
+	h.running.Store(true)
+	defer close(h.finished)
+
+	reconcileTicker := time.NewTicker(time.Minute)
+	defer reconcileTicker.Stop()
+	cleanupTicker := time.NewTicker(5 * time.Minute)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-reconcileTicker.C:
+			h.reconcileOrphans()
+		case <-cleanupTicker.C:
 			h.cleanup()
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// Stop ends Run and waits for it to return. It is safe to call more than once,
+// and Run exits immediately if Stop was called before it started.
+func (h *Hub) Stop() {
+	h.stopOnce.Do(func() { close(h.quit) })
+	if h.running.Load() {
+		<-h.finished
+	}
+}
+
+// reconcileOrphans fails unfinished streams that have gone quiet for longer than
+// orphanTimeout, e.g. because their job died, so clients are not left waiting.
+// The regular cleanup removes them once their clients disconnect.
+func (h *Hub) reconcileOrphans() {
+	if h.orphanTimeout <= 0 {
+		return
+	}
+
+	h.mu.RLock()
+	streams := make([]*Stream, 0, len(h.chans))
+	for _, stream := range h.chans {
+		streams = append(streams, stream)
+	}
+	h.mu.RUnlock()
+
+	for _, stream := range streams {
+		stream.mu.Lock()
+		if !stream.done && time.Since(stream.lastActivity) > h.orphanTimeout {
+			stream.publish(orphanMessage)
+			stream.publish("[DONE]")
 		}
-	}()
+		stream.mu.Unlock()
+	}
 }
 
+// Shutdown ends every unfinished stream with an error and [DONE], so connected
+// clients' handlers return instead of holding up server shutdown. Messages sent
+// for those streams afterwards are dropped. It returns how many streams were ended.
+func (h *Hub) Shutdown() int {
+	h.mu.RLock()
+	streams := make([]*Stream, 0, len(h.chans))
+	for _, stream := range h.chans {
+		streams = append(streams, stream)
+	}
+	h.mu.RUnlock()
+
+	ended := 0
+	for _, stream := range streams {
+		stream.mu.Lock()
+		if !stream.done {
+			stream.publish(shutdownMessage)
+			stream.publish("[DONE]")
+			ended++
+		}
+		stream.mu.Unlock()
+	}
+	return ended
+}
+
+// cleanup drops finished streams without clients, and evicts streams older than the
+// stream TTL even if they are unfinished or have clients, e.g. because their job hangs
+// while still sending messages now and then
 func (h *Hub) cleanup() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	for id, stream := range h.chans {
-		stream.mu.RLock()
-		done := stream.done
-		clientCount := len(stream.clients)
-		stream.mu.RUnlock()
-
-		if done && clientCount == 0 {
+		stream.mu.Lock()
+		age := time.Since(stream.createdAt)
+		switch {
+		case h.streamTTL > 0 && age > h.streamTTL:
+			stream.evict()
+			delete(h.chans, id)
+			h.logger.Warn("evicted expired stream", zap.String("id", id), zap.Duration("age", age))
+		case stream.done && len(stream.clients) == 0:
 			delete(h.chans, id)
 		}
+		stream.mu.Unlock()
 	}
 }
 
-func (h *Hub) Create(id string) {
+// Create registers a stream for id owned by owner. When the hub is full it evicts the
+// oldest finished stream without clients, or returns ErrTooManyStreams if there is none.
+func (h *Hub) Create(id, owner string) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if _, ok := h.chans[id]; !ok {
-		h.chans[id] = &Stream{
-			clients: make([]*Client, 0),
-			buffer:  make([]string, 0),
-			done:    false,
-		}
+	if _, ok := h.chans[id]; ok {
+		return nil
+	}
+
+	if h.maxStreams > 0 && len(h.chans) >= h.maxStreams && !h.evictOldestDone() {
+		return ErrTooManyStreams
 	}
+
+	h.chans[id] = newStream(owner, h.bufferLimit, &h.completed)
+	return nil
 }
 
-func (h *Hub) AddClient(id string) *Client {
+// Restart replaces id's finished stream, if any, with a new empty one owned by owner,
+// so a job can run again under the same id. It returns ErrStreamActive if the stream
+// has not finished, and like Create ErrTooManyStreams when the hub is full.
+func (h *Hub) Restart(id, owner string) error {
 	h.mu.Lock()
+	defer h.mu.Unlock()
+	if stream, ok := h.chans[id]; ok {
+		stream.mu.RLock()
+		done := stream.done
+		stream.mu.RUnlock()
+		if !done {
+			return ErrStreamActive
+		}
+		delete(h.chans, id)
+	}
+
+	if h.maxStreams > 0 && len(h.chans) >= h.maxStreams && !h.evictOldestDone() {
+		return ErrTooManyStreams
+	}
+	h.chans[id] = newStream(owner, h.bufferLimit, &h.completed)
+	return nil
+}
+
+// maxStreamStats caps the streams listed in Stats, so the response stays small with many streams
+const maxStreamStats = 20
+
+// Counts are the hub's totals, cheap enough to read on every metrics scrape
+type Counts struct {
+	// Streams counts the streams held, Active those of them that have not finished
+	Streams int `json:"streams"`
+	Active  int `json:"active"`
+	Clients int `json:"clients"`
+	// BufferedMessages and BufferedBytes add up the buffers of all held streams
+	BufferedMessages int `json:"buffered_messages"`
+	BufferedBytes    int `json:"buffered_bytes"`
+	// Completed counts the streams that finished since the server started, including dropped ones
+	Completed int64 `json:"completed"`
+}
+
+// Stats is a snapshot of the hub's streams, for spotting stuck or leaking streams
+type Stats struct {
+	Counts
+	// TopStreams lists the streams with the most clients, oldest first among equals
+	TopStreams []StreamStats `json:"top_streams"`
+}
+
+// StreamStats describes a single stream
+type StreamStats struct {
+	ID               string  `json:"id"`
+	Clients          int     `json:"clients"`
+	Done             bool    `json:"done"`
+	BufferedMessages int     `json:"buffered_messages"`
+	AgeSeconds       float64 `json:"age_seconds"`
+}
+
+// Counts returns the hub's totals without listing its streams
+func (h *Hub) Counts() Counts {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.countLocked()
+}
+
+// countLocked adds up the held streams; h.mu must be held
+func (h *Hub) countLocked() Counts {
+	counts := Counts{Streams: len(h.chans), Completed: h.completed.Load()}
+	for _, stream := range h.chans {
+		stream.mu.RLock()
+		if !stream.done {
+			counts.Active++
+		}
+		counts.Clients += len(stream.clients)
+		counts.BufferedMessages += len(stream.buffer)
+		counts.BufferedBytes += stream.bufferBytes
+		stream.mu.RUnlock()
+	}
+	return counts
+}
+
+// Stats returns a snapshot of the hub's streams and clients
+func (h *Hub) Stats() Stats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := Stats{Counts: h.countLocked()}
+	streams := make([]StreamStats, 0, len(h.chans))
+	for id, stream := range h.chans {
+		stream.mu.RLock()
+		streams = append(streams, StreamStats{
+			ID:               id,
+			Clients:          len(stream.clients),
+			Done:             stream.done,
+			BufferedMessages: len(stream.buffer),
+			AgeSeconds:       time.Since(stream.createdAt).Seconds(),
+		})
+		stream.mu.RUnlock()
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		if streams[i].Clients != streams[j].Clients {
+			return streams[i].Clients > streams[j].Clients
+		}
+		return streams[i].AgeSeconds > streams[j].AgeSeconds
+	})
+	stats.TopStreams = streams[:min(len(streams), maxStreamStats)]
+	return stats
+}
+
+// Owner returns the owner recorded when id's stream was created
+func (h *Hub) Owner(id string) (string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
 	stream, ok := h.chans[id]
 	if !ok {
-		stream = &Stream{
-			clients: make([]*Client, 0),
-			buffer:  make([]string, 0),
-			done:    false,
+		return "", false
+	}
+	return stream.owner, true
+}
+
+// evictOldestDone removes the oldest finished stream with no clients; h.mu must be held
+func (h *Hub) evictOldestDone() bool {
+	var oldestID string
+	var oldest time.Time
+
+	for id, stream := range h.chans {
+		stream.mu.RLock()
+		evictable := stream.done && len(stream.clients) == 0
+		createdAt := stream.createdAt
+		stream.mu.RUnlock()
+
+		if evictable && (oldestID == "" || createdAt.Before(oldest)) {
+			oldestID, oldest = id, createdAt
 		}
-		h.chans[id] = stream
 	}
-	h.mu.Unlock()
 
-	client := &Client{Ch: make(chan string, 200)} // Larger buffer
+	if oldestID == "" {
+		return false
+	}
+	delete(h.chans, oldestID)
+	return true
+}
+
+// Remove drops a stream that will never receive messages, e.g. when its job could not be queued
+func (h *Hub) Remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.chans, id)
+}
+
+// Exists reports whether a stream was created for id and is still held by the hub
+func (h *Hub) Exists(id string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, ok := h.chans[id]
+	return ok
+}
+
+// AddClient subscribes a client to an existing stream; its first Next returns the backlog
+// after the message with id lastEventID, or all of it when lastEventID is 0 or not an id
+// of the stream. It returns ErrStreamNotFound if no stream was created for id, and
+// ErrStreamEnded if the stream has finished and has nothing after lastEventID.
+func (h *Hub) AddClient(id string, lastEventID int64) (*Client, error) {
+	// held until the client is added, so RemoveClient can't prune the stream in between
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	stream, ok := h.chans[id]
+	if !ok {
+		return nil, ErrStreamNotFound
+	}
 
 	stream.mu.Lock()
-	stream.clients = append(stream.clients, client)
+	defer stream.mu.Unlock()
 
-	// send buffered messages to new client - BLOCKING to ensure delivery
-	for _, msg := range stream.buffer {
-		client.Ch <- msg // Block instead of select/default
+	// the backlog is read from the shared buffer rather than copied, so a late joiner
+	// on a finished job costs no more than one that was there from the start
+	if lastEventID < 0 || lastEventID > stream.lastID {
+		lastEventID = 0
+	}
+	client := &Client{Notify: make(chan struct{}, 1), stream: stream, delivered: lastEventID}
+	pending := stream.indexAfter(lastEventID) < len(stream.buffer)
+	if stream.done && !pending {
+		return nil, ErrStreamEnded
+	}
+	if pending {
+		client.notify()
+	}
+	stream.clients = append(stream.clients, client)
+	if !stream.everAttached {
+		stream.everAttached = true
+		close(stream.attached)
 	}
-	stream.mu.Unlock()
 
-	return client
+	return client, nil
 }
 
-func (h *Hub) RemoveClient(id string, client *Client) {
+// Attached returns a channel that is closed once a client has attached to id's stream,
+// or false if there is no such stream
+func (h *Hub) Attached(id string) (<-chan struct{}, bool) {
 	h.mu.RLock()
+	defer h.mu.RUnlock()
 	stream, ok := h.chans[id]
-	h.mu.RUnlock()
-
 	if !ok {
-		return
+		return nil, false
 	}
+	return stream.attached, true
+}
+
+// indexAfter returns the buffer index of the first message with an id above id; stream.mu must be held
+func (stream *Stream) indexAfter(id int64) int {
+	return sort.Search(len(stream.buffer), func(i int) bool { return stream.buffer[i].ID > id })
+}
 
+// RemoveClient unsubscribes client from id's stream. A finished stream is dropped with its
+// last client rather than at the next cleanup; streams that finish before any client attaches
+// are still left to cleanup, so a client has time to read them.
+func (h *Hub) RemoveClient(id string, client *Client) {
+	// held while the stream is checked and dropped, so AddClient can't attach in between
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the client's own stream, since id may have been restarted with a new one since it attached
+	stream := client.stream
 	stream.mu.Lock()
-	for i, c := range stream.clients {
-		if c == client {
-			stream.clients = append(stream.clients[:i], stream.clients[i+1:]...)
-			break
-		}
+	defer stream.mu.Unlock()
+	i := slices.Index(stream.clients, client)
+	if i < 0 {
+		// already removed, and its channel closed, by an eviction
+		return
 	}
-	stream.mu.Unlock()
+	stream.clients = slices.Delete(stream.clients, i, i+1)
+	close(client.Notify)
+
+	if stream.done && len(stream.clients) == 0 && h.chans[id] == stream {
+		delete(h.chans, id)
+	}
+}
 
-	close(client.Ch)
+// evict ends an unfinished stream with an error and closes its clients' channels, so
+// their readers stop; stream.mu must be held
+func (stream *Stream) evict() {
+	if !stream.done {
+		stream.publish(expiredMessage)
+		stream.publish("[DONE]")
+	}
+	// a pending notification is still received before the close, so readers get the end first
+	for _, client := range stream.clients {
+		close(client.Notify)
+	}
+	stream.clients = nil
 }
 
 func (h *Hub) Send(id, msg string) error {
@@ -126,24 +548,71 @@ func (h *Hub) Send(id, msg string) error {
 	stream.mu.Lock()
 	defer stream.mu.Unlock()
 
+	// a finished stream (including one failed as orphaned) takes no more messages
+	if stream.done {
+		return nil
+	}
+	stream.publish(msg)
+
+	return nil
+}
+
+// publish buffers msg and fans it out to connected clients; stream.mu must be held
+func (stream *Stream) publish(msg string) {
 	// buffer message FIRST
-	stream.buffer = append(stream.buffer, msg)
+	stream.lastID++
+	message := Message{ID: stream.lastID, Data: msg}
+	compacting := stream.limit.MaxBytes > 0 && stream.limit.Section != nil
+	if compacting {
+		message.section, message.partial = stream.limit.Section(msg)
+	}
+	stream.buffer = append(stream.buffer, message)
+	stream.bufferBytes += len(msg)
+	stream.lastActivity = time.Now()
+	if compacting && stream.bufferBytes > max(stream.limit.MaxBytes, stream.compactAbove) {
+		stream.compact()
+	}
 
 	// mark as done if end signal
 	if msg == "[DONE]" {
 		stream.done = true
+		stream.completed.Add(1)
 	}
 
-	// send to all connected clients (non-blocking with larger buffer)
+	// clients read the message from the buffer when they catch up, so none is ever skipped
 	for _, client := range stream.clients {
-		select {
-		case client.Ch <- msg:
-			// Message sent successfully
-		default:
-			// Client channel is full, but message is in buffer
-			// so client will get it when they catch up
-		}
+		client.notify()
 	}
+}
 
-	return nil
+// compact drops the partial updates that a later message of the same section replaces;
+// stream.mu must be held. Clients may still hold slices of the old buffer, so the kept
+// messages are copied rather than moved within it.
+func (stream *Stream) compact() {
+	// walking backwards, a partial update is replaced if its section was already seen
+	seen := make(map[string]bool)
+	keep := make([]bool, len(stream.buffer))
+	kept := 0
+	for i := len(stream.buffer) - 1; i >= 0; i-- {
+		msg := stream.buffer[i]
+		keep[i] = msg.section == "" || !msg.partial || !seen[msg.section]
+		if msg.section != "" {
+			seen[msg.section] = true
+		}
+		if keep[i] {
+			kept++
+		}
+	}
+	if kept < len(stream.buffer) {
+		buffer := make([]Message, 0, kept)
+		stream.bufferBytes = 0
+		for i, msg := range stream.buffer {
+			if keep[i] {
+				buffer = append(buffer, msg)
+				stream.bufferBytes += len(msg.Data)
+			}
+		}
+		stream.buffer = buffer
+	}
+	stream.compactAbove = 2 * stream.bufferBytes
 }
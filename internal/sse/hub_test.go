@@ -0,0 +1,137 @@
+package sse
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestHub returns a hub without buffer limits or a cleanup loop
+func newTestHub(t *testing.T) *Hub {
+	t.Helper()
+	hub := NewHub(10, time.Minute, 0, BufferLimit{}, zap.NewNop())
+	t.Cleanup(hub.Stop)
+	return hub
+}
+
+// publish creates stream id and sends msgs to it
+func publish(t *testing.T, hub *Hub, id string, msgs ...string) {
+	t.Helper()
+	if err := hub.Create(id, "owner"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, msg := range msgs {
+		if err := hub.Send(id, msg); err != nil {
+			t.Fatalf("Send(%q): %v", msg, err)
+		}
+	}
+}
+
+// received returns the data of msgs
+func received(msgs []Message) []string {
+	data := make([]string, len(msgs))
+	for i, msg := range msgs {
+		data[i] = msg.Data
+	}
+	return data
+}
+
+func TestLateJoinerReceivesCompletedStream(t *testing.T) {
+	hub := newTestHub(t)
+	msgs := []string{"first", "second", "third", "[DONE]"}
+	publish(t, hub, "job-1", msgs...)
+
+	client, err := hub.AddClient("job-1", 0)
+	if err != nil {
+		t.Fatalf("AddClient: %v", err)
+	}
+	select {
+	case <-client.Notify:
+	default:
+		t.Fatal("late joiner was not notified of the backlog")
+	}
+	if got := received(client.Next()); !slices.Equal(got, msgs) {
+		t.Errorf("late joiner received %q, want %q", got, msgs)
+	}
+	if got := client.Next(); len(got) != 0 {
+		t.Errorf("second Next returned %d messages, want none", len(got))
+	}
+}
+
+func TestUnknownStream(t *testing.T) {
+	hub := newTestHub(t)
+
+	if hub.Exists("job-unknown") {
+		t.Error("Exists reported a stream that was never created")
+	}
+	if _, err := hub.AddClient("job-unknown", 0); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("AddClient error = %v, want ErrStreamNotFound", err)
+	}
+}
+
+func TestAddClientResumesAfterLastEventID(t *testing.T) {
+	hub := newTestHub(t)
+	publish(t, hub, "job-1", "first", "second", "third")
+
+	tests := []struct {
+		name        string
+		lastEventID int64
+		want        []string
+	}{
+		{"fresh connection", 0, []string{"first", "second", "third"}},
+		{"mid-stream", 2, []string{"third"}},
+		{"id never handed out", 42, []string{"first", "second", "third"}},
+		{"negative id", -1, []string{"first", "second", "third"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := hub.AddClient("job-1", tt.lastEventID)
+			if err != nil {
+				t.Fatalf("AddClient: %v", err)
+			}
+			defer hub.RemoveClient("job-1", client)
+			if got := received(client.Next()); !slices.Equal(got, tt.want) {
+				t.Errorf("received %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// a client caught up on an unfinished stream waits for what comes next
+	client, err := hub.AddClient("job-1", 3)
+	if err != nil {
+		t.Fatalf("AddClient caught up: %v", err)
+	}
+	if got := client.Next(); len(got) != 0 {
+		t.Errorf("caught up client received %d messages, want none", len(got))
+	}
+	if err := hub.Send("job-1", "[DONE]"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	msgs := client.Next()
+	if got := received(msgs); !slices.Equal(got, []string{"[DONE]"}) {
+		t.Errorf("caught up client received %q, want [DONE]", got)
+	}
+	if msgs[0].ID != 4 {
+		t.Errorf("[DONE] id = %d, want 4", msgs[0].ID)
+	}
+}
+
+func TestAddClientAfterDone(t *testing.T) {
+	hub := newTestHub(t)
+	publish(t, hub, "job-1", "first", "[DONE]")
+
+	if _, err := hub.AddClient("job-1", 2); !errors.Is(err, ErrStreamEnded) {
+		t.Errorf("AddClient after [DONE] error = %v, want ErrStreamEnded", err)
+	}
+	// a client that missed [DONE] still gets it
+	client, err := hub.AddClient("job-1", 1)
+	if err != nil {
+		t.Fatalf("AddClient before [DONE]: %v", err)
+	}
+	if got := received(client.Next()); !slices.Equal(got, []string{"[DONE]"}) {
+		t.Errorf("received %q, want [DONE]", got)
+	}
+}
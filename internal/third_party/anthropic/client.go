@@ -0,0 +1,305 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"code-bridge/internal/third_party/provider_http"
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// baseURL is the Anthropic API; the Messages API is called directly over HTTP
+const baseURL = "https://api.anthropic.com/v1/"
+
+// apiVersion is sent as the anthropic-version header
+const apiVersion = "2023-06-01"
+
+// maxTokens caps the response length unless the request sets its own; the Messages API requires a limit
+const maxTokens = 8192
+
+// maxEventBytes bounds a single line of the event stream
+const maxEventBytes = 1024 * 1024
+
+type Client struct {
+	// httpClient is owned by the client so Close can release its connections
+	httpClient *http.Client
+	apiKey     string
+	model      string
+}
+
+// NewAnthropicClient creates a client sending its requests through httpClient, which it then owns
+func NewAnthropicClient(cfg types.AnthropicConfig, httpClient *http.Client) *Client {
+	return &Client{httpClient: httpClient, apiKey: cfg.APIKey, model: cfg.Model}
+}
+
+// Close releases the client's idle HTTP connections
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Warm opens a connection to the API ahead of the first request
+func (c *Client) Warm(ctx context.Context) error {
+	return provider_http.Warm(ctx, c.httpClient, baseURL)
+}
+
+// Name returns the provider name
+func (c *Client) Name() string {
+	return "anthropic"
+}
+
+// Model returns the model used when a request does not override it
+func (c *Client) Model() string {
+	return c.model
+}
+
+// messageRequest is the body of a Messages API request
+type messageRequest struct {
+	Model         string    `json:"model"`
+	MaxTokens     int64     `json:"max_tokens"`
+	Messages      []message `json:"messages"`
+	Stream        bool      `json:"stream,omitempty"`
+	Temperature   *float64  `json:"temperature,omitempty"`
+	StopSequences []string  `json:"stop_sequences,omitempty"`
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// messageResponse is a whole message, as returned without streaming and in message_start events
+type messageResponse struct {
+	ID         string         `json:"id"`
+	Model      string         `json:"model"`
+	StopReason string         `json:"stop_reason"`
+	Content    []contentBlock `json:"content"`
+	Usage      usage          `json:"usage"`
+}
+
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type usage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+// streamEvent is one event of a streamed message; only the fields of the events used are set
+type streamEvent struct {
+	Type    string          `json:"type"`
+	Message messageResponse `json:"message"`
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage usage     `json:"usage"`
+	Error *apiError `json:"error"`
+}
+
+type apiError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// StreamCompletion streams a message from the Messages API, forwarding text deltas to onChunk
+func (c *Client) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	body, model, err := c.messageRequest(prompt, opts, true)
+	if err != nil {
+		return err
+	}
+	resp, err := c.post(ctx, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	metadata := types.CompletionMetadata{Provider: c.Name(), Model: model}
+	started, outputStarted, completed := false, false, false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxEventBytes)
+	for scanner.Scan() {
+		// the event name is repeated in the data's type, so only data lines are read
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var event streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return fmt.Errorf("anthropic stream sent an invalid event: %w", err)
+		}
+		if !started {
+			started = true
+			if err := opts.Emit(types.EventResponseStarted); err != nil {
+				return err
+			}
+		}
+
+		switch event.Type {
+		case "message_start":
+			metadata.ResponseID = event.Message.ID
+			if event.Message.Model != "" {
+				metadata.Model = event.Message.Model
+			}
+			metadata.PromptTokens = event.Message.Usage.InputTokens
+		case "content_block_delta":
+			// thinking and tool input deltas carry no translation text
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				continue
+			}
+			if !outputStarted {
+				outputStarted = true
+				if err := opts.Emit(types.EventOutputStarted); err != nil {
+					return err
+				}
+			}
+			if err := onChunk(event.Delta.Text); err != nil {
+				return err
+			}
+		case "message_delta":
+			metadata.CompletionTokens = event.Usage.OutputTokens
+			if event.Delta.StopReason != "" && !completed {
+				completed = true
+				metadata.FinishReason = event.Delta.StopReason
+				if err := opts.Emit(types.EventResponseCompleted); err != nil {
+					return err
+				}
+			}
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("anthropic stream failed: %w", event.Error)
+			}
+			return errors.New("anthropic stream failed")
+		case "message_stop":
+			metadata.TotalTokens = metadata.PromptTokens + metadata.CompletionTokens
+			if opts.OnMetadata != nil {
+				opts.OnMetadata(metadata)
+			}
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("anthropic stream failed: %w", err)
+	}
+	return errors.New("anthropic stream ended before the message was complete")
+}
+
+// Completion returns the whole response of a single non-streaming message
+func (c *Client) Completion(ctx context.Context, prompt string, opts types.CompletionOptions) (string, error) {
+	body, model, err := c.messageRequest(prompt, opts, false)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.post(ctx, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var msg messageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&msg); err != nil {
+		return "", fmt.Errorf("anthropic completion failed: invalid response: %w", err)
+	}
+	var text strings.Builder
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	if opts.OnMetadata != nil {
+		metadata := types.CompletionMetadata{
+			Provider:         c.Name(),
+			Model:            model,
+			ResponseID:       msg.ID,
+			FinishReason:     msg.StopReason,
+			PromptTokens:     msg.Usage.InputTokens,
+			CompletionTokens: msg.Usage.OutputTokens,
+			TotalTokens:      msg.Usage.InputTokens + msg.Usage.OutputTokens,
+		}
+		if msg.Model != "" {
+			metadata.Model = msg.Model
+		}
+		opts.OnMetadata(metadata)
+	}
+	return text.String(), nil
+}
+
+// messageRequest builds the request body for prompt and returns it with the model it uses.
+// Seeds are not supported by the Messages API and are left out.
+func (c *Client) messageRequest(prompt string, opts types.CompletionOptions, stream bool) ([]byte, string, error) {
+	model := c.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+	// requests may ask for up to 2.0, which the Messages API rejects
+	if opts.Temperature != nil && *opts.Temperature > 1 {
+		return nil, "", fmt.Errorf("anthropic accepts a temperature between 0 and 1, got %g", *opts.Temperature)
+	}
+
+	limit := int64(maxTokens)
+	if opts.MaxOutputTokens != nil {
+		limit = *opts.MaxOutputTokens
+	}
+
+	body, err := json.Marshal(messageRequest{
+		Model:         model,
+		MaxTokens:     limit,
+		Messages:      []message{{Role: "user", Content: prompt}},
+		Stream:        stream,
+		Temperature:   opts.Temperature,
+		StopSequences: opts.StopSequences,
+	})
+	return body, model, err
+}
+
+// post sends a Messages API request, returning a provider_http.StatusError for non-2xx responses
+func (c *Client) post(ctx context.Context, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("anthropic request failed: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	var failure struct {
+		Error *apiError `json:"error"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	message := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &failure) == nil && failure.Error != nil {
+		message = failure.Error.Error()
+	}
+	return nil, &provider_http.StatusError{
+		Provider:   c.Name(),
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		RetryAfter: provider_http.ParseRetryAfter(resp.Header),
+	}
+}
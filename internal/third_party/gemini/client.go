@@ -1,59 +1,191 @@
 package gemini
 
 import (
+	"code-bridge/internal/third_party/provider_http"
 	"code-bridge/pkg/types"
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 
+	"go.uber.org/zap"
 	"google.golang.org/genai"
 )
 
+// baseURL is the Gemini API, connected to by Warm
+const baseURL = "https://generativelanguage.googleapis.com/"
+
 type Client struct {
 	client *genai.Client
+	// httpClient is owned by the client so Close can release its connections;
+	// genai.Client itself has nothing to close
+	httpClient *http.Client
+	model      string
+	logger     *zap.Logger
 }
 
-func NewGeminiClient(geminiConfig types.GeminiConfig) *Client {
+// NewGeminiClient creates a client sending its requests through httpClient, which it then owns
+func NewGeminiClient(geminiConfig types.GeminiConfig, httpClient *http.Client, logger *zap.Logger) *Client {
 	apiKey := geminiConfig.APIKey
 	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
-		APIKey: apiKey,
+		APIKey:     apiKey,
+		HTTPClient: httpClient,
 	})
 	if err != nil {
 		panic(fmt.Sprintf("failed to create Gemini client: %v", err))
 	}
 	return &Client{
-		client: client,
+		client:     client,
+		httpClient: httpClient,
+		model:      geminiConfig.Model,
+		logger:     logger,
 	}
 }
 
+// Close releases the client's idle HTTP connections
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Warm opens a connection to the API ahead of the first request
+func (c *Client) Warm(ctx context.Context) error {
+	return provider_http.Warm(ctx, c.httpClient, baseURL)
+}
+
+// Name returns the provider name
+func (c *Client) Name() string {
+	return "gemini"
+}
+
+// Model returns the model used when a request does not override it
+func (c *Client) Model() string {
+	return c.model
+}
+
+// SupportsSeed reports that Gemini honours CompletionOptions.Seed
+func (c *Client) SupportsSeed() bool {
+	return true
+}
+
 // StreamCompletion implements streaming completion using Google Gemini API
-func (c *Client) StreamCompletion(ctx context.Context, prompt string, onChunk func(string) error) error {
-
-	stream := c.client.Models.GenerateContentStream(ctx,
-		"gemini-2.5-flash",
-		[]*genai.Content{
-			{
-				Role: "user",
-				Parts: []*genai.Part{
-					{
-						Text: prompt,
-					},
-				},
-			},
-		},
-		&genai.GenerateContentConfig{},
-	)
+func (c *Client) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	model, config := c.generateConfig(opts)
+	stream := c.client.Models.GenerateContentStream(ctx, model, userContent(prompt), config)
 
-	for chunk := range stream {
-		text := chunk.Text()
-		fmt.Printf("chunk: %s", text)
-		err := onChunk(text)
+	metadata := types.CompletionMetadata{Provider: c.Name(), Model: model}
+	started, outputStarted, completed := false, false, false
+	for chunk, err := range stream {
 		if err != nil {
-			log.Printf("chunk failed: %v", err)
-			return err
+			return fmt.Errorf("gemini stream failed: %w", err)
+		}
+		recordMetadata(&metadata, chunk)
+		if !started {
+			started = true
+			if err := opts.Emit(types.EventResponseStarted); err != nil {
+				return err
+			}
+		}
+		// Usage-only and finish events carry no text; don't feed them to the parser
+		if text := chunk.Text(); text != "" {
+			if !outputStarted {
+				outputStarted = true
+				if err := opts.Emit(types.EventOutputStarted); err != nil {
+					return err
+				}
+			}
+			c.logger.Debug("chunk received", zap.String("provider", c.Name()), zap.Int("chunk_bytes", len(text)))
+			err := onChunk(text)
+			if err != nil {
+				c.logger.Debug("chunk callback failed", zap.String("provider", c.Name()), zap.Error(err))
+				return err
+			}
+		}
+		// the finish reason usually arrives together with the last piece of text
+		if !completed && len(chunk.Candidates) > 0 && chunk.Candidates[0].FinishReason != "" {
+			completed = true
+			if err := opts.Emit(types.EventResponseCompleted); err != nil {
+				return err
+			}
 		}
 	}
 
-	fmt.Println("\n\nStream finished.")
+	c.logger.Debug("stream finished", zap.String("provider", c.Name()), zap.String("model", model))
+	if opts.OnMetadata != nil {
+		opts.OnMetadata(metadata)
+	}
 	return nil
 }
+
+// Completion returns the whole response of a single non-streaming generation
+func (c *Client) Completion(ctx context.Context, prompt string, opts types.CompletionOptions) (string, error) {
+	model, config := c.generateConfig(opts)
+	response, err := c.client.Models.GenerateContent(ctx, model, userContent(prompt), config)
+	if err != nil {
+		return "", fmt.Errorf("gemini completion failed: %w", err)
+	}
+
+	if opts.OnMetadata != nil {
+		metadata := types.CompletionMetadata{Provider: c.Name(), Model: model}
+		recordMetadata(&metadata, response)
+		opts.OnMetadata(metadata)
+	}
+	return response.Text(), nil
+}
+
+// generateConfig returns the model and generation settings for opts
+func (c *Client) generateConfig(opts types.CompletionOptions) (string, *genai.GenerateContentConfig) {
+	model := c.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	config := &genai.GenerateContentConfig{}
+	if opts.Temperature != nil {
+		temperature := float32(*opts.Temperature)
+		config.Temperature = &temperature
+	}
+	if opts.Seed != nil {
+		seed := int32(*opts.Seed)
+		config.Seed = &seed
+	}
+	if opts.MaxOutputTokens != nil {
+		// requests are validated to at most 65536, so this fits
+		config.MaxOutputTokens = int32(*opts.MaxOutputTokens)
+	}
+	if len(opts.StopSequences) > 0 {
+		config.StopSequences = opts.StopSequences
+	}
+	return model, config
+}
+
+// userContent wraps prompt as a single user turn
+func userContent(prompt string) []*genai.Content {
+	return []*genai.Content{
+		{
+			Role: "user",
+			Parts: []*genai.Part{
+				{
+					Text: prompt,
+				},
+			},
+		},
+	}
+}
+
+// recordMetadata copies the response id, model version, finish reason and usage from a stream chunk
+func recordMetadata(metadata *types.CompletionMetadata, chunk *genai.GenerateContentResponse) {
+	if chunk.ResponseID != "" {
+		metadata.ResponseID = chunk.ResponseID
+	}
+	if chunk.ModelVersion != "" {
+		metadata.Model = chunk.ModelVersion
+	}
+	if len(chunk.Candidates) > 0 && chunk.Candidates[0].FinishReason != "" {
+		metadata.FinishReason = string(chunk.Candidates[0].FinishReason)
+	}
+	if usage := chunk.UsageMetadata; usage != nil {
+		metadata.PromptTokens = int64(usage.PromptTokenCount)
+		metadata.CompletionTokens = int64(usage.CandidatesTokenCount)
+		metadata.TotalTokens = int64(usage.TotalTokenCount)
+	}
+}
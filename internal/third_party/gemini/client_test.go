@@ -0,0 +1,85 @@
+package gemini
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/genai"
+)
+
+// newStreamServer returns a client for a local server answering every generation with events
+func newStreamServer(t *testing.T, events ...string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  server.Client(),
+		HTTPOptions: genai.HTTPOptions{BaseURL: server.URL + "/"},
+	})
+	if err != nil {
+		t.Fatalf("genai.NewClient: %v", err)
+	}
+	return &Client{client: client, httpClient: server.Client(), model: "gemini-test", logger: zap.NewNop()}
+}
+
+func TestStreamCompletionSkipsEventsWithoutText(t *testing.T) {
+	client := newStreamServer(t,
+		// role-only
+		`{"candidates":[{"content":{"role":"model","parts":[]}}]}`,
+		// empty text
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":""}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello"}]}}]}`,
+		`{"candidates":[{"content":{"role":"model","parts":[{"text":", world"}]}}]}`,
+		// finish-only
+		`{"candidates":[{"finishReason":"STOP"}],"modelVersion":"gemini-test-001"}`,
+		// usage-only
+		`{"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":3,"totalTokenCount":15}}`,
+	)
+
+	var chunks []string
+	var events []types.CompletionEvent
+	var metadata types.CompletionMetadata
+	opts := types.CompletionOptions{
+		OnEvent: func(event types.CompletionEvent) error {
+			events = append(events, event)
+			return nil
+		},
+		OnMetadata: func(m types.CompletionMetadata) { metadata = m },
+	}
+	err := client.StreamCompletion(context.Background(), "prompt", opts, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamCompletion: %v", err)
+	}
+
+	if want := []string{"Hello", ", world"}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+	wantEvents := []types.CompletionEvent{types.EventResponseStarted, types.EventOutputStarted, types.EventResponseCompleted}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("events = %v, want %v", events, wantEvents)
+	}
+	wantMetadata := types.CompletionMetadata{
+		Provider: "gemini", Model: "gemini-test-001", FinishReason: "STOP",
+		PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15,
+	}
+	if metadata != wantMetadata {
+		t.Errorf("metadata = %+v, want %+v", metadata, wantMetadata)
+	}
+}
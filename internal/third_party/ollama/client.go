@@ -0,0 +1,234 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"code-bridge/internal/third_party/provider_http"
+	"code-bridge/pkg/types"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxLineBytes bounds a single line of the streamed response
+const maxLineBytes = 1024 * 1024
+
+type Client struct {
+	// httpClient is owned by the client so Close can release its connections
+	httpClient *http.Client
+	// host is the Ollama server, e.g. http://localhost:11434
+	host  string
+	model string
+}
+
+// NewOllamaClient creates a client for the Ollama server at cfg.Host, sending its requests
+// through httpClient, which it then owns
+func NewOllamaClient(cfg types.OllamaConfig, httpClient *http.Client) *Client {
+	return &Client{httpClient: httpClient, host: strings.TrimRight(cfg.Host, "/"), model: cfg.Model}
+}
+
+// Close releases the client's idle HTTP connections
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Warm opens a connection to the server ahead of the first request
+func (c *Client) Warm(ctx context.Context) error {
+	return provider_http.Warm(ctx, c.httpClient, c.host)
+}
+
+// Name returns the provider name
+func (c *Client) Name() string {
+	return "ollama"
+}
+
+// Model returns the model used when a request does not override it
+func (c *Client) Model() string {
+	return c.model
+}
+
+// SupportsSeed reports that Ollama honours CompletionOptions.Seed
+func (c *Client) SupportsSeed() bool {
+	return true
+}
+
+// generateRequest is the body of an /api/generate request
+type generateRequest struct {
+	Model   string          `json:"model"`
+	Prompt  string          `json:"prompt"`
+	Stream  bool            `json:"stream"`
+	Options generateOptions `json:"options,omitempty"`
+}
+
+type generateOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	Seed        *int64   `json:"seed,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	NumPredict  *int64   `json:"num_predict,omitempty"`
+}
+
+// generateResponse is one line of a streamed response, or the whole response without streaming.
+// Token counts and the done reason are only set once Done is.
+type generateResponse struct {
+	Model           string `json:"model"`
+	Response        string `json:"response"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int64  `json:"prompt_eval_count"`
+	EvalCount       int64  `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+// metadata converts the final response line to CompletionMetadata
+func (r generateResponse) metadata(model string) types.CompletionMetadata {
+	if r.Model != "" {
+		model = r.Model
+	}
+	return types.CompletionMetadata{
+		Provider:         "ollama",
+		Model:            model,
+		FinishReason:     r.DoneReason,
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+// StreamCompletion streams a completion from the server's newline-delimited JSON responses,
+// forwarding each line's response text to onChunk
+func (c *Client) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	model, resp, err := c.generate(ctx, prompt, opts, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	started, outputStarted := false, false
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var part generateResponse
+		if err := json.Unmarshal(line, &part); err != nil {
+			return fmt.Errorf("ollama stream sent an invalid line: %w", err)
+		}
+		if part.Error != "" {
+			return fmt.Errorf("ollama stream failed: %s", part.Error)
+		}
+		if !started {
+			started = true
+			if err := opts.Emit(types.EventResponseStarted); err != nil {
+				return err
+			}
+		}
+
+		if part.Response != "" {
+			if !outputStarted {
+				outputStarted = true
+				if err := opts.Emit(types.EventOutputStarted); err != nil {
+					return err
+				}
+			}
+			if err := onChunk(part.Response); err != nil {
+				return err
+			}
+		}
+		if part.Done {
+			if err := opts.Emit(types.EventResponseCompleted); err != nil {
+				return err
+			}
+			if opts.OnMetadata != nil {
+				opts.OnMetadata(part.metadata(model))
+			}
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("ollama stream failed: %w", err)
+	}
+	return errors.New("ollama stream ended before the response was done")
+}
+
+// Completion returns the whole response of a single non-streaming request
+func (c *Client) Completion(ctx context.Context, prompt string, opts types.CompletionOptions) (string, error) {
+	model, resp, err := c.generate(ctx, prompt, opts, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result generateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("ollama completion failed: invalid response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("ollama completion failed: %s", result.Error)
+	}
+	if opts.OnMetadata != nil {
+		opts.OnMetadata(result.metadata(model))
+	}
+	return result.Response, nil
+}
+
+// generate sends an /api/generate request for prompt, returning the model it asked for and
+// the response, or a provider_http.StatusError for non-2xx responses
+func (c *Client) generate(ctx context.Context, prompt string, opts types.CompletionOptions, stream bool) (string, *http.Response, error) {
+	model := c.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+	body, err := json.Marshal(generateRequest{
+		Model:  model,
+		Prompt: prompt,
+		Stream: stream,
+		Options: generateOptions{
+			Temperature: opts.Temperature,
+			Seed:        opts.Seed,
+			Stop:        opts.StopSequences,
+			NumPredict:  opts.MaxOutputTokens,
+		},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.host+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("ollama request failed: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return model, resp, nil
+	}
+	defer resp.Body.Close()
+
+	// e.g. {"error":"model \"llama3.2\" not found, try pulling it first"}
+	var failure generateResponse
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	message := strings.TrimSpace(string(raw))
+	if json.Unmarshal(raw, &failure) == nil && failure.Error != "" {
+		message = failure.Error
+	}
+	return "", nil, &provider_http.StatusError{
+		Provider:   c.Name(),
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		RetryAfter: provider_http.ParseRetryAfter(resp.Header),
+	}
+}
@@ -1,55 +1,209 @@
 package codebridge_openai
 
 import (
+	"code-bridge/internal/third_party/provider_http"
 	"code-bridge/pkg/types"
 	"context"
 	"fmt"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/packages/ssestream"
-	"github.com/openai/openai-go/v3/responses"
-	"log"
+	"net/http"
 
 	"github.com/openai/openai-go/v3"
+	"go.uber.org/zap"
 )
 
+// defaultBaseURL is the OpenAI API, connected to by Warm
+const defaultBaseURL = "https://api.openai.com/v1/"
+
 type Client struct {
 	client *openai.Client
+	// httpClient is owned by the client so Close can release its connections
+	httpClient *http.Client
+	// name and model identify the provider; they differ for OpenAI-compatible servers
+	name  string
+	model string
+	// baseURL is the API the client talks to
+	baseURL string
+	logger  *zap.Logger
 }
 
-func NewOpenAIClient(openAIConfig types.OpenAIConfig) *Client {
+// NewOpenAIClient creates a client sending its requests through httpClient, which it then owns
+func NewOpenAIClient(openAIConfig types.OpenAIConfig, httpClient *http.Client, logger *zap.Logger) *Client {
 	// Create and return the client; actual SDK init may differ
 	apiKey := openAIConfig.APIKey
-	c := openai.NewClient(option.WithAPIKey(apiKey))
-	return &Client{client: &c}
-}
-
-// StreamCompletion demonstrates a streaming call; adjust to the real SDK
-func (c *Client) StreamCompletion(ctx context.Context, prompt string, onChunk func(string) error) error {
-	stream := c.client.Responses.NewStreaming(ctx, responses.ResponseNewParams{
-		Model: "gpt-5-nano",
-		Input: responses.ResponseNewParamsInputUnion{OfString: openai.String(prompt)},
-	})
-	//stream, err := c.client.Chat.CreateStream(ctx, openai.ChatCreateParams{ /* fill */ })
-	defer func(stream *ssestream.Stream[responses.ResponseStreamEventUnion]) {
-		err := stream.Close()
-		if err != nil {
-			log.Fatalf("Failed to close stream: %v\n", err)
+	// retries are left to translator_provider.Retrying, which caps how long a Retry-After may hold a job
+	c := openai.NewClient(option.WithAPIKey(apiKey), option.WithHTTPClient(httpClient), option.WithMaxRetries(0))
+	return &Client{client: &c, httpClient: httpClient, name: "openai", model: openAIConfig.Model, baseURL: defaultBaseURL, logger: logger}
+}
+
+// Close releases the client's idle HTTP connections
+func (c *Client) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// Warm opens a connection to the API ahead of the first request
+func (c *Client) Warm(ctx context.Context) error {
+	return provider_http.Warm(ctx, c.httpClient, c.baseURL)
+}
+
+// Name returns the provider name
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Model returns the model used when a request does not override it
+func (c *Client) Model() string {
+	return c.model
+}
+
+// SupportsSeed reports that OpenAI (and llama.cpp) honour CompletionOptions.Seed
+func (c *Client) SupportsSeed() bool {
+	return true
+}
+
+// StreamCompletion streams a chat completion; the Chat Completions API is used
+// rather than Responses because it accepts a sampling seed
+func (c *Client) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	params, model := c.chatParams(prompt, opts)
+	if opts.OnMetadata != nil {
+		// usage is only reported in a final chunk when asked for
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{IncludeUsage: openai.Bool(true)}
+	}
+	metadata := types.CompletionMetadata{Provider: c.Name(), Model: model}
+
+	stream := c.client.Chat.Completions.NewStreaming(ctx, params)
+	// the response is already complete or failed, so a close error is only worth a log line
+	defer func(stream *ssestream.Stream[openai.ChatCompletionChunk]) {
+		if err := stream.Close(); err != nil && ctx.Err() == nil {
+			c.logger.Warn("failed to close stream", zap.String("provider", c.Name()), zap.Error(err))
 		}
 	}(stream)
 
+	started, outputStarted, completed := false, false, false
 	for stream.Next() {
 		currentChunk := stream.Current()
-		text := currentChunk.Text
-		log.Printf("chunk: %s", text)
-		err := onChunk(text)
-		if err != nil {
-			return err
+		recordMetadata(&metadata, currentChunk)
+		if !started {
+			started = true
+			if err := opts.Emit(types.EventResponseStarted); err != nil {
+				return err
+			}
+		}
+		// Role, tool-call, finish and usage events carry no content delta; skip them
+		if len(currentChunk.Choices) == 0 {
+			continue
+		}
+		choice := currentChunk.Choices[0]
+		if text := choice.Delta.Content; text != "" {
+			if !outputStarted {
+				outputStarted = true
+				if err := opts.Emit(types.EventOutputStarted); err != nil {
+					return err
+				}
+			}
+			c.logger.Debug("chunk received", zap.String("provider", c.Name()), zap.Int("chunk_bytes", len(text)))
+			err := onChunk(text)
+			if err != nil {
+				return err
+			}
+		}
+		if !completed && choice.FinishReason != "" {
+			completed = true
+			if err := opts.Emit(types.EventResponseCompleted); err != nil {
+				return err
+			}
 		}
 	}
 	// Check for any errors that occurred during streaming
 	if err := stream.Err(); err != nil {
-		log.Fatalf("Stream error: %v\n", err)
+		// a cancelled or timed out request stops the stream on purpose
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.logger.Error("stream failed", zap.String("provider", c.Name()), zap.String("model", model), zap.Error(err))
+		return fmt.Errorf("%s stream failed: %w", c.Name(), err)
+	}
+	c.logger.Debug("stream finished", zap.String("provider", c.Name()), zap.String("model", model))
+	if opts.OnMetadata != nil {
+		opts.OnMetadata(metadata)
 	}
-	fmt.Println("\n\nStream finished.")
 	return nil
 }
+
+// Completion returns the whole response of a single non-streaming chat completion
+func (c *Client) Completion(ctx context.Context, prompt string, opts types.CompletionOptions) (string, error) {
+	params, model := c.chatParams(prompt, opts)
+	completion, err := c.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("%s completion failed: %w", c.Name(), err)
+	}
+
+	metadata := types.CompletionMetadata{
+		Provider:         c.Name(),
+		Model:            model,
+		ResponseID:       completion.ID,
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		TotalTokens:      completion.Usage.TotalTokens,
+	}
+	if completion.Model != "" {
+		metadata.Model = completion.Model
+	}
+	text := ""
+	if len(completion.Choices) > 0 {
+		text = completion.Choices[0].Message.Content
+		metadata.FinishReason = completion.Choices[0].FinishReason
+	}
+	if opts.OnMetadata != nil {
+		opts.OnMetadata(metadata)
+	}
+	return text, nil
+}
+
+// chatParams builds the request for prompt and returns it with the model it uses
+func (c *Client) chatParams(prompt string, opts types.CompletionOptions) (openai.ChatCompletionNewParams, string) {
+	model := c.model
+	if opts.Model != "" {
+		model = opts.Model
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model: model,
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
+		},
+	}
+	if opts.Temperature != nil {
+		params.Temperature = openai.Float(*opts.Temperature)
+	}
+	if opts.Seed != nil {
+		params.Seed = openai.Int(*opts.Seed)
+	}
+	if opts.MaxOutputTokens != nil {
+		params.MaxCompletionTokens = openai.Int(*opts.MaxOutputTokens)
+	}
+	if len(opts.StopSequences) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: opts.StopSequences}
+	}
+	return params, model
+}
+
+// recordMetadata copies the response id, served model, finish reason and usage from a stream chunk
+func recordMetadata(metadata *types.CompletionMetadata, chunk openai.ChatCompletionChunk) {
+	if chunk.ID != "" {
+		metadata.ResponseID = chunk.ID
+	}
+	if chunk.Model != "" {
+		metadata.Model = chunk.Model
+	}
+	if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
+		metadata.FinishReason = chunk.Choices[0].FinishReason
+	}
+	if chunk.JSON.Usage.Valid() {
+		metadata.PromptTokens = chunk.Usage.PromptTokens
+		metadata.CompletionTokens = chunk.Usage.CompletionTokens
+		metadata.TotalTokens = chunk.Usage.TotalTokens
+	}
+}
@@ -0,0 +1,116 @@
+package codebridge_openai
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newStreamServer returns a client for a local server answering every chat completion with events
+func newStreamServer(t *testing.T, events ...string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return NewLocalClient(types.LocalLLMConfig{BaseURL: server.URL, Model: "test"}, server.Client(), zap.NewNop())
+}
+
+func TestStreamCompletionSkipsEventsWithoutContent(t *testing.T) {
+	client := newStreamServer(t,
+		// role-only
+		`{"id":"chatcmpl-1","model":"test-model","choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+		// empty content
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":""}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":", world"}}]}`,
+		// finish-only
+		`{"id":"chatcmpl-1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		// usage-only, sent without choices
+		`{"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`,
+		`[DONE]`,
+	)
+
+	var chunks []string
+	var events []types.CompletionEvent
+	var metadata types.CompletionMetadata
+	opts := types.CompletionOptions{
+		OnEvent: func(event types.CompletionEvent) error {
+			events = append(events, event)
+			return nil
+		},
+		OnMetadata: func(m types.CompletionMetadata) { metadata = m },
+	}
+	err := client.StreamCompletion(context.Background(), "prompt", opts, func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamCompletion: %v", err)
+	}
+
+	if want := []string{"Hello", ", world"}; !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+	wantEvents := []types.CompletionEvent{types.EventResponseStarted, types.EventOutputStarted, types.EventResponseCompleted}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("events = %v, want %v", events, wantEvents)
+	}
+	wantMetadata := types.CompletionMetadata{
+		Provider: "local", Model: "test-model", ResponseID: "chatcmpl-1", FinishReason: "stop",
+		PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15,
+	}
+	if metadata != wantMetadata {
+		t.Errorf("metadata = %+v, want %+v", metadata, wantMetadata)
+	}
+}
+
+// a broken stream used to end the process with log.Fatal; the tests reaching their
+// assertions shows it is returned instead
+func TestStreamCompletionReturnsStreamErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+	}{
+		{"malformed event", []string{`{"choices":[{"index":0,"delta":{"content":"Hel"}}]}`, `{"choices":[`}},
+		{"error event", []string{`{"error":{"message":"model overloaded","type":"server_error"}}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStreamServer(t, tt.events...)
+			err := client.StreamCompletion(context.Background(), "prompt", types.CompletionOptions{}, func(string) error { return nil })
+			if err == nil {
+				t.Fatal("StreamCompletion returned no error for a broken stream")
+			}
+			if !strings.Contains(err.Error(), "local stream failed") {
+				t.Errorf("error = %v, want a stream failure", err)
+			}
+		})
+	}
+}
+
+func TestStreamCompletionReturnsHTTPErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"internal error"}}`, http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	client := NewLocalClient(types.LocalLLMConfig{BaseURL: server.URL, Model: "test"}, server.Client(), zap.NewNop())
+
+	err := client.StreamCompletion(context.Background(), "prompt", types.CompletionOptions{}, func(string) error { return nil })
+	if err == nil {
+		t.Fatal("StreamCompletion returned no error for a 500 response")
+	}
+	if !strings.Contains(err.Error(), "local stream failed") {
+		t.Errorf("error = %v, want a stream failure", err)
+	}
+}
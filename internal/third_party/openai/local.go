@@ -0,0 +1,24 @@
+package codebridge_openai
+
+import (
+	"code-bridge/pkg/types"
+	"net/http"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"go.uber.org/zap"
+)
+
+// NewLocalClient returns a client for a local OpenAI-compatible server such as
+// llama.cpp's llama-server, which streams chat completions at cfg.BaseURL
+func NewLocalClient(cfg types.LocalLLMConfig, httpClient *http.Client, logger *zap.Logger) *Client {
+	c := openai.NewClient(
+		option.WithBaseURL(cfg.BaseURL),
+		// llama-server only checks the key when started with --api-key
+		option.WithAPIKey(cfg.APIKey),
+		option.WithHTTPClient(httpClient),
+		// retries are left to translator_provider.Retrying
+		option.WithMaxRetries(0),
+	)
+	return &Client{client: &c, httpClient: httpClient, name: "local", model: cfg.Model, baseURL: cfg.BaseURL, logger: logger}
+}
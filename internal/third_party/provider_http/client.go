@@ -0,0 +1,46 @@
+package provider_http
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// dialTimeout bounds establishing a TCP connection to a provider
+const dialTimeout = 10 * time.Second
+
+// NewClient returns an HTTP client for a provider SDK whose transport keeps a pool of
+// idle connections alive between translations, so only the first request pays for
+// the TCP and TLS handshakes
+func NewClient(cfg types.ProviderHTTPConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}).DialContext
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	// every connection of a provider client goes to the same host
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConns
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	return &http.Client{Transport: transport}
+}
+
+// Warm opens a connection to baseURL with a HEAD request and leaves it in client's idle
+// pool. Any response counts, since only the connection is wanted.
+func Warm(ctx context.Context, client *http.Client, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, baseURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid provider url %q: %w", baseURL, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", req.URL.Host, err)
+	}
+	// the connection only returns to the pool once the body is read and closed
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
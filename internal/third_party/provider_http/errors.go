@@ -0,0 +1,40 @@
+package provider_http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusError is returned by the clients calling a provider's API directly when it answers
+// with a non-2xx status, so callers can tell transient failures from permanent ones
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	// Message is the API's error message, or the response body if it has none
+	Message string
+	// RetryAfter is how long the API asked to wait before trying again, or zero if it didn't
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s request failed with status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// ParseRetryAfter returns the wait a Retry-After header asks for, given in seconds or as an
+// HTTP date, or zero if the header is absent or invalid
+func ParseRetryAfter(header http.Header) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package translation_history
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// translation is one row of the translations table
+type translation struct {
+	bun.BaseModel `bun:"table:translations"`
+
+	ID             string            `bun:"id,pk"`
+	ParentID       string            `bun:"parent_id,notnull,default:''"`
+	Client         string            `bun:"client,notnull"`
+	Owner          string            `bun:"owner,notnull"`
+	SourceLanguage string            `bun:"source_language,notnull"`
+	TargetLanguage string            `bun:"target_language,notnull"`
+	SourceCode     string            `bun:"source_code,notnull"`
+	TranslatedCode string            `bun:"translated_code,notnull"`
+	Explanation    string            `bun:"explanation,notnull"`
+	Notes          string            `bun:"notes,notnull"`
+	Dependencies   string            `bun:"dependencies,notnull,default:''"`
+	Metadata       map[string]string `bun:"metadata,type:jsonb"`
+	CreatedAt      time.Time         `bun:"created_at,notnull,default:current_timestamp"`
+}
+
+// PostgresStore keeps finished translations in the translations table
+type PostgresStore struct {
+	db *bun.DB
+}
+
+// NewPostgresStore creates the translations table if needed
+func NewPostgresStore(ctx context.Context, db *bun.DB) (*PostgresStore, error) {
+	if _, err := db.NewCreateTable().Model((*translation)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create translations table: %w", err)
+	}
+	// tables created before dependencies were stored lack the column
+	if _, err := db.ExecContext(ctx, "ALTER TABLE translations ADD COLUMN IF NOT EXISTS dependencies TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, fmt.Errorf("failed to add translations dependencies column: %w", err)
+	}
+	// and tables created before reruns were linked to their original lack this one
+	if _, err := db.ExecContext(ctx, "ALTER TABLE translations ADD COLUMN IF NOT EXISTS parent_id TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, fmt.Errorf("failed to add translations parent_id column: %w", err)
+	}
+	// history is always listed per session, newest first
+	_, err := db.NewCreateIndex().
+		Model((*translation)(nil)).
+		Index("translations_owner_created_at_idx").
+		Column("owner", "created_at").
+		IfNotExists().
+		Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create translations index: %w", err)
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// Save inserts a finished translation; a job resumed after completing is stored once
+func (s *PostgresStore) Save(ctx context.Context, t types.Translation) error {
+	row := translation{
+		ID:             t.ID,
+		ParentID:       t.ParentID,
+		Client:         t.Client,
+		Owner:          t.Owner,
+		SourceLanguage: t.SourceLanguage,
+		TargetLanguage: t.TargetLanguage,
+		SourceCode:     t.SourceCode,
+		TranslatedCode: t.TranslatedCode,
+		Explanation:    t.Explanation,
+		Notes:          t.Notes,
+		Dependencies:   t.Dependencies,
+		Metadata:       t.Metadata,
+		CreatedAt:      t.CreatedAt,
+	}
+	if _, err := s.db.NewInsert().Model(&row).On("CONFLICT (id) DO NOTHING").Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save translation: %w", err)
+	}
+	return nil
+}
+
+// List returns a page of owner's translations, newest first
+func (s *PostgresStore) List(ctx context.Context, owner string, limit, offset int) ([]types.Translation, error) {
+	var rows []translation
+	err := s.db.NewSelect().
+		Model(&rows).
+		Where("owner = ?", owner).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list translations: %w", err)
+	}
+
+	translations := make([]types.Translation, len(rows))
+	for i, row := range rows {
+		translations[i] = row.toTranslation()
+	}
+	return translations, nil
+}
+
+// Get returns the translation stored under id
+func (s *PostgresStore) Get(ctx context.Context, id string) (*types.Translation, error) {
+	var row translation
+	if err := s.db.NewSelect().Model(&row).Where("id = ?", id).Scan(ctx); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTranslationNotFound
+		}
+		return nil, fmt.Errorf("failed to load translation: %w", err)
+	}
+	t := row.toTranslation()
+	return &t, nil
+}
+
+func (row translation) toTranslation() types.Translation {
+	return types.Translation{
+		ID:             row.ID,
+		ParentID:       row.ParentID,
+		Client:         row.Client,
+		Owner:          row.Owner,
+		SourceLanguage: row.SourceLanguage,
+		TargetLanguage: row.TargetLanguage,
+		SourceCode:     row.SourceCode,
+		TranslatedCode: row.TranslatedCode,
+		Explanation:    row.Explanation,
+		Notes:          row.Notes,
+		Dependencies:   row.Dependencies,
+		Metadata:       row.Metadata,
+		CreatedAt:      row.CreatedAt,
+	}
+}
@@ -0,0 +1,19 @@
+package translation_history
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+)
+
+// ErrTranslationNotFound is returned by Get for ids that were never stored
+var ErrTranslationNotFound = errors.New("translation not found")
+
+// Store keeps finished translations so clients can look them up later
+type Store interface {
+	Save(ctx context.Context, translation types.Translation) error
+	// Get returns the translation stored under id, or ErrTranslationNotFound
+	Get(ctx context.Context, id string) (*types.Translation, error)
+	// List returns owner's translations, newest first
+	List(ctx context.Context, owner string, limit, offset int) ([]types.Translation, error)
+}
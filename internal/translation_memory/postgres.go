@@ -0,0 +1,107 @@
+package translation_memory
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// memoryEntry is one remembered segment translation of a scope for a language pair
+type memoryEntry struct {
+	bun.BaseModel `bun:"table:translation_memory"`
+
+	ID             int64     `bun:"id,pk,autoincrement"`
+	Scope          string    `bun:"scope,notnull,unique:translation_memory_scope_key"`
+	SourceLanguage string    `bun:"source_language,notnull,unique:translation_memory_scope_key"`
+	TargetLanguage string    `bun:"target_language,notnull,unique:translation_memory_scope_key"`
+	SourceHash     string    `bun:"source_hash,notnull,unique:translation_memory_scope_key"`
+	Source         string    `bun:"source,notnull"`
+	Target         string    `bun:"target,notnull"`
+	UpdatedAt      time.Time `bun:"updated_at,notnull,default:current_timestamp"`
+}
+
+// PostgresMemory is a translation memory backed by the translation_memory table
+type PostgresMemory struct {
+	db *bun.DB
+}
+
+// NewPostgresMemory creates the translation_memory table if needed
+func NewPostgresMemory(ctx context.Context, db *bun.DB) (*PostgresMemory, error) {
+	if _, err := db.NewCreateTable().Model((*memoryEntry)(nil)).IfNotExists().Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create translation_memory table: %w", err)
+	}
+	// tables created before memory was scoped lack the column; their segments get the empty
+	// scope, which no caller has, so they are no longer recalled
+	migrations := []string{
+		"ALTER TABLE translation_memory ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE translation_memory DROP CONSTRAINT IF EXISTS translation_memory_key",
+		"CREATE UNIQUE INDEX IF NOT EXISTS translation_memory_scope_key ON translation_memory (scope, source_language, target_language, source_hash)",
+	}
+	for _, migration := range migrations {
+		if _, err := db.ExecContext(ctx, migration); err != nil {
+			return nil, fmt.Errorf("failed to scope translation_memory: %w", err)
+		}
+	}
+	return &PostgresMemory{db: db}, nil
+}
+
+// Lookup returns the scope's stored segments for the language pair matching keys
+func (m *PostgresMemory) Lookup(ctx context.Context, scope, sourceLang, targetLang string, keys []string) ([]types.MemorySegment, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	var entries []memoryEntry
+	err := m.db.NewSelect().
+		Model(&entries).
+		Where("scope = ?", scope).
+		Where("source_language = ?", sourceLang).
+		Where("target_language = ?", targetLang).
+		Where("source_hash IN (?)", bun.In(keys)).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up translation memory: %w", err)
+	}
+
+	segments := make([]types.MemorySegment, len(entries))
+	for i, entry := range entries {
+		segments[i] = types.MemorySegment{Key: entry.SourceHash, Source: entry.Source, Target: entry.Target}
+	}
+	return segments, nil
+}
+
+// Store upserts segments for the scope and language pair; the latest translation of a segment wins
+func (m *PostgresMemory) Store(ctx context.Context, scope, sourceLang, targetLang string, segments []types.MemorySegment) error {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	entries := make([]memoryEntry, len(segments))
+	for i, segment := range segments {
+		entries[i] = memoryEntry{
+			Scope:          scope,
+			SourceLanguage: sourceLang,
+			TargetLanguage: targetLang,
+			SourceHash:     segment.Key,
+			Source:         segment.Source,
+			Target:         segment.Target,
+			UpdatedAt:      now,
+		}
+	}
+
+	_, err := m.db.NewInsert().
+		Model(&entries).
+		On("CONFLICT (scope, source_language, target_language, source_hash) DO UPDATE").
+		Set("source = EXCLUDED.source").
+		Set("target = EXCLUDED.target").
+		Set("updated_at = EXCLUDED.updated_at").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to store translation memory: %w", err)
+	}
+	return nil
+}
@@ -1,32 +1,150 @@
 package translator_provider
 
 import (
+	"code-bridge/internal/metrics"
+	"code-bridge/internal/third_party/anthropic"
 	"code-bridge/internal/third_party/gemini"
+	"code-bridge/internal/third_party/ollama"
 	codebridge_openai "code-bridge/internal/third_party/openai"
+	"code-bridge/internal/third_party/provider_http"
 	"code-bridge/pkg/types"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
 )
 
-// Factory creates translator providers based on the specified type
+// Factory creates translator providers based on the specified type.
+// Providers are created once and cached, so it is safe to call CreateProvider
+// from multiple request goroutines.
 type Factory struct {
 	config *types.Config
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	providers map[GenerativeProviderType]TranslatorProvider
+	// latency records how long each provider takes, for the fastest selection strategy
+	latency *LatencyRegistry
+	// metrics counts failed completion streams; nil records nothing
+	metrics *metrics.Metrics
 }
 
 // NewFactory creates a new provider factory
-func NewFactory(config *types.Config) *Factory {
+func NewFactory(config *types.Config, logger *zap.Logger, metrics *metrics.Metrics) *Factory {
 	return &Factory{
-		config: config,
+		config:    config,
+		logger:    logger,
+		providers: make(map[GenerativeProviderType]TranslatorProvider),
+		latency:   NewLatencyRegistry(),
+		metrics:   metrics,
 	}
 }
 
-// CreateProvider creates a translator provider based on the specified type
+// Latency returns the completion times recorded for the providers created by the factory
+func (f *Factory) Latency() *LatencyRegistry {
+	return f.latency
+}
+
+// CreateProvider returns the translator provider for the specified type,
+// creating its SDK client on first use
 func (f *Factory) CreateProvider(providerType GenerativeProviderType) (TranslatorProvider, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if provider, ok := f.providers[providerType]; ok {
+		return provider, nil
+	}
+
+	var provider TranslatorProvider
 	switch providerType {
 	case ProviderOpenAI:
-		return codebridge_openai.NewOpenAIClient(f.config.OpenAI), nil
+		provider = codebridge_openai.NewOpenAIClient(f.config.OpenAI, provider_http.NewClient(f.config.ProviderHTTP), f.logger)
 	case ProviderGemini:
-		return gemini.NewGeminiClient(f.config.Gemini), nil
+		provider = gemini.NewGeminiClient(f.config.Gemini, provider_http.NewClient(f.config.ProviderHTTP), f.logger)
+	case ProviderLocal:
+		provider = codebridge_openai.NewLocalClient(f.config.Local, provider_http.NewClient(f.config.ProviderHTTP), f.logger)
+	case ProviderAnthropic:
+		provider = anthropic.NewAnthropicClient(f.config.Anthropic, provider_http.NewClient(f.config.ProviderHTTP))
+	case ProviderOllama:
+		provider = ollama.NewOllamaClient(f.config.Ollama, provider_http.NewClient(f.config.ProviderHTTP))
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
+	provider = f.withRecovery(provider)
+	provider = &timedProvider{wrapped: wrapped{provider: provider}, providerType: providerType, latency: f.latency, metrics: f.metrics}
+
+	f.providers[providerType] = provider
+	return provider, nil
+}
+
+// withRecovery wraps client with retries and, where enabled and supported, the streaming
+// fallback. The retries go inside the fallback: a transient stream failure is retried as a
+// stream, and a non-streaming completion is only tried once the retries are used up.
+func (f *Factory) withRecovery(client TranslatorProvider) TranslatorProvider {
+	provider := WithRetry(client, f.config.Retry.MaxAttempts-1, f.config.Retry.BaseDelay, f.config.Retry.MaxRetryAfter)
+	if completer, ok := client.(Completer); ok && f.config.Translator.StreamingFallback {
+		provider = NewStreamingFallback(provider, completer)
+	}
+	return provider
+}
+
+// CreateFallback returns a provider trying providerTypes in order (see FallbackProvider),
+// or just the provider when there is one
+func (f *Factory) CreateFallback(providerTypes []GenerativeProviderType) (TranslatorProvider, error) {
+	if len(providerTypes) == 0 {
+		return nil, errors.New("no providers to fall back between")
+	}
+	providers := make([]TranslatorProvider, len(providerTypes))
+	for i, providerType := range providerTypes {
+		provider, err := f.CreateProvider(providerType)
+		if err != nil {
+			return nil, err
+		}
+		providers[i] = provider
+	}
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	// not cached with the providers it wraps, which Close closes
+	return NewFallbackProvider(providers), nil
+}
+
+// Warm connects to providerType's API ahead of its first translation and returns how long
+// that took, which is roughly what the first translation saves. Providers that cannot be
+// warmed return zero.
+func (f *Factory) Warm(ctx context.Context, providerType GenerativeProviderType) (time.Duration, error) {
+	provider, err := f.CreateProvider(providerType)
+	if err != nil {
+		return 0, err
+	}
+	warmer, ok := provider.(Warmer)
+	if !ok {
+		return 0, nil
+	}
+	start := time.Now()
+	if err := warmer.Warm(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// Close closes every provider created so far that holds resources (implements io.Closer)
+func (f *Factory) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var errs []error
+	for providerType, provider := range f.providers {
+		if closer, ok := provider.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close %s provider: %w", providerType, err))
+			}
+		}
+	}
+	clear(f.providers)
+	return errors.Join(errs...)
 }
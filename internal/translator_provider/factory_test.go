@@ -0,0 +1,43 @@
+package translator_provider
+
+import (
+	"code-bridge/pkg/types"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCreateProviderConcurrentCallsShareInstance(t *testing.T) {
+	factory := NewFactory(&types.Config{
+		OpenAI: types.OpenAIConfig{APIKey: "test", Model: "gpt-5-nano"},
+		Ollama: types.OllamaConfig{Host: "http://localhost:11434", Model: "llama3.2"},
+		Retry:  types.RetryConfig{MaxAttempts: 1},
+	}, zap.NewNop(), nil)
+	t.Cleanup(func() { _ = factory.Close() })
+
+	const callers = 64
+	for _, providerType := range []GenerativeProviderType{ProviderOpenAI, ProviderOllama} {
+		providers := make([]TranslatorProvider, callers)
+		var wg sync.WaitGroup
+		for i := range callers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				provider, err := factory.CreateProvider(providerType)
+				if err != nil {
+					t.Errorf("CreateProvider(%s): %v", providerType, err)
+					return
+				}
+				providers[i] = provider
+			}()
+		}
+		wg.Wait()
+
+		for i, provider := range providers {
+			if provider != providers[0] {
+				t.Fatalf("%s: caller %d got a different instance", providerType, i)
+			}
+		}
+	}
+}
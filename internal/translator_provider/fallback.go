@@ -0,0 +1,59 @@
+package translator_provider
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FallbackProvider tries an ordered list of providers, moving on to the next one when a
+// provider fails before it produced any output, e.g. because it is rate limited. Once
+// output has been streamed the failure is returned as is, since the client already has
+// part of that provider's response. Name, model and seed support are the first provider's.
+type FallbackProvider struct {
+	wrapped
+	providers []TranslatorProvider
+}
+
+// NewFallbackProvider creates a provider trying providers in order; it needs at least one
+func NewFallbackProvider(providers []TranslatorProvider) *FallbackProvider {
+	return &FallbackProvider{wrapped: wrapped{provider: providers[0]}, providers: providers}
+}
+
+// StreamCompletion streams from the first provider that succeeds or starts producing output
+func (f *FallbackProvider) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	var errs []error
+	for i, provider := range f.providers {
+		streamOpts := opts
+		// a model requested for the first provider means nothing to the others, which use their default
+		if i > 0 {
+			streamOpts.Model = ""
+		}
+		committed, err := streamAttempt(ctx, provider, prompt, streamOpts, onChunk)
+		if err == nil || committed || ctx.Err() != nil {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", wrapped{provider: provider}.Name(), err))
+	}
+	return &fallbackError{errs: errs}
+}
+
+// fallbackError is returned when every provider failed. Unlike errors.Join it keeps the
+// message on one line, since it ends up in an SSE data line.
+type fallbackError struct {
+	errs []error
+}
+
+func (e *fallbackError) Error() string {
+	messages := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		messages[i] = err.Error()
+	}
+	return "all providers failed: " + strings.Join(messages, "; ")
+}
+
+// Unwrap lets errors.Is and errors.As see every provider's error
+func (e *fallbackError) Unwrap() []error {
+	return e.errs
+}
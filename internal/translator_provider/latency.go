@@ -0,0 +1,73 @@
+package translator_provider
+
+import (
+	"code-bridge/internal/metrics"
+	"code-bridge/pkg/types"
+	"context"
+	"sync"
+	"time"
+)
+
+// latencySmoothing is the weight of the newest sample in a provider's average latency
+const latencySmoothing = 0.2
+
+// failureLatency is the sample recorded for a failed completion. A provider that keeps
+// failing, e.g. with a rejected key, would otherwise never be measured and, having no
+// samples, be picked first by the fastest strategy forever.
+const failureLatency = 5 * time.Minute
+
+// LatencyRegistry keeps an exponentially weighted average of how long each
+// provider takes to complete a response
+type LatencyRegistry struct {
+	mu      sync.Mutex
+	average map[GenerativeProviderType]time.Duration
+}
+
+// NewLatencyRegistry creates an empty registry
+func NewLatencyRegistry() *LatencyRegistry {
+	return &LatencyRegistry{average: make(map[GenerativeProviderType]time.Duration)}
+}
+
+// Record adds a completion time for provider
+func (r *LatencyRegistry) Record(provider GenerativeProviderType, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	average, ok := r.average[provider]
+	if !ok {
+		r.average[provider] = latency
+		return
+	}
+	r.average[provider] = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(average))
+}
+
+// Average returns provider's average latency, or false if it has no samples yet
+func (r *LatencyRegistry) Average(provider GenerativeProviderType) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	average, ok := r.average[provider]
+	return average, ok
+}
+
+// timedProvider records how long each successful completion of the wrapped provider took,
+// and counts the completions that failed, recording failureLatency for them
+type timedProvider struct {
+	wrapped
+	providerType GenerativeProviderType
+	latency      *LatencyRegistry
+	metrics      *metrics.Metrics
+}
+
+// StreamCompletion streams from the wrapped provider, recording the time taken on success
+func (t *timedProvider) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	start := time.Now()
+	err := t.provider.StreamCompletion(ctx, prompt, opts, onChunk)
+	switch {
+	case err == nil:
+		t.latency.Record(t.providerType, time.Since(start))
+	case ctx.Err() == nil:
+		// a cancelled or timed out translation is not the provider failing
+		t.latency.Record(t.providerType, failureLatency)
+		t.metrics.StreamError(string(t.providerType))
+	}
+	return err
+}
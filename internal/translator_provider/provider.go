@@ -1,16 +1,40 @@
 package translator_provider
 
-import "context"
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"fmt"
+)
 
 // TranslatorProvider defines the interface that all translation providers must implement
 type TranslatorProvider interface {
-	StreamCompletion(ctx context.Context, prompt string, onChunk func(string) error) error
+	StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error
+}
+
+// Warmer is implemented by providers that can open a connection to their API ahead of the first request
+type Warmer interface {
+	Warm(ctx context.Context) error
 }
 
 // GenerativeProviderType represents the type of translation provider
 type GenerativeProviderType string
 
 const (
-	ProviderOpenAI GenerativeProviderType = "openai"
-	ProviderGemini GenerativeProviderType = "gemini"
+	ProviderOpenAI    GenerativeProviderType = "openai"
+	ProviderGemini    GenerativeProviderType = "gemini"
+	ProviderAnthropic GenerativeProviderType = "anthropic"
+	// ProviderLocal is a local OpenAI-compatible server such as llama.cpp's llama-server
+	ProviderLocal GenerativeProviderType = "local"
+	// ProviderOllama is an Ollama server, typically on the same machine
+	ProviderOllama GenerativeProviderType = "ollama"
 )
+
+// ParseProviderType validates a provider name and returns its GenerativeProviderType
+func ParseProviderType(name string) (GenerativeProviderType, error) {
+	switch providerType := GenerativeProviderType(name); providerType {
+	case ProviderOpenAI, ProviderGemini, ProviderLocal, ProviderAnthropic, ProviderOllama:
+		return providerType, nil
+	default:
+		return "", fmt.Errorf("unsupported provider type: %s", name)
+	}
+}
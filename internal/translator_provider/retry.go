@@ -0,0 +1,150 @@
+package translator_provider
+
+import (
+	"code-bridge/internal/third_party/provider_http"
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/openai/openai-go/v3"
+	"google.golang.org/genai"
+)
+
+// ErrCodeRateLimited classifies failures of providers asking to wait longer than MAX_RETRY_AFTER
+const ErrCodeRateLimited = "rate_limited"
+
+// Retrying wraps a provider so that a stream failing with a transient error (rate limiting,
+// a server error, a dropped connection) before it produced any output is retried with
+// exponential backoff, or after the wait the provider asked for with Retry-After. Other
+// failures, and failures after output started, are returned as is.
+type Retrying struct {
+	wrapped
+	maxRetries    int
+	baseDelay     time.Duration
+	maxRetryAfter time.Duration
+}
+
+// WithRetry wraps provider to retry up to maxRetries times, waiting about baseDelay before
+// the first retry and twice as long before each one after it. A Retry-After from the provider
+// replaces that wait up to maxRetryAfter (zero for no cap); a longer one fails the stream with
+// a RateLimitedError. Without retries provider is returned unwrapped.
+func WithRetry(provider TranslatorProvider, maxRetries int, baseDelay, maxRetryAfter time.Duration) TranslatorProvider {
+	if maxRetries <= 0 {
+		return provider
+	}
+	return &Retrying{wrapped: wrapped{provider: provider}, maxRetries: maxRetries, baseDelay: baseDelay, maxRetryAfter: maxRetryAfter}
+}
+
+// RateLimitedError is returned when a provider asks to be retried later than MAX_RETRY_AFTER
+// allows, so the caller or a fallback provider can move on instead of holding the job
+type RateLimitedError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s: provider asked to retry after %s: %v", ErrCodeRateLimited, e.RetryAfter, e.Err)
+}
+
+// Unwrap returns the provider's error
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the machine-readable error code
+func (e *RateLimitedError) Code() string {
+	return ErrCodeRateLimited
+}
+
+// StreamCompletion streams from the wrapped provider, retrying transient failures
+func (r *Retrying) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	for attempt := 0; ; attempt++ {
+		committed, err := streamAttempt(ctx, r.provider, prompt, opts, onChunk)
+		if err == nil || committed || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+		delay := backoff(r.baseDelay, attempt)
+		if wait := retryAfter(err); wait > 0 {
+			if r.maxRetryAfter > 0 && wait > r.maxRetryAfter {
+				return &RateLimitedError{RetryAfter: wait, Err: err}
+			}
+			delay = wait
+		}
+		if attempt == r.maxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the wait before retry attempt+1: baseDelay doubled for every earlier
+// retry, of which a random half is kept so concurrent jobs don't retry in lockstep
+func backoff(baseDelay time.Duration, attempt int) time.Duration {
+	delay := baseDelay << attempt
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryable reports whether err is likely to go away by trying again: rate limiting,
+// server errors and network failures are, while e.g. a rejected API key or an invalid
+// request are not. Cancellation is never retried; callers check their context first.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if status := statusCode(err); status != 0 {
+		return status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= http.StatusInternalServerError
+	}
+	// the request's own deadline was ruled out by the caller, so this one timed out lower down
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryAfter returns the wait a provider API asked for with Retry-After, or 0 if err carries none
+func retryAfter(err error) time.Duration {
+	var openaiErr *openai.Error
+	var statusErr *provider_http.StatusError
+	switch {
+	case errors.As(err, &openaiErr) && openaiErr.Response != nil:
+		return provider_http.ParseRetryAfter(openaiErr.Response.Header)
+	case errors.As(err, &statusErr):
+		return statusErr.RetryAfter
+	default:
+		return 0
+	}
+}
+
+// statusCode returns the HTTP status a provider API failed with, or 0 if err carries none
+func statusCode(err error) int {
+	var openaiErr *openai.Error
+	var geminiErr genai.APIError
+	var statusErr *provider_http.StatusError
+	switch {
+	case errors.As(err, &openaiErr):
+		return openaiErr.StatusCode
+	case errors.As(err, &geminiErr):
+		return geminiErr.Code
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,65 @@
+package translator_provider
+
+import (
+	"code-bridge/internal/code_translator"
+	"code-bridge/internal/third_party/provider_http"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// rateLimited returns a 429 asking to be retried after wait
+func rateLimited(wait time.Duration) error {
+	return &provider_http.StatusError{Provider: "test", StatusCode: http.StatusTooManyRequests, RetryAfter: wait}
+}
+
+func TestRetryHonorsRetryAfterUpToCap(t *testing.T) {
+	client := &flakyProvider{err: rateLimited(50 * time.Millisecond), failures: 1, response: "translated"}
+	provider := WithRetry(client, 2, time.Millisecond, time.Second)
+
+	start := time.Now()
+	got, err := complete(t, provider)
+	if err != nil {
+		t.Fatalf("StreamCompletion: %v", err)
+	}
+	if got != "translated" || client.streams != 2 {
+		t.Errorf("output = %q after %d streams, want %q after 2", got, client.streams, "translated")
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("retried after %s, want at least the 50ms asked for", waited)
+	}
+}
+
+func TestRetryFailsFastBeyondRetryAfterCap(t *testing.T) {
+	client := &flakyProvider{err: rateLimited(10 * time.Minute), failures: 1, response: "translated"}
+	provider := WithRetry(client, 2, time.Millisecond, time.Second)
+
+	start := time.Now()
+	_, err := complete(t, provider)
+	var limited *RateLimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("error = %v, want a RateLimitedError", err)
+	}
+	if limited.RetryAfter != 10*time.Minute {
+		t.Errorf("RetryAfter = %s, want 10m", limited.RetryAfter)
+	}
+	if code := code_translator.ErrorCode(err); code != ErrCodeRateLimited {
+		t.Errorf("ErrorCode = %q, want %q", code, ErrCodeRateLimited)
+	}
+	if client.streams != 1 || time.Since(start) > time.Second {
+		t.Errorf("%d streams in %s, want one stream and no wait", client.streams, time.Since(start))
+	}
+}
+
+func TestRetryWithoutCapWaitsAsAsked(t *testing.T) {
+	client := &flakyProvider{err: rateLimited(20 * time.Millisecond), failures: 1, response: "translated"}
+	provider := WithRetry(client, 1, time.Millisecond, 0)
+
+	if _, err := complete(t, provider); err != nil {
+		t.Fatalf("StreamCompletion: %v", err)
+	}
+	if client.streams != 2 {
+		t.Errorf("streams = %d, want 2", client.streams)
+	}
+}
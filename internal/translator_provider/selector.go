@@ -0,0 +1,110 @@
+package translator_provider
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Provider selection strategies accepted by PROVIDER_STRATEGY
+const (
+	StrategyCheapest   = "cheapest"
+	StrategyFastest    = "fastest"
+	StrategyRoundRobin = "round_robin"
+)
+
+// DefaultPrices are the USD prices per million output tokens of each provider's default
+// model, used by the cheapest strategy unless PROVIDER_PRICES overrides them
+var DefaultPrices = map[GenerativeProviderType]float64{
+	ProviderOpenAI:    0.40,
+	ProviderGemini:    2.50,
+	ProviderLocal:     0,
+	ProviderAnthropic: 15.00,
+	ProviderOllama:    0,
+}
+
+// SelectionPolicy picks one of the candidate providers for a request
+type SelectionPolicy interface {
+	Pick(candidates []GenerativeProviderType) GenerativeProviderType
+}
+
+// ProviderSelector picks the provider for requests that don't name one
+type ProviderSelector struct {
+	candidates []GenerativeProviderType
+	policy     SelectionPolicy
+}
+
+// NewProviderSelector creates a selector choosing among candidates with policy
+func NewProviderSelector(candidates []GenerativeProviderType, policy SelectionPolicy) (*ProviderSelector, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("provider selection needs at least one provider")
+	}
+	return &ProviderSelector{candidates: candidates, policy: policy}, nil
+}
+
+// Select returns the name of the provider to use for the next request
+func (s *ProviderSelector) Select() string {
+	return string(s.policy.Pick(s.candidates))
+}
+
+// NewSelectionPolicy returns the policy for a PROVIDER_STRATEGY value. prices is
+// consulted by the cheapest strategy and latency by the fastest.
+func NewSelectionPolicy(strategy string, prices map[GenerativeProviderType]float64, latency *LatencyRegistry) (SelectionPolicy, error) {
+	switch strategy {
+	case StrategyCheapest:
+		return cheapestPolicy{prices: prices}, nil
+	case StrategyFastest:
+		return fastestPolicy{latency: latency}, nil
+	case StrategyRoundRobin:
+		return &roundRobinPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider strategy: %s", strategy)
+	}
+}
+
+// cheapestPolicy picks the candidate with the lowest price; unpriced candidates are never picked over priced ones
+type cheapestPolicy struct {
+	prices map[GenerativeProviderType]float64
+}
+
+func (p cheapestPolicy) Pick(candidates []GenerativeProviderType) GenerativeProviderType {
+	best := candidates[0]
+	bestPrice, bestKnown := p.prices[best]
+	for _, candidate := range candidates[1:] {
+		price, known := p.prices[candidate]
+		if known && (!bestKnown || price < bestPrice) {
+			best, bestPrice, bestKnown = candidate, price, true
+		}
+	}
+	return best
+}
+
+// fastestPolicy picks the candidate with the lowest average latency. Candidates without
+// samples are picked first, so every provider is measured before it is compared; a
+// failed completion counts as a failureLatency sample, so a failing one is measured too.
+type fastestPolicy struct {
+	latency *LatencyRegistry
+}
+
+func (p fastestPolicy) Pick(candidates []GenerativeProviderType) GenerativeProviderType {
+	var best GenerativeProviderType
+	var bestLatency int64
+	for _, candidate := range candidates {
+		latency, ok := p.latency.Average(candidate)
+		if !ok {
+			return candidate
+		}
+		if best == "" || int64(latency) < bestLatency {
+			best, bestLatency = candidate, int64(latency)
+		}
+	}
+	return best
+}
+
+// roundRobinPolicy cycles through the candidates
+type roundRobinPolicy struct {
+	next atomic.Uint64
+}
+
+func (p *roundRobinPolicy) Pick(candidates []GenerativeProviderType) GenerativeProviderType {
+	return candidates[(p.next.Add(1)-1)%uint64(len(candidates))]
+}
@@ -0,0 +1,52 @@
+package translator_provider
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFastestPolicySkipsFailingUnmeasuredProvider(t *testing.T) {
+	latency := NewLatencyRegistry()
+	policy := fastestPolicy{latency: latency}
+	candidates := []GenerativeProviderType{ProviderOpenAI, ProviderGemini}
+	latency.Record(ProviderGemini, 3*time.Second)
+
+	// openai has never succeeded, so it has no samples and is tried first
+	if got := policy.Pick(candidates); got != ProviderOpenAI {
+		t.Fatalf("Pick = %s, want the unmeasured openai", got)
+	}
+
+	failing := &timedProvider{
+		wrapped:      wrapped{provider: &flakyProvider{err: errors.New("invalid API key"), failures: 1}},
+		providerType: ProviderOpenAI,
+		latency:      latency,
+	}
+	if _, err := complete(t, failing); err == nil {
+		t.Fatal("StreamCompletion succeeded, want the provider's error")
+	}
+
+	for range 10 {
+		if got := policy.Pick(candidates); got != ProviderGemini {
+			t.Fatalf("Pick = %s after openai failed, want gemini", got)
+		}
+	}
+}
+
+func TestTimedProviderIgnoresCancelledCompletions(t *testing.T) {
+	latency := NewLatencyRegistry()
+	provider := &timedProvider{
+		wrapped:      wrapped{provider: &flakyProvider{err: context.Canceled, failures: 1}},
+		providerType: ProviderOpenAI,
+		latency:      latency,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = provider.StreamCompletion(ctx, "prompt", types.CompletionOptions{}, func(string) error { return nil })
+
+	if _, ok := latency.Average(ProviderOpenAI); ok {
+		t.Error("a cancelled completion was recorded as a sample")
+	}
+}
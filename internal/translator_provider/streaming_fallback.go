@@ -0,0 +1,69 @@
+package translator_provider
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Completer is implemented by providers that can return a whole completion without streaming
+type Completer interface {
+	Completion(ctx context.Context, prompt string, opts types.CompletionOptions) (string, error)
+}
+
+// StreamingFallback wraps a provider so that a stream failing before it produced any
+// output, because streaming is unavailable or the stream broke, is retried as a single
+// non-streaming completion, delivered as one chunk. Other failures, failures after output
+// started, and those caused by the chunk callback are returned as is.
+type StreamingFallback struct {
+	wrapped
+	completer Completer
+}
+
+// NewStreamingFallback wraps provider, which must also implement Completer
+func NewStreamingFallback(provider TranslatorProvider, completer Completer) *StreamingFallback {
+	return &StreamingFallback{wrapped: wrapped{provider: provider}, completer: completer}
+}
+
+// StreamCompletion streams from the wrapped provider, falling back to Completion
+func (f *StreamingFallback) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	committed, err := streamAttempt(ctx, f.provider, prompt, opts, onChunk)
+	if err == nil || committed || ctx.Err() != nil || !streamingFailed(err) {
+		return err
+	}
+
+	text, completionErr := f.completer.Completion(ctx, prompt, opts)
+	if completionErr != nil {
+		// the streaming error is usually the more telling one
+		return err
+	}
+	if text != "" {
+		if err := opts.Emit(types.EventOutputStarted); err != nil {
+			return err
+		}
+		if err := onChunk(text); err != nil {
+			return err
+		}
+	}
+	return opts.Emit(types.EventResponseCompleted)
+}
+
+// streamingFailed reports whether err means streaming itself failed, so a non-streaming
+// completion may still succeed: the endpoint doesn't support streaming (404, 405 or 501,
+// e.g. from a proxy on the path), or the stream broke while it was read. Other API errors,
+// such as a rejected key, an invalid request or rate limiting, would fail the completion too.
+func streamingFailed(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	switch statusCode(err) {
+	case 0:
+		// no API status, so the request went through and reading or decoding the stream failed
+		return true
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,126 @@
+package translator_provider
+
+import (
+	"code-bridge/internal/third_party/provider_http"
+	"code-bridge/pkg/types"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// flakyProvider fails as many streams as failures with err, then streams response;
+// its Completion always returns response
+type flakyProvider struct {
+	err         error
+	failures    int
+	response    string
+	streams     int
+	completions int
+}
+
+func (p *flakyProvider) StreamCompletion(ctx context.Context, prompt string, opts types.CompletionOptions, onChunk func(string) error) error {
+	p.streams++
+	if p.streams <= p.failures {
+		return p.err
+	}
+	return onChunk(p.response)
+}
+
+func (p *flakyProvider) Completion(ctx context.Context, prompt string, opts types.CompletionOptions) (string, error) {
+	p.completions++
+	return p.response, nil
+}
+
+// newRecoveringFactory returns a factory trying each stream three times, with the streaming fallback on
+func newRecoveringFactory() *Factory {
+	return NewFactory(&types.Config{
+		Retry:      types.RetryConfig{MaxAttempts: 3},
+		Translator: types.TranslatorConfig{StreamingFallback: true},
+	}, zap.NewNop(), nil)
+}
+
+// complete streams prompt from provider and returns the chunks
+func complete(t *testing.T, provider TranslatorProvider) (string, error) {
+	t.Helper()
+	var got string
+	err := provider.StreamCompletion(context.Background(), "prompt", types.CompletionOptions{}, func(chunk string) error {
+		got += chunk
+		return nil
+	})
+	return got, err
+}
+
+func TestRetriesRunBeforeStreamingFallback(t *testing.T) {
+	client := &flakyProvider{
+		err:      &provider_http.StatusError{Provider: "test", StatusCode: http.StatusTooManyRequests},
+		failures: 2,
+		response: "translated",
+	}
+	got, err := complete(t, newRecoveringFactory().withRecovery(client))
+	if err != nil {
+		t.Fatalf("StreamCompletion: %v", err)
+	}
+	if got != "translated" {
+		t.Errorf("output = %q, want %q", got, "translated")
+	}
+	if client.streams != 3 || client.completions != 0 {
+		t.Errorf("streams = %d, completions = %d; want 3 streams and no completion", client.streams, client.completions)
+	}
+}
+
+func TestStreamingFallbackAfterRetriesAreUsedUp(t *testing.T) {
+	client := &flakyProvider{err: io.ErrUnexpectedEOF, failures: 10, response: "translated"}
+	got, err := complete(t, newRecoveringFactory().withRecovery(client))
+	if err != nil {
+		t.Fatalf("StreamCompletion: %v", err)
+	}
+	if got != "translated" {
+		t.Errorf("output = %q, want %q", got, "translated")
+	}
+	if client.streams != 3 || client.completions != 1 {
+		t.Errorf("streams = %d, completions = %d; want 3 streams and one completion", client.streams, client.completions)
+	}
+}
+
+func TestStreamingFallbackOnlyForStreamingFailures(t *testing.T) {
+	status := func(code int) error {
+		return &provider_http.StatusError{Provider: "test", StatusCode: code}
+	}
+	tests := []struct {
+		name     string
+		err      error
+		fallback bool
+	}{
+		{"not found", status(http.StatusNotFound), true},
+		{"method not allowed", status(http.StatusMethodNotAllowed), true},
+		{"not implemented", status(http.StatusNotImplemented), true},
+		{"stream cut off", io.ErrUnexpectedEOF, true},
+		{"broken event", errors.New("local stream failed: unexpected end of JSON input"), true},
+		{"bad request", status(http.StatusBadRequest), false},
+		{"unauthorized", status(http.StatusUnauthorized), false},
+		{"forbidden", status(http.StatusForbidden), false},
+		{"rate limited", status(http.StatusTooManyRequests), false},
+		{"server error", status(http.StatusInternalServerError), false},
+		{"canceled lower down", context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &flakyProvider{err: tt.err, failures: 1, response: "translated"}
+			got, err := complete(t, NewStreamingFallback(client, client))
+
+			if !tt.fallback {
+				if !errors.Is(err, tt.err) || client.completions != 0 {
+					t.Errorf("error = %v, completions = %d; want the stream error and no completion", err, client.completions)
+				}
+				return
+			}
+			if err != nil || got != "translated" || client.completions != 1 {
+				t.Errorf("error = %v, output = %q, completions = %d; want the completion's output", err, got, client.completions)
+			}
+		})
+	}
+}
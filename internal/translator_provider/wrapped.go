@@ -0,0 +1,71 @@
+package translator_provider
+
+import (
+	"code-bridge/pkg/types"
+	"context"
+	"io"
+)
+
+// wrapped forwards a provider's optional capabilities, so callers of a wrapper
+// around it still see its name, model, seed support and Close
+type wrapped struct {
+	provider TranslatorProvider
+}
+
+// Name returns the wrapped provider's name
+func (w wrapped) Name() string {
+	if describer, ok := w.provider.(interface{ Name() string }); ok {
+		return describer.Name()
+	}
+	return ""
+}
+
+// Model returns the wrapped provider's default model
+func (w wrapped) Model() string {
+	if describer, ok := w.provider.(interface{ Model() string }); ok {
+		return describer.Model()
+	}
+	return ""
+}
+
+// SupportsSeed reports whether the wrapped provider honours seeds
+func (w wrapped) SupportsSeed() bool {
+	supporter, ok := w.provider.(interface{ SupportsSeed() bool })
+	return ok && supporter.SupportsSeed()
+}
+
+// Close closes the wrapped provider if it holds resources
+func (w wrapped) Close() error {
+	if closer, ok := w.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Warm warms the wrapped provider's connection if it supports that
+func (w wrapped) Warm(ctx context.Context) error {
+	if warmer, ok := w.provider.(Warmer); ok {
+		return warmer.Warm(ctx)
+	}
+	return nil
+}
+
+// streamAttempt streams one completion from provider and reports whether it went too far
+// to be repeated: output reached onChunk, or onChunk or opts.OnEvent failed. Wrappers
+// retrying or falling back only do so for failures of attempts that were not committed.
+func streamAttempt(ctx context.Context, provider TranslatorProvider, prompt string, opts types.CompletionOptions, onChunk func(string) error) (committed bool, err error) {
+	if onEvent := opts.OnEvent; onEvent != nil {
+		opts.OnEvent = func(event types.CompletionEvent) error {
+			if err := onEvent(event); err != nil {
+				committed = true
+				return err
+			}
+			return nil
+		}
+	}
+	err = provider.StreamCompletion(ctx, prompt, opts, func(chunk string) error {
+		committed = true
+		return onChunk(chunk)
+	})
+	return committed, err
+}
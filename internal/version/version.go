@@ -0,0 +1,32 @@
+package version
+
+import "runtime"
+
+// Build metadata, set at build time with
+// -ldflags "-X code-bridge/internal/version.Version=... -X code-bridge/internal/version.Commit=..."
+var (
+	// Version is the release version, e.g. a git tag
+	Version = "dev"
+	// Commit is the git commit the binary was built from
+	Commit = "unknown"
+	// BuildTime is when the binary was built, in RFC 3339
+	BuildTime = "unknown"
+)
+
+// BuildInfo describes the running build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Info returns the build metadata along with the Go runtime version
+func Info() BuildInfo {
+	return BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,347 @@
+package worker_pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrQueueFull is returned by Submit when every worker is busy and the queue has no room
+var ErrQueueFull = errors.New("translation queue is full")
+
+// ErrPoolStopped is returned by Submit once Stop has been called
+var ErrPoolStopped = errors.New("worker pool is stopped")
+
+// maxTrackedClients bounds the per-client wait statistics; idle clients are dropped beyond it
+const maxTrackedClients = 1000
+
+// Job is a unit of work run by a pool worker
+type Job func()
+
+type task struct {
+	job        Job
+	enqueuedAt time.Time
+}
+
+// level holds the queued jobs of one priority, per client in round-robin order
+type level struct {
+	queues map[string][]task
+	order  []string // clients with queued jobs, in round-robin order
+	next   int
+	queued int
+}
+
+// Pool runs submitted jobs on a fixed number of workers. Jobs that have to wait
+// are queued by priority, then per client and dequeued round-robin across clients,
+// so one client submitting many jobs cannot starve another client's single job.
+// Waiting jobs age: every agingStep waited counts as one priority level higher,
+// so low priority jobs are not starved by a steady stream of high priority ones.
+type Pool struct {
+	logger    *zap.Logger
+	workers   int
+	capacity  int
+	agingStep time.Duration
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	levels  [priorityCount]level
+	queued  int
+	stopped bool
+	wg      sync.WaitGroup
+
+	// wait statistics, guarded by mu
+	started   int64
+	totalWait time.Duration
+	maxWait   time.Duration
+	clients   map[string]*clientStats
+
+	active    atomic.Int64
+	completed atomic.Int64
+}
+
+type clientStats struct {
+	queued    int
+	started   int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// Stats is a snapshot of pool load
+type Stats struct {
+	Workers       int   `json:"workers"`
+	Active        int64 `json:"active"`
+	QueueDepth    int   `json:"queue_depth"`
+	QueueCapacity int   `json:"queue_capacity"`
+	Completed     int64 `json:"completed"`
+	// QueueDepthByPriority counts queued jobs per priority name
+	QueueDepthByPriority map[string]int `json:"queue_depth_by_priority"`
+	AvgWaitMs            float64        `json:"avg_wait_ms"`
+	MaxWaitMs            float64        `json:"max_wait_ms"`
+	Clients              []ClientStats  `json:"clients"`
+}
+
+// ClientStats reports queueing for a single client
+type ClientStats struct {
+	Client    string  `json:"client"`
+	Queued    int     `json:"queued"`
+	Started   int64   `json:"started"`
+	AvgWaitMs float64 `json:"avg_wait_ms"`
+	MaxWaitMs float64 `json:"max_wait_ms"`
+}
+
+// NewPool creates a pool with the given number of workers and total queue capacity.
+// A queued job is treated as one priority higher for every agingStep it has waited;
+// zero disables aging. Workers are not started until Start is called.
+func NewPool(logger *zap.Logger, workers, queueSize int, agingStep time.Duration) *Pool {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	p := &Pool{
+		logger:    logger,
+		workers:   workers,
+		capacity:  queueSize,
+		agingStep: agingStep,
+		clients:   make(map[string]*clientStats),
+	}
+	for i := range p.levels {
+		p.levels[i].queues = make(map[string][]task)
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// Start launches the workers
+func (p *Pool) Start() {
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		for p.queued == 0 && !p.stopped {
+			p.cond.Wait()
+		}
+		if p.queued == 0 {
+			// stopped and drained
+			p.mu.Unlock()
+			return
+		}
+		client, t := p.dequeue()
+		p.recordWait(client, time.Since(t.enqueuedAt))
+		p.mu.Unlock()
+
+		p.active.Add(1)
+		p.run(t.job)
+		p.active.Add(-1)
+		p.completed.Add(1)
+	}
+}
+
+// dequeue takes the next job from the level with the highest aged priority; p.mu must be held
+func (p *Pool) dequeue() (string, task) {
+	client, t := p.levels[p.pickLevel(time.Now())].dequeue()
+	p.queued--
+	if stats, ok := p.clients[client]; ok {
+		stats.queued--
+	}
+	return client, t
+}
+
+// pickLevel returns the non-empty level whose oldest job has the highest priority once
+// aged; ties go to the higher base priority. p.mu must be held and a job must be queued.
+func (p *Pool) pickLevel(now time.Time) Priority {
+	best, bestScore := Priority(-1), -1
+	for priority := PriorityHigh; priority >= PriorityLow; priority-- {
+		lvl := &p.levels[priority]
+		if lvl.queued == 0 {
+			continue
+		}
+		score := int(priority)
+		if p.agingStep > 0 {
+			score += int(now.Sub(lvl.oldest()) / p.agingStep)
+		}
+		if score > bestScore {
+			best, bestScore = priority, score
+		}
+	}
+	return best
+}
+
+// oldest returns when the longest-waiting job of the level was queued
+func (l *level) oldest() time.Time {
+	var oldest time.Time
+	for _, queue := range l.queues {
+		if enqueuedAt := queue[0].enqueuedAt; oldest.IsZero() || enqueuedAt.Before(oldest) {
+			oldest = enqueuedAt
+		}
+	}
+	return oldest
+}
+
+// push appends a job to client's queue
+func (l *level) push(client string, t task) {
+	if _, ok := l.queues[client]; !ok {
+		l.order = append(l.order, client)
+	}
+	l.queues[client] = append(l.queues[client], t)
+	l.queued++
+}
+
+// dequeue takes the oldest job of the next client in round-robin order
+func (l *level) dequeue() (string, task) {
+	client := l.order[l.next]
+	queue := l.queues[client]
+	t := queue[0]
+
+	if len(queue) == 1 {
+		delete(l.queues, client)
+		l.order = append(l.order[:l.next], l.order[l.next+1:]...)
+	} else {
+		l.queues[client] = queue[1:]
+		l.next++
+	}
+	if l.next >= len(l.order) {
+		l.next = 0
+	}
+	l.queued--
+	return client, t
+}
+
+// run executes a job, keeping a panicking job from taking the worker down with it
+func (p *Pool) run(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error("worker pool job panicked", zap.Any("panic", r))
+		}
+	}()
+	job()
+}
+
+// recordWait adds a job's queue wait to the pool and client statistics; p.mu must be held
+func (p *Pool) recordWait(client string, wait time.Duration) {
+	p.started++
+	p.totalWait += wait
+	p.maxWait = max(p.maxWait, wait)
+
+	if stats, ok := p.clients[client]; ok {
+		stats.started++
+		stats.totalWait += wait
+		stats.maxWait = max(stats.maxWait, wait)
+	}
+}
+
+// Submit queues a job for client at the given priority without blocking. It returns
+// ErrQueueFull when the queue is at capacity and ErrPoolStopped after Stop.
+func (p *Pool) Submit(client string, priority Priority, job Job) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return ErrPoolStopped
+	}
+	if p.queued >= p.capacity {
+		return ErrQueueFull
+	}
+
+	p.levels[priority.clamp()].push(client, task{job: job, enqueuedAt: time.Now()})
+	p.queued++
+	p.clientStats(client).queued++
+
+	p.cond.Signal()
+	return nil
+}
+
+// clientStats returns the statistics entry for client, creating it and dropping
+// idle clients when too many are tracked; p.mu must be held
+func (p *Pool) clientStats(client string) *clientStats {
+	if stats, ok := p.clients[client]; ok {
+		return stats
+	}
+	if len(p.clients) >= maxTrackedClients {
+		for id, stats := range p.clients {
+			if stats.queued == 0 {
+				delete(p.clients, id)
+			}
+		}
+	}
+	stats := &clientStats{}
+	p.clients[client] = stats
+	return stats
+}
+
+// Stop stops accepting jobs and waits for queued and running jobs to finish,
+// or for ctx to be done, whichever comes first
+func (p *Pool) Stop(ctx context.Context) error {
+	p.mu.Lock()
+	p.stopped = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns current queue depth, worker usage and queue wait times, overall and per client
+func (p *Pool) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := Stats{
+		Workers:       p.workers,
+		Active:        p.active.Load(),
+		QueueDepth:    p.queued,
+		QueueCapacity: p.capacity,
+		Completed:     p.completed.Load(),
+
+		QueueDepthByPriority: make(map[string]int, priorityCount),
+		AvgWaitMs:            averageMs(p.totalWait, p.started),
+		MaxWaitMs:            durationMs(p.maxWait),
+		Clients:              make([]ClientStats, 0, len(p.clients)),
+	}
+	for priority := range p.levels {
+		stats.QueueDepthByPriority[Priority(priority).String()] = p.levels[priority].queued
+	}
+	for client, c := range p.clients {
+		stats.Clients = append(stats.Clients, ClientStats{
+			Client:    client,
+			Queued:    c.queued,
+			Started:   c.started,
+			AvgWaitMs: averageMs(c.totalWait, c.started),
+			MaxWaitMs: durationMs(c.maxWait),
+		})
+	}
+	sort.Slice(stats.Clients, func(i, j int) bool { return stats.Clients[i].Client < stats.Clients[j].Client })
+	return stats
+}
+
+func averageMs(total time.Duration, count int64) float64 {
+	if count == 0 {
+		return 0
+	}
+	return durationMs(total / time.Duration(count))
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
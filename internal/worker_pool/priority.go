@@ -0,0 +1,38 @@
+package worker_pool
+
+import "fmt"
+
+// Priority orders queued jobs; higher priorities are dequeued first
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	priorityCount = int(PriorityHigh) + 1
+)
+
+var priorityNames = [priorityCount]string{"low", "normal", "high"}
+
+// ParsePriority maps a request's priority name to a Priority; empty means normal
+func ParsePriority(name string) (Priority, error) {
+	if name == "" {
+		return PriorityNormal, nil
+	}
+	for i, candidate := range priorityNames {
+		if name == candidate {
+			return Priority(i), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid priority %q: must be low, normal or high", name)
+}
+
+func (p Priority) String() string {
+	return priorityNames[p.clamp()]
+}
+
+// clamp maps out-of-range values to the nearest valid priority
+func (p Priority) clamp() Priority {
+	return min(max(p, PriorityLow), PriorityHigh)
+}
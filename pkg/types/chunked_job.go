@@ -0,0 +1,31 @@
+package types
+
+import "time"
+
+// ChunkedJob is a large-file translation done one part at a time, persisted so it
+// can be resumed from its last completed part after an interruption
+type ChunkedJob struct {
+	ID     string
+	Client string
+	// Owner is the session that created the job; it may resume the job without a resume token
+	Owner   string
+	Request TranslateRequest
+	// Parts are the line ranges of Request.Code translated one at a time, in order
+	Parts []PartRange
+	// Translated holds the translations of the completed parts, in order
+	Translated []string
+	CreatedAt  time.Time
+	// CompletedAt is when the last of the completed parts was saved; zero before the first
+	CompletedAt time.Time
+}
+
+// Complete reports whether every part has been translated
+func (j *ChunkedJob) Complete() bool {
+	return len(j.Translated) >= len(j.Parts)
+}
+
+// PartRange is the 1-based, inclusive line range of one part of a chunked job
+type PartRange struct {
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+}
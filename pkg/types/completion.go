@@ -0,0 +1,54 @@
+package types
+
+// CompletionOptions holds per-request generation settings passed down to providers.
+// Zero values mean "use the provider default".
+type CompletionOptions struct {
+	// Model overrides the provider's default model
+	Model string
+	// Temperature controls sampling randomness (0.0-2.0)
+	Temperature *float64
+	// Seed requests deterministic sampling from providers that support it
+	Seed *int64
+	// MaxOutputTokens caps the length of the response
+	MaxOutputTokens *int64
+	// StopSequences end generation when any of them is produced
+	StopSequences []string
+	// OnMetadata, when set, receives what the provider reported about the
+	// completion once the stream has finished
+	OnMetadata func(CompletionMetadata)
+	// OnEvent, when set, receives the provider's lifecycle signals as they happen.
+	// Returning an error aborts the completion like an error from the chunk callback.
+	OnEvent func(CompletionEvent) error
+}
+
+// CompletionEvent is a lifecycle signal from a provider stream; it carries no content
+type CompletionEvent string
+
+const (
+	// EventResponseStarted is sent when the provider's first stream event arrives
+	EventResponseStarted CompletionEvent = "response_started"
+	// EventOutputStarted is sent with the first piece of generated text
+	EventOutputStarted CompletionEvent = "output_started"
+	// EventResponseCompleted is sent when the provider reports a finish reason
+	EventResponseCompleted CompletionEvent = "response_completed"
+)
+
+// Emit passes event to OnEvent if it is set
+func (o CompletionOptions) Emit(event CompletionEvent) error {
+	if o.OnEvent == nil {
+		return nil
+	}
+	return o.OnEvent(event)
+}
+
+// CompletionMetadata describes a finished completion as reported by the provider.
+// Fields the provider doesn't report are left empty.
+type CompletionMetadata struct {
+	Provider         string `json:"provider"`
+	Model            string `json:"model,omitempty"`
+	ResponseID       string `json:"response_id,omitempty"`
+	FinishReason     string `json:"finish_reason,omitempty"`
+	PromptTokens     int64  `json:"prompt_tokens,omitempty"`
+	CompletionTokens int64  `json:"completion_tokens,omitempty"`
+	TotalTokens      int64  `json:"total_tokens,omitempty"`
+}
@@ -1,18 +1,40 @@
 package types
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/spf13/viper"
 	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	OpenAI   OpenAIConfig
-	Gemini   GeminiConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	OpenAI       OpenAIConfig
+	Gemini       GeminiConfig
+	Local        LocalLLMConfig
+	Anthropic    AnthropicConfig
+	Ollama       OllamaConfig
+	ProviderHTTP ProviderHTTPConfig
+	Retry        RetryConfig
+	Translator   TranslatorConfig
+	SourceURL    SourceFetchConfig
+	SSE          SSEConfig
+	WorkerPool   WorkerPoolConfig
+	Models       ModelConfig
+	Selection    ProviderSelectionConfig
+	Abuse        AbuseConfig
+	DeadLetter   DeadLetterConfig
+	Metadata     RequestMetadataConfig
+	Sections     SectionsConfig
+	Features     FeatureFlags
 }
 
 type ServerConfig struct {
@@ -21,8 +43,32 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `yaml:"read_timeout"`
 	WriteTimeout    time.Duration `yaml:"write_timeout"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
-	AppEnv          string
-	LogLevel        string
+	// RequestTimeout bounds every request except SSE streams
+	RequestTimeout time.Duration
+	AppEnv         string
+	LogLevel       string
+	// LogFormat is json (default) or console
+	LogFormat string
+	// Provider is the default translation provider: openai, gemini (default), local, anthropic or ollama
+	Provider string
+	// Providers, when set, replaces Provider with providers tried in order, each used when the
+	// ones before it fail without producing output
+	Providers []string
+	// AllowedOrigins are the origins browsers may call the API from; "*" allows any origin,
+	// without credentials. Empty allows only same-origin pages.
+	AllowedOrigins []string
+	// APIKeys are the keys accepted by the translation endpoints; empty disables authentication
+	APIKeys []string
+	// AdminAPIKeys are the keys accepted by the /admin endpoints; they default to APIKeys
+	AdminAPIKeys []string
+}
+
+// DefaultProvider returns the provider used first for requests that don't name one
+func (c ServerConfig) DefaultProvider() string {
+	if len(c.Providers) > 0 {
+		return c.Providers[0]
+	}
+	return c.Provider
 }
 
 type DatabaseConfig struct {
@@ -36,10 +82,208 @@ type DatabaseConfig struct {
 
 type OpenAIConfig struct {
 	APIKey string
+	// Model is used for requests that don't name one
+	Model string
 }
 
 type GeminiConfig struct {
 	APIKey string
+	// Model is used for requests that don't name one
+	Model string
+}
+
+// LocalLLMConfig points at a local OpenAI-compatible server such as llama.cpp's llama-server
+type LocalLLMConfig struct {
+	BaseURL string
+	Model   string
+	APIKey  string
+}
+
+type AnthropicConfig struct {
+	APIKey string
+	Model  string
+}
+
+// OllamaConfig points at an Ollama server, for translating without sending code off the machine
+type OllamaConfig struct {
+	Host  string
+	Model string
+}
+
+type ProviderHTTPConfig struct {
+	// MaxIdleConns is how many idle connections each provider client keeps open for reuse
+	MaxIdleConns int
+	// IdleConnTimeout closes connections that stayed idle this long
+	IdleConnTimeout time.Duration
+	// KeepAlive is the TCP keep-alive interval of provider connections
+	KeepAlive time.Duration
+	// Warm connects to the default provider at startup so the first translation skips connection setup
+	Warm bool
+}
+
+type RetryConfig struct {
+	// MaxAttempts is how many times a provider is tried when it fails transiently before any
+	// output; 1 disables retries
+	MaxAttempts int
+	// BaseDelay is about how long the first retry waits; each later retry waits twice as long
+	BaseDelay time.Duration
+	// MaxRetryAfter caps how long a retry waits when the provider asks for a wait with
+	// Retry-After; longer waits fail the attempt as rate limited. Zero removes the cap.
+	MaxRetryAfter time.Duration
+}
+
+type TranslatorConfig struct {
+	// MaxPromptTokens is the estimated prompt size above which a translation
+	// is rejected before calling the provider
+	MaxPromptTokens int
+	// MaxCodeBytes is the largest code, sent or fetched from source_url, a translation accepts
+	MaxCodeBytes int
+	// Examples are few-shot demonstrations injected into prompts for matching language pairs
+	Examples []TranslationExample
+	// MaxExampleChars skips examples whose combined source and target exceed this length
+	MaxExampleChars int
+	// DeltaFlushBytes and DeltaFlushInterval hold back delta chunks until either
+	// this many bytes of a section changed or this much time passed since the last one
+	DeltaFlushBytes    int
+	DeltaFlushInterval time.Duration
+	// StopOnComplete stops the provider stream as soon as the translated code's fence is closed
+	StopOnComplete bool
+	// WarnLanguageMismatch sends a language_mismatch warning when the translated code is fenced as another language
+	WarnLanguageMismatch bool
+	// CacheEnabled reuses the provider response for identical requests; CacheSize
+	// bounds the number of responses kept and CacheTTL how long each is reused
+	CacheEnabled bool
+	CacheSize    int
+	CacheTTL     time.Duration
+	// SectionHeadings adds heading synonyms per response section (explanation, notes, dependencies, code)
+	// to the built-in ones, for models that don't keep to the === format
+	SectionHeadings map[string][]string
+	// StripPromptArtifacts removes lines of the prompt the model echoed from the response sections
+	StripPromptArtifacts bool
+	// StreamingFallback retries a stream that fails before any output as a non-streaming completion
+	StreamingFallback bool
+	// FormatStrictness is the default handling of responses missing sections: lenient sends
+	// what there is, strict asks again and then fails the translation
+	FormatStrictness string
+	// ConfigConversion converts between JSON, YAML and TOML with parsers instead of the provider
+	ConfigConversion bool
+	// ChunkPartBytes is the approximate size of each part of a chunked translation
+	ChunkPartBytes int
+}
+
+// TranslationExample is a single few-shot demonstration for a language pair
+type TranslationExample struct {
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	Source         string `json:"source"`
+	Target         string `json:"target"`
+}
+
+type SSEConfig struct {
+	// MaxStreams caps the number of streams held by the hub at once
+	MaxStreams int
+	// BufferMaxBytes is the size of a stream's buffered messages above which delta chunks
+	// replaced by later chunks of their section are dropped
+	BufferMaxBytes int
+	// ResumeTokenSecret signs the tokens required to attach to a stream; random per process when empty
+	ResumeTokenSecret string
+	// ResumeTokenTTL is how long a resume token stays valid after the job is created
+	ResumeTokenTTL time.Duration
+	// OrphanTimeout fails unfinished streams that received no messages for this long
+	OrphanTimeout time.Duration
+	// StreamTTL evicts streams this long after their job was created, finished or not; zero disables this
+	StreamTTL time.Duration
+	// RetryMs is sent as the SSE retry hint: how long browsers wait before reconnecting
+	RetryMs int
+	// HeartbeatInterval is how long a stream may be silent before a keep-alive comment is sent,
+	// so proxies don't close it while the provider is slow; zero disables heartbeats
+	HeartbeatInterval time.Duration
+	// AttachGracePeriod is how long a job may run without any client attaching to its stream
+	// before it is cancelled and the stream discarded; zero disables this
+	AttachGracePeriod time.Duration
+	// ReplayMaxAge is how long after completing a persisted result is still replayed when a
+	// client reconnects to a stream that has been cleaned up
+	ReplayMaxAge time.Duration
+}
+
+type SectionsConfig struct {
+	// Streamed are the response sections (explanation, notes, dependencies, code) sent to clients; always includes code
+	Streamed []string
+	// Persisted are the response sections kept when a finished translation is stored
+	Persisted []string
+	// HistoryPreviewChars is how many characters of the source and translated code GET /translate/history
+	// lists per translation; zero lists them in full
+	HistoryPreviewChars int
+}
+
+type ModelConfig struct {
+	// Aliases map human-friendly names like "fast" to a concrete provider and model
+	Aliases map[string]ModelAlias
+	// Allowed are the models requests may name, by provider; providers without an entry accept any model
+	Allowed map[string][]string
+}
+
+// ModelAllowed reports whether requests may ask provider for model
+func (c ModelConfig) ModelAllowed(provider, model string) bool {
+	allowed, ok := c.Allowed[provider]
+	return !ok || slices.Contains(allowed, model)
+}
+
+type ProviderSelectionConfig struct {
+	// Strategy picks a provider for requests that name neither provider nor model:
+	// cheapest, fastest or round_robin. Empty uses the default provider.
+	Strategy string
+	// Providers are the candidates; empty means every provider with an API key
+	Providers []string
+	// Prices override the built-in USD prices per million output tokens used by the cheapest strategy
+	Prices map[string]float64
+}
+
+// ModelAlias is the provider and model a model alias resolves to
+type ModelAlias struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+type AbuseConfig struct {
+	// MaxFailures is how many consecutive failed jobs block a client
+	MaxFailures int
+	// Cooldown is how long a blocked client is refused new jobs
+	Cooldown time.Duration
+	// RateLimitRPM is how many translation requests a client may make per minute; zero disables the limit
+	RateLimitRPM int
+	// RateLimitBurst is how many requests a client may make at once before RateLimitRPM applies
+	RateLimitBurst int
+}
+
+type DeadLetterConfig struct {
+	// Sink is where failed translations are recorded: postgres, log or off
+	Sink string
+}
+
+type RequestMetadataConfig struct {
+	// AllowedKeys are the metadata keys requests may set; they end up in logs and
+	// metric labels, so the set is fixed to keep cardinality bounded. Empty disables metadata.
+	AllowedKeys []string
+	// MaxValueLength bounds each metadata value
+	MaxValueLength int
+}
+
+type WorkerPoolConfig struct {
+	// Workers is the number of translation jobs run against providers at once
+	Workers int
+	// QueueSize is how many jobs may wait for a worker before new ones are rejected
+	QueueSize int
+	// PriorityAging raises a queued job's priority by one level for every interval waited
+	PriorityAging time.Duration
+	// HighPriorityClients may submit high priority jobs; empty allows every client
+	HighPriorityClients []string
+}
+
+type SourceFetchConfig struct {
+	AllowedHosts []string
+	MaxBytes     int64
+	Timeout      time.Duration
 }
 
 func validateRequiredEnvs(v *viper.Viper, requiredEnvs []string) error {
@@ -51,6 +295,121 @@ func validateRequiredEnvs(v *viper.Viper, requiredEnvs []string) error {
 	return nil
 }
 
+// splitList parses a comma-separated env value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// metadataKeyPattern restricts request metadata keys to names that are valid log fields and metric labels
+var metadataKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// sectionNames are the response sections SECTION_HEADING_SYNONYMS may add headings for
+var sectionNames = []string{"explanation", "notes", "dependencies", "code"}
+
+// parseSectionHeadings parses SECTION_HEADING_SYNONYMS entries of the form
+// section=heading|heading, e.g. notes=## Caveats|Caveats:
+func parseSectionHeadings(value string) (map[string][]string, error) {
+	headings := make(map[string][]string)
+	for _, entry := range splitList(value) {
+		section, synonyms, ok := strings.Cut(entry, "=")
+		section = strings.ToLower(strings.TrimSpace(section))
+		if !ok || !slices.Contains(sectionNames, section) {
+			return nil, fmt.Errorf("invalid SECTION_HEADING_SYNONYMS entry %q, expected section=heading|heading with section one of %s", entry, strings.Join(sectionNames, ", "))
+		}
+		for _, synonym := range strings.Split(synonyms, "|") {
+			if synonym = strings.TrimSpace(synonym); synonym != "" {
+				headings[section] = append(headings[section], synonym)
+			}
+		}
+	}
+	return headings, nil
+}
+
+// parseSections parses a comma-separated list of response sections for key; empty selects every section
+func parseSections(key, value string) ([]string, error) {
+	sections := splitList(strings.ToLower(value))
+	if len(sections) == 0 {
+		return slices.Clone(sectionNames), nil
+	}
+	for _, section := range sections {
+		if !slices.Contains(sectionNames, section) {
+			return nil, fmt.Errorf("invalid %s entry %q, expected one of %s", key, section, strings.Join(sectionNames, ", "))
+		}
+	}
+	return sections, nil
+}
+
+// parseModelAliases parses MODEL_ALIASES entries of the form name=provider:model
+func parseModelAliases(value string) (map[string]ModelAlias, error) {
+	aliases := make(map[string]ModelAlias)
+	for _, entry := range splitList(value) {
+		name, target, ok := strings.Cut(entry, "=")
+		provider, model, ok2 := strings.Cut(target, ":")
+		name, provider, model = strings.TrimSpace(name), strings.TrimSpace(provider), strings.TrimSpace(model)
+		if !ok || !ok2 || name == "" || provider == "" || model == "" {
+			return nil, fmt.Errorf("invalid MODEL_ALIASES entry %q, expected name=provider:model", entry)
+		}
+		aliases[strings.ToLower(name)] = ModelAlias{Provider: provider, Model: model}
+	}
+	return aliases, nil
+}
+
+// parseAllowedModels parses ALLOWED_MODELS entries of the form provider=model|model,
+// e.g. openai=gpt-5-nano|gpt-4o
+func parseAllowedModels(value string) (map[string][]string, error) {
+	allowed := make(map[string][]string)
+	for _, entry := range splitList(value) {
+		provider, models, ok := strings.Cut(entry, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if !ok || provider == "" {
+			return nil, fmt.Errorf("invalid ALLOWED_MODELS entry %q, expected provider=model|model", entry)
+		}
+		for _, model := range strings.Split(models, "|") {
+			if model = strings.TrimSpace(model); model != "" {
+				allowed[provider] = append(allowed[provider], model)
+			}
+		}
+		if len(allowed[provider]) == 0 {
+			return nil, fmt.Errorf("invalid ALLOWED_MODELS entry %q, expected at least one model", entry)
+		}
+	}
+	return allowed, nil
+}
+
+// parseProviderPrices parses PROVIDER_PRICES entries of the form provider=price
+func parseProviderPrices(value string) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	for _, entry := range splitList(value) {
+		name, raw, ok := strings.Cut(entry, "=")
+		price, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || err != nil || price < 0 {
+			return nil, fmt.Errorf("invalid PROVIDER_PRICES entry %q, expected provider=price", entry)
+		}
+		prices[name] = price
+	}
+	return prices, nil
+}
+
+// loadExamples reads few-shot translation examples from a JSON array file
+func loadExamples(path string) ([]TranslationExample, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read translation examples: %w", err)
+	}
+	var examples []TranslationExample
+	if err := json.Unmarshal(data, &examples); err != nil {
+		return nil, fmt.Errorf("failed to parse translation examples: %w", err)
+	}
+	return examples, nil
+}
+
 // LoadConfig reads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	v := viper.New()
@@ -87,6 +446,16 @@ func LoadConfig() (*Config, error) {
 			Port:     v.GetString("SERVER_PORT"),
 			AppEnv:   v.GetString("APP_ENV"),
 			LogLevel: v.GetString("LOG_LEVEL"),
+			// unknown formats fall back to json
+			LogFormat: strings.ToLower(v.GetString("LOG_FORMAT")),
+			Provider:  strings.ToLower(strings.TrimSpace(v.GetString("TRANSLATOR_PROVIDER"))),
+			Providers: splitList(strings.ToLower(v.GetString("TRANSLATOR_PROVIDERS"))),
+
+			AllowedOrigins: splitList(v.GetString("ALLOWED_ORIGINS")),
+			APIKeys:        splitList(v.GetString("API_KEYS")),
+			AdminAPIKeys:   splitList(v.GetString("ADMIN_API_KEYS")),
+
+			RequestTimeout: v.GetDuration("REQUEST_TIMEOUT"),
 		},
 		Database: DatabaseConfig{
 			Name:     v.GetString("DB_NAME"),
@@ -98,16 +467,330 @@ func LoadConfig() (*Config, error) {
 		},
 		OpenAI: OpenAIConfig{
 			APIKey: v.GetString("OPENAI_API_KEY"),
+			Model:  v.GetString("OPENAI_MODEL"),
 		},
 		Gemini: GeminiConfig{
 			APIKey: v.GetString("GEMINI_API_KEY"),
+			Model:  v.GetString("GEMINI_MODEL"),
+		},
+		Local: LocalLLMConfig{
+			BaseURL: v.GetString("LOCAL_LLM_BASE_URL"),
+			Model:   v.GetString("LOCAL_LLM_MODEL"),
+			APIKey:  v.GetString("LOCAL_LLM_API_KEY"),
+		},
+		Anthropic: AnthropicConfig{
+			APIKey: v.GetString("ANTHROPIC_API_KEY"),
+			Model:  v.GetString("ANTHROPIC_MODEL"),
+		},
+		Ollama: OllamaConfig{
+			Host:  v.GetString("OLLAMA_HOST"),
+			Model: v.GetString("OLLAMA_MODEL"),
+		},
+		ProviderHTTP: ProviderHTTPConfig{
+			MaxIdleConns:    v.GetInt("PROVIDER_MAX_IDLE_CONNS"),
+			IdleConnTimeout: v.GetDuration("PROVIDER_IDLE_CONN_TIMEOUT"),
+			KeepAlive:       v.GetDuration("PROVIDER_KEEPALIVE"),
+			Warm:            v.GetBool("PROVIDER_WARM"),
+		},
+		Retry: RetryConfig{
+			MaxAttempts:   v.GetInt("RETRY_MAX_ATTEMPTS"),
+			BaseDelay:     v.GetDuration("RETRY_BASE_DELAY"),
+			MaxRetryAfter: v.GetDuration("MAX_RETRY_AFTER"),
+		},
+		Translator: TranslatorConfig{
+			MaxPromptTokens: v.GetInt("MAX_PROMPT_TOKENS"),
+			MaxCodeBytes:    v.GetInt("MAX_CODE_BYTES"),
+			MaxExampleChars: v.GetInt("TRANSLATION_EXAMPLE_MAX_CHARS"),
+
+			DeltaFlushBytes:    v.GetInt("DELTA_FLUSH_MIN_BYTES"),
+			DeltaFlushInterval: v.GetDuration("DELTA_FLUSH_INTERVAL"),
+
+			StopOnComplete: v.GetBool("STOP_ON_FORMAT_COMPLETE"),
+			// on unless explicitly disabled
+			WarnLanguageMismatch: !v.IsSet("LANGUAGE_MISMATCH_WARNINGS") || v.GetBool("LANGUAGE_MISMATCH_WARNINGS"),
+
+			CacheEnabled: !v.IsSet("TRANSLATION_CACHE") || v.GetBool("TRANSLATION_CACHE"),
+			CacheSize:    v.GetInt("TRANSLATION_CACHE_SIZE"),
+			CacheTTL:     v.GetDuration("TRANSLATION_CACHE_TTL"),
+
+			StreamingFallback: !v.IsSet("STREAMING_FALLBACK") || v.GetBool("STREAMING_FALLBACK"),
+			// on unless explicitly disabled
+			StripPromptArtifacts: !v.IsSet("STRIP_PROMPT_ARTIFACTS") || v.GetBool("STRIP_PROMPT_ARTIFACTS"),
+			ConfigConversion:     !v.IsSet("CONFIG_CONVERSION") || v.GetBool("CONFIG_CONVERSION"),
+			ChunkPartBytes:       v.GetInt("CHUNKED_PART_BYTES"),
+			FormatStrictness:     strings.ToLower(v.GetString("FORMAT_STRICTNESS")),
+		},
+		SourceURL: SourceFetchConfig{
+			AllowedHosts: splitList(v.GetString("SOURCE_URL_ALLOWED_HOSTS")),
+			MaxBytes:     v.GetInt64("SOURCE_URL_MAX_BYTES"),
+			Timeout:      v.GetDuration("SOURCE_URL_TIMEOUT"),
+		},
+		SSE: SSEConfig{
+			MaxStreams:        v.GetInt("SSE_MAX_STREAMS"),
+			BufferMaxBytes:    v.GetInt("SSE_BUFFER_MAX_BYTES"),
+			ResumeTokenSecret: v.GetString("RESUME_TOKEN_SECRET"),
+			ResumeTokenTTL:    v.GetDuration("RESUME_TOKEN_TTL"),
+			OrphanTimeout:     v.GetDuration("SSE_ORPHAN_TIMEOUT"),
+			StreamTTL:         v.GetDuration("SSE_STREAM_TTL"),
+			RetryMs:           v.GetInt("SSE_RETRY_MS"),
+			HeartbeatInterval: v.GetDuration("SSE_HEARTBEAT_INTERVAL"),
+			ReplayMaxAge:      v.GetDuration("RESULT_REPLAY_MAX_AGE"),
+			AttachGracePeriod: v.GetDuration("SSE_ATTACH_GRACE_PERIOD"),
 		},
+		WorkerPool: WorkerPoolConfig{
+			Workers:   v.GetInt("WORKER_POOL_SIZE"),
+			QueueSize: v.GetInt("WORKER_QUEUE_SIZE"),
+
+			PriorityAging:       v.GetDuration("WORKER_PRIORITY_AGING"),
+			HighPriorityClients: splitList(v.GetString("HIGH_PRIORITY_CLIENTS")),
+		},
+		Abuse: AbuseConfig{
+			MaxFailures: v.GetInt("ABUSE_MAX_FAILURES"),
+			Cooldown:    v.GetDuration("ABUSE_COOLDOWN"),
+
+			RateLimitRPM:   v.GetInt("RATE_LIMIT_RPM"),
+			RateLimitBurst: v.GetInt("RATE_LIMIT_BURST"),
+		},
+		DeadLetter: DeadLetterConfig{
+			Sink: strings.ToLower(v.GetString("DEAD_LETTER_SINK")),
+		},
+		Selection: ProviderSelectionConfig{
+			Strategy:  strings.ToLower(v.GetString("PROVIDER_STRATEGY")),
+			Providers: splitList(v.GetString("PROVIDER_STRATEGY_PROVIDERS")),
+		},
+		Metadata: RequestMetadataConfig{
+			AllowedKeys:    splitList(v.GetString("REQUEST_METADATA_KEYS")),
+			MaxValueLength: v.GetInt("REQUEST_METADATA_MAX_VALUE_LENGTH"),
+		},
+		Features: loadFeatureFlags(v),
 	}
 
 	// Set default values for server if not provided
 	if config.Server.Port == "" {
 		config.Server.Port = "6777"
 	}
+	if config.Server.Provider == "" {
+		config.Server.Provider = "gemini"
+	}
+	if config.ProviderHTTP.MaxIdleConns <= 0 {
+		config.ProviderHTTP.MaxIdleConns = 16
+	}
+	if config.ProviderHTTP.IdleConnTimeout <= 0 {
+		config.ProviderHTTP.IdleConnTimeout = 90 * time.Second
+	}
+	if config.ProviderHTTP.KeepAlive <= 0 {
+		config.ProviderHTTP.KeepAlive = 30 * time.Second
+	}
+	if config.Retry.MaxAttempts <= 0 {
+		config.Retry.MaxAttempts = 3
+	}
+	if config.Retry.BaseDelay <= 0 {
+		config.Retry.BaseDelay = 500 * time.Millisecond
+	}
+	if !v.IsSet("MAX_RETRY_AFTER") || config.Retry.MaxRetryAfter < 0 {
+		config.Retry.MaxRetryAfter = 30 * time.Second
+	}
+	if config.Local.BaseURL == "" {
+		config.Local.BaseURL = "http://localhost:8080/v1"
+	}
+	if config.Local.Model == "" {
+		// llama-server serves whichever model it was started with and ignores the name
+		config.Local.Model = "local"
+	}
+	if config.OpenAI.Model == "" {
+		config.OpenAI.Model = "gpt-5-nano"
+	}
+	if config.Gemini.Model == "" {
+		config.Gemini.Model = "gemini-2.5-flash"
+	}
+	if config.Anthropic.Model == "" {
+		config.Anthropic.Model = "claude-sonnet-4-5"
+	}
+	if config.Ollama.Host == "" {
+		config.Ollama.Host = "http://localhost:11434"
+	}
+	if config.Ollama.Model == "" {
+		config.Ollama.Model = "llama3.2"
+	}
+	if config.Server.RequestTimeout <= 0 {
+		config.Server.RequestTimeout = 30 * time.Second
+	}
+	// a frontend served by a dev server on another port works out of the box in development only
+	if !v.IsSet("ALLOWED_ORIGINS") && config.Server.AppEnv == "development" {
+		config.Server.AllowedOrigins = []string{"*"}
+	}
+	// the admin endpoints can stop every instance from taking jobs, so they are never left
+	// open where translations are protected
+	if len(config.Server.AdminAPIKeys) == 0 {
+		config.Server.AdminAPIKeys = config.Server.APIKeys
+	}
+
+	// Set default values for translator if not provided
+	if config.Translator.MaxPromptTokens <= 0 {
+		config.Translator.MaxPromptTokens = 100000
+	}
+	if config.Translator.MaxCodeBytes <= 0 {
+		config.Translator.MaxCodeBytes = 100 * 1024
+	}
+
+	if config.Translator.MaxExampleChars <= 0 {
+		config.Translator.MaxExampleChars = 4000
+	}
+	if config.Translator.DeltaFlushBytes <= 0 {
+		config.Translator.DeltaFlushBytes = 64
+	}
+	if config.Translator.DeltaFlushInterval <= 0 {
+		config.Translator.DeltaFlushInterval = 100 * time.Millisecond
+	}
+	if config.Translator.FormatStrictness == "" {
+		config.Translator.FormatStrictness = "lenient"
+	}
+	if config.Translator.ChunkPartBytes <= 0 {
+		config.Translator.ChunkPartBytes = 8192
+	}
+	if config.Translator.CacheSize <= 0 {
+		config.Translator.CacheSize = 256
+	}
+	if config.Translator.CacheTTL <= 0 {
+		config.Translator.CacheTTL = time.Hour
+	}
+	if raw := v.GetString("SECTION_HEADING_SYNONYMS"); raw != "" {
+		headings, err := parseSectionHeadings(raw)
+		if err != nil {
+			return nil, err
+		}
+		config.Translator.SectionHeadings = headings
+	}
+	if path := v.GetString("TRANSLATION_EXAMPLES_FILE"); path != "" {
+		examples, err := loadExamples(path)
+		if err != nil {
+			return nil, err
+		}
+		config.Translator.Examples = examples
+	}
+
+	streamed, err := parseSections("STREAMED_SECTIONS", v.GetString("STREAMED_SECTIONS"))
+	if err != nil {
+		return nil, err
+	}
+	// without the code section a client would receive no translation at all
+	if !slices.Contains(streamed, "code") {
+		return nil, errors.New("STREAMED_SECTIONS must include code")
+	}
+	persisted, err := parseSections("PERSISTED_SECTIONS", v.GetString("PERSISTED_SECTIONS"))
+	if err != nil {
+		return nil, err
+	}
+	config.Sections = SectionsConfig{Streamed: streamed, Persisted: persisted, HistoryPreviewChars: v.GetInt("HISTORY_PREVIEW_CHARS")}
+	// zero lists the code in full, so only an unset or negative value gets the default
+	if !v.IsSet("HISTORY_PREVIEW_CHARS") || config.Sections.HistoryPreviewChars < 0 {
+		config.Sections.HistoryPreviewChars = 500
+	}
+
+	aliases, err := parseModelAliases(v.GetString("MODEL_ALIASES"))
+	if err != nil {
+		return nil, err
+	}
+	config.Models.Aliases = aliases
+
+	allowedModels, err := parseAllowedModels(v.GetString("ALLOWED_MODELS"))
+	if err != nil {
+		return nil, err
+	}
+	config.Models.Allowed = allowedModels
+	// an alias the allow-list rejects could never be used, so it is a configuration mistake
+	for name, alias := range aliases {
+		if !config.Models.ModelAllowed(alias.Provider, alias.Model) {
+			return nil, fmt.Errorf("MODEL_ALIASES entry %q names %s:%s, which ALLOWED_MODELS does not allow", name, alias.Provider, alias.Model)
+		}
+	}
+
+	prices, err := parseProviderPrices(v.GetString("PROVIDER_PRICES"))
+	if err != nil {
+		return nil, err
+	}
+	config.Selection.Prices = prices
+
+	// Set default values for sse if not provided
+	if config.SSE.MaxStreams <= 0 {
+		config.SSE.MaxStreams = 1000
+	}
+	if config.SSE.ResumeTokenTTL <= 0 {
+		config.SSE.ResumeTokenTTL = 30 * time.Minute
+	}
+	if config.SSE.OrphanTimeout <= 0 {
+		config.SSE.OrphanTimeout = 5 * time.Minute
+	}
+	if config.SSE.BufferMaxBytes <= 0 {
+		config.SSE.BufferMaxBytes = 1024 * 1024
+	}
+	// zero turns the TTL off, so only an unset or negative value gets the default
+	if !v.IsSet("SSE_STREAM_TTL") || config.SSE.StreamTTL < 0 {
+		config.SSE.StreamTTL = 10 * time.Minute
+	}
+	if config.SSE.RetryMs <= 0 {
+		config.SSE.RetryMs = 3000
+	}
+	// zero turns the grace period off, so only an unset value gets the default
+	if !v.IsSet("SSE_ATTACH_GRACE_PERIOD") {
+		config.SSE.AttachGracePeriod = 30 * time.Second
+	}
+	// zero turns heartbeats off, so only an unset or negative value gets the default
+	if !v.IsSet("SSE_HEARTBEAT_INTERVAL") || config.SSE.HeartbeatInterval < 0 {
+		config.SSE.HeartbeatInterval = 15 * time.Second
+	}
+	if config.SSE.ReplayMaxAge <= 0 {
+		config.SSE.ReplayMaxAge = 24 * time.Hour
+	}
+
+	// zero turns blocking off, so only an unset or negative value gets the default
+	if !v.IsSet("ABUSE_MAX_FAILURES") || config.Abuse.MaxFailures < 0 {
+		config.Abuse.MaxFailures = 5
+	}
+	if config.Abuse.Cooldown <= 0 {
+		config.Abuse.Cooldown = 10 * time.Minute
+	}
+	// zero turns the rate limit off, so only an unset or negative value gets the default
+	if !v.IsSet("RATE_LIMIT_RPM") || config.Abuse.RateLimitRPM < 0 {
+		config.Abuse.RateLimitRPM = 60
+	}
+	if config.Abuse.RateLimitBurst <= 0 {
+		config.Abuse.RateLimitBurst = 10
+	}
+
+	if config.DeadLetter.Sink == "" {
+		config.DeadLetter.Sink = "postgres"
+	}
+
+	for _, key := range config.Metadata.AllowedKeys {
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid REQUEST_METADATA_KEYS entry %q, expected lowercase letters, digits and underscores (at most 32)", key)
+		}
+	}
+	if config.Metadata.MaxValueLength <= 0 {
+		config.Metadata.MaxValueLength = 128
+	}
+
+	if config.WorkerPool.Workers <= 0 {
+		config.WorkerPool.Workers = 8
+	}
+	if config.WorkerPool.QueueSize <= 0 {
+		config.WorkerPool.QueueSize = 100
+	}
+	if config.WorkerPool.PriorityAging <= 0 {
+		config.WorkerPool.PriorityAging = 10 * time.Second
+	}
+
+	// Set default values for source url fetching if not provided
+	if len(config.SourceURL.AllowedHosts) == 0 {
+		config.SourceURL.AllowedHosts = []string{"gist.githubusercontent.com", "raw.githubusercontent.com"}
+	}
+	if config.SourceURL.MaxBytes <= 0 {
+		config.SourceURL.MaxBytes = 256 * 1024
+	}
+	if config.SourceURL.Timeout <= 0 {
+		config.SourceURL.Timeout = 10 * time.Second
+	}
 
 	return config, nil
 }
@@ -0,0 +1,38 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSectionHeadings(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string][]string
+		wantErr bool
+	}{
+		{"single heading", "notes=Caveats:", map[string][]string{"notes": {"Caveats:"}}, false},
+		{
+			"several sections and headings",
+			"notes=## Caveats|Caveats:, Code = === CODE === | Converted Code: ",
+			map[string][]string{"notes": {"## Caveats", "Caveats:"}, "code": {"=== CODE ===", "Converted Code:"}},
+			false,
+		},
+		{"repeated section", "explanation=Summary:,explanation=## Summary", map[string][]string{"explanation": {"Summary:", "## Summary"}}, false},
+		{"empty headings skipped", "dependencies=|Packages:|", map[string][]string{"dependencies": {"Packages:"}}, false},
+		{"unknown section", "summary=Summary:", nil, true},
+		{"missing =", "notes", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSectionHeadings(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("headings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
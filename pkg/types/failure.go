@@ -0,0 +1,14 @@
+package types
+
+import "time"
+
+// FailedTranslation is a translation job that failed, kept for analysis and replay
+type FailedTranslation struct {
+	JobID  string
+	Client string
+	// ErrorCode classifies the failure (e.g. provider_error, timeout) for aggregation
+	ErrorCode string
+	Error     string
+	Request   TranslateRequest
+	FailedAt  time.Time
+}
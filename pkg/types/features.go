@@ -0,0 +1,44 @@
+package types
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const featureEnvPrefix = "FEATURE_"
+
+// FeatureFlags holds env-driven toggles for experimental functionality,
+// e.g. FEATURE_PROVIDER_METADATA=true enables the "provider_metadata" flag
+type FeatureFlags struct {
+	enabled map[string]bool
+}
+
+// Enabled reports whether the named flag is switched on; unknown flags are off
+func (f FeatureFlags) Enabled(name string) bool {
+	return f.enabled[strings.ToLower(name)]
+}
+
+// loadFeatureFlags collects FEATURE_* values from the process environment and the .env file
+func loadFeatureFlags(v *viper.Viper) FeatureFlags {
+	flags := FeatureFlags{enabled: make(map[string]bool)}
+
+	keys := v.AllKeys()
+	for _, kv := range os.Environ() {
+		if key, _, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(key, featureEnvPrefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		upper := strings.ToUpper(key)
+		if !strings.HasPrefix(upper, featureEnvPrefix) {
+			continue
+		}
+		name := strings.ToLower(strings.TrimPrefix(upper, featureEnvPrefix))
+		flags.enabled[name] = v.GetBool(upper)
+	}
+
+	return flags
+}
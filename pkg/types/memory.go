@@ -0,0 +1,9 @@
+package types
+
+// MemorySegment is a source segment (typically one function) and its prior
+// translation, keyed by a hash of the normalized source
+type MemorySegment struct {
+	Key    string
+	Source string
+	Target string
+}
@@ -1,7 +1,76 @@
 package types
 
 type TranslateRequest struct {
-	Code           string `json:"code" binding:"required"`
+	Code           string `json:"code"`
+	SourceURL      string `json:"source_url"`
 	TargetLanguage string `json:"target_language" binding:"required"`
 	SourceLanguage string `json:"source_language"`
+	// Filename is used to infer SourceLanguage from its extension when that is empty
+	Filename string `json:"filename,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	// ModelAlias selects a configured provider and model by name instead of Provider and Model
+	ModelAlias    string   `json:"model_alias,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	Seed          *int64   `json:"seed,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+	// MaxOutputTokens caps the length of the provider's response; a cap too low for every
+	// section leaves the response incomplete
+	MaxOutputTokens *int64 `json:"max_output_tokens,omitempty"`
+	// Output selects an additional output format; "patch" adds a unified diff against the source
+	Output string `json:"output,omitempty"`
+	// StartLine and EndLine (1-based, inclusive) translate only part of the code
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+	// IncludeMetadata adds a metadata chunk with the provider's model, finish reason and token
+	// usage; only allowed when the provider_metadata feature flag is on
+	IncludeMetadata bool `json:"include_metadata,omitempty"`
+	// Framework names a target framework or library the translation should use, e.g. "gin"
+	Framework string `json:"framework,omitempty"`
+	// Instructions are extra free-form guidance added to the prompt
+	Instructions string `json:"instructions,omitempty"`
+	// TermMap forces source terms (identifiers, domain words) to specific target terms
+	TermMap map[string]string `json:"term_map,omitempty"`
+	// EnforceTermMap rewrites any source term left in the translated code to its target term
+	EnforceTermMap bool `json:"enforce_term_map,omitempty"`
+	// CodeOnly skips the explanation and notes sections, in the prompt and the output,
+	// for faster and cheaper responses
+	CodeOnly bool `json:"code_only,omitempty"`
+	// IncludeDependencies adds a dependencies section listing the packages the translated
+	// code needs, formatted for the target language's manifest (go.mod, requirements.txt, ...)
+	IncludeDependencies bool `json:"include_dependencies,omitempty"`
+	// FormatStrictness is strict or lenient and overrides FORMAT_STRICTNESS: strict asks the
+	// model again when sections are missing and fails the job if they are still missing
+	FormatStrictness string `json:"format_strictness,omitempty"`
+	// Priority is low, normal (default) or high; higher priority jobs are started first
+	Priority string `json:"priority,omitempty"`
+	// Chunked translates a large file one part at a time, persisting each part so the job
+	// can be resumed with POST /translate/:id/resume if it is interrupted
+	Chunked bool `json:"chunked,omitempty"`
+	// UseMemory opts in to reusing, and adding to, prior translations of the same functions
+	UseMemory bool `json:"use_memory,omitempty"`
+	// MemoryScope is the caller whose translation memory UseMemory reads and adds to, so one
+	// client's code never reaches another's prompts; it is set by the server, never by clients
+	MemoryScope string `json:"-"`
+	// Metadata are client tags for usage attribution, e.g. {"feature": "editor"}. They are
+	// logged and stored with the job but never reach the provider; keys must be allowed by
+	// REQUEST_METADATA_KEYS. Not to be confused with IncludeMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// ParentID is the translation a rerun repeats; it is set by the server, never by clients
+	ParentID string `json:"-"`
+}
+
+// CompletionOptions returns the provider generation settings requested by the client
+func (r TranslateRequest) CompletionOptions() CompletionOptions {
+	return CompletionOptions{
+		Model:           r.Model,
+		Temperature:     r.Temperature,
+		Seed:            r.Seed,
+		StopSequences:   r.StopSequences,
+		MaxOutputTokens: r.MaxOutputTokens,
+	}
+}
+
+type DrainRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
 }
@@ -0,0 +1,25 @@
+package types
+
+import "time"
+
+// Translation is a finished translation kept in the history. Sections left out of
+// PERSISTED_SECTIONS are stored empty.
+type Translation struct {
+	// ID is the job id the translation was streamed under
+	ID     string `json:"id"`
+	Client string `json:"-"`
+	// Owner is the session that created the job; history is only listed to it
+	Owner          string `json:"-"`
+	SourceLanguage string `json:"source_language"`
+	TargetLanguage string `json:"target_language"`
+	SourceCode     string `json:"source_code"`
+	TranslatedCode string `json:"translated_code,omitempty"`
+	Explanation    string `json:"explanation,omitempty"`
+	Notes          string `json:"notes,omitempty"`
+	// Dependencies is only set for requests with include_dependencies
+	Dependencies string            `json:"dependencies,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	// ParentID is the translation this one re-ran with other settings, if any
+	ParentID  string    `json:"parent_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
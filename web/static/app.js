@@ -174,7 +174,7 @@ func main() {
             }
 
             const data = await response.json();
-            this.startStreaming(data.id);
+            this.startStreaming(data.id, data.resume_token);
 
         } catch (error) {
             this.showNotification(`Translation failed: ${error.message}`, 'error');
@@ -183,7 +183,7 @@ func main() {
         }
     }
 
-    startStreaming(jobId) {
+    startStreaming(jobId, resumeToken) {
         const outputContainer = document.getElementById('outputContainer');
         const statusEl = document.getElementById('streamStatus');
 
@@ -207,7 +207,7 @@ func main() {
             }
         }, 120000);
 
-        this.eventSource = new EventSource(`/translate/stream/${jobId}`);
+        this.eventSource = new EventSource(`/translate/stream/${jobId}?resume_token=${encodeURIComponent(resumeToken)}`);
 
         this.eventSource.onopen = () => {
             console.log('Stream connected');